@@ -0,0 +1,96 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Command ledger provides maintenance tools for ledger storage.
+//
+// Usage:
+//
+//	ledger fsck [-data <dir>] [-repair]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/insolar/insolar/ledger/storage/fsck"
+	"github.com/insolar/insolar/ledger/storage/leveldb"
+)
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: ledger <command> [flags]")
+	fmt.Fprintln(os.Stderr, "")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	fmt.Fprintln(os.Stderr, "  fsck    check ledger storage consistency")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "fsck":
+		os.Exit(runFsck(os.Args[2:]))
+	case "help", "-h", "-help", "--help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+}
+
+// runFsck checks LevelLedger storage and returns process exit code: 0 if storage is consistent (or was repaired), 1 if
+// problems remain and 2 on failure.
+func runFsck(args []string) int {
+	flags := flag.NewFlagSet("fsck", flag.ExitOnError)
+	dataDir := flags.String("data", leveldb.DefaultConfig().DataDirectory, "ledger data directory")
+	repair := flags.Bool("repair", false, "repair found problems where possible")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// Opening writable ledger creates a new one, so check that there is something to check first.
+	if _, err := os.Stat(*dataDir); err != nil {
+		fmt.Fprintln(os.Stderr, "failed to open ledger:", err)
+		return 2
+	}
+	cfg := leveldb.DefaultConfig()
+	cfg.DataDirectory = *dataDir
+	cfg.ReadOnly = !*repair
+	ledger, err := leveldb.NewLevelLedger(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to open ledger:", err)
+		return 2
+	}
+	defer ledger.Close() // nolint: errcheck
+
+	report, err := fsck.Check(ledger, fsck.Options{Repair: *repair})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "check failed:", err)
+		return 2
+	}
+	for _, p := range report.Problems {
+		fmt.Println(p)
+	}
+	fmt.Printf("checked %d records, %d lifelines, %d request results, %d blobs: %d problems found\n",
+		report.Records, report.Lifelines, report.Results, report.Blobs, len(report.Problems))
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
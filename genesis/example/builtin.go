@@ -0,0 +1,72 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package example
+
+import (
+	"github.com/ugorji/go/codec"
+
+	"github.com/insolar/insolar/genesis/model/class"
+	"github.com/insolar/insolar/logicrunner/builtin"
+)
+
+// Names of example contracts registered in builtin executor (see builtin.Deploy).
+const (
+	BuiltinMemberName = "example." + class.MemberID
+	BuiltinWalletName = "example." + class.WalletID
+)
+
+// Member and wallet keep their state in plain fields, so they run in builtin executor with the state as object
+// memory. Domains keep their children in the object model and aren't registered.
+func init() {
+	builtin.Register(BuiltinMemberName, func() interface{} { return &member{} })
+	builtin.Register(BuiltinWalletName, func() interface{} { return &wallet{} })
+}
+
+// memberMemory is object memory of member.
+type memberMemory struct {
+	Username  string
+	PublicKey string
+}
+
+// CodecEncodeSelf encodes member state as object memory.
+func (m *member) CodecEncodeSelf(e *codec.Encoder) {
+	e.MustEncode(memberMemory{Username: m.username, PublicKey: m.publicKey})
+}
+
+// CodecDecodeSelf decodes member state from object memory.
+func (m *member) CodecDecodeSelf(d *codec.Decoder) {
+	var mem memberMemory
+	d.MustDecode(&mem)
+	m.username, m.publicKey = mem.Username, mem.PublicKey
+}
+
+// walletMemory is object memory of wallet.
+type walletMemory struct {
+	Balance int
+}
+
+// CodecEncodeSelf encodes wallet state as object memory.
+func (w *wallet) CodecEncodeSelf(e *codec.Encoder) {
+	e.MustEncode(walletMemory{Balance: w.balance})
+}
+
+// CodecDecodeSelf decodes wallet state from object memory.
+func (w *wallet) CodecDecodeSelf(d *codec.Decoder) {
+	var mem walletMemory
+	d.MustDecode(&mem)
+	w.balance = mem.Balance
+}
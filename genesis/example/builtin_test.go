@@ -0,0 +1,90 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package example
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+
+	"github.com/insolar/insolar/ledger/artifactmanager"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage/memory"
+	"github.com/insolar/insolar/logicrunner"
+	"github.com/insolar/insolar/logicrunner/builtin"
+)
+
+func encodeCBOR(t *testing.T, v interface{}) []byte {
+	var data []byte
+	assert.NoError(t, codec.NewEncoderBytes(&data, new(codec.CborHandle)).Encode(v))
+	return data
+}
+
+// activateBuiltin deploys builtin contract, activates its class and object with provided memory.
+func activateBuiltin(t *testing.T, am artifactmanager.ArtifactManager, name string, memory []byte) record.Reference {
+	requestRef, err := am.RegisterRequest(&record.CallRequest{Method: name})
+	assert.NoError(t, err)
+	codeRef, err := builtin.Deploy(am, *requestRef, name)
+	assert.NoError(t, err)
+	requestRef, err = am.RegisterRequest(&record.CallRequest{RequestRecord: record.RequestRecord{Target: *codeRef}})
+	assert.NoError(t, err)
+	classRef, err := am.ActivateClass(*requestRef, *codeRef, nil)
+	assert.NoError(t, err)
+	requestRef, err = am.RegisterRequest(&record.CallRequest{RequestRecord: record.RequestRecord{Target: *classRef}})
+	assert.NoError(t, err)
+	objRef, err := am.ActivateObj(*requestRef, *classRef, memory)
+	assert.NoError(t, err)
+	return *objRef
+}
+
+// execBuiltin calls method of the object through LedgerRunner and returns decoded results.
+func execBuiltin(t *testing.T, r logicrunner.LogicRunner, objRef record.Reference, method string) []interface{} {
+	object := logicrunner.Object{
+		MachineType: logicrunner.MachineTypeBuiltin,
+		Reference:   logicrunner.Reference(objRef.String()),
+	}
+	ret, err := r.Exec(object, method, encodeCBOR(t, []interface{}{}))
+	assert.NoError(t, err)
+	var res []interface{}
+	assert.NoError(t, codec.NewDecoderBytes(ret, new(codec.CborHandle)).Decode(&res))
+	return res
+}
+
+func TestBuiltinContracts(t *testing.T) {
+	am := artifactmanager.NewArtifactManager(memory.NewMemLedger())
+	am.SetArchPref([]record.ArchType{builtin.ArchType})
+	runner := logicrunner.NewRunner()
+	assert.NoError(t, runner.RegisterExecutor(logicrunner.MachineTypeBuiltin, builtin.NewBuiltin(am)))
+	r := logicrunner.NewLedgerRunner(runner, am)
+
+	memberMemory := encodeCBOR(t, memberMemory{Username: "alice", PublicKey: "key"})
+	memberRef := activateBuiltin(t, am, BuiltinMemberName, memberMemory)
+	assert.Equal(t, []interface{}{"alice"}, execBuiltin(t, r, memberRef, "GetUsername"))
+	assert.Equal(t, []interface{}{"key"}, execBuiltin(t, r, memberRef, "GetPublicKey"))
+
+	walletRef := activateBuiltin(t, am, BuiltinWalletName, encodeCBOR(t, walletMemory{Balance: 5}))
+	assert.Equal(t, []interface{}{uint64(5)}, execBuiltin(t, r, walletRef, "GetBalance"))
+
+	_, obj, err := am.GetLatestObj(walletRef, record.Reference{}, record.Reference{})
+	assert.NoError(t, err)
+	memory, err := obj.GetMemory()
+	assert.NoError(t, err)
+	var w wallet
+	assert.NoError(t, codec.NewDecoderBytes(memory, new(codec.CborHandle)).Decode(&w))
+	assert.Equal(t, 5, w.GetBalance())
+}
@@ -63,5 +63,17 @@ Entities:
 		w := m.GetOrCreateComposite(wFactory)
 		w.GetBalance()
 
+////
+
+	Builtin contracts - member and wallet are registered in builtin executor (see logicrunner/builtin). Their memory is
+	CBOR encoded state, so they run in-process without goplugin. Contract is deployed to ledger and its classes are
+	activated with returned code reference. Builtin executor fetches contract name from the code record, so artifact
+	manager's architecture preferences should include builtin.ArchType.
+
+	Usage:
+
+		codeRef, err := builtin.Deploy(am, requestRef, BuiltinWalletName)
+		classRef, err := am.ActivateClass(requestRef, *codeRef, nil)
+
 */
 package example
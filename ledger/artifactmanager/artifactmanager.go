@@ -25,6 +25,10 @@ import (
 )
 
 // ArtifactManager is a high level storage interface.
+//
+// Every mutation is made on behalf of a request record, which should be stored before the mutation. Mutations of an
+// existing object or class require the request to target its head, ActivateObj requires the request to target the
+// class. Each request can produce only one result.
 type ArtifactManager interface {
 	// SetArchPref stores a list of preferred VM architectures memory.
 	//
@@ -33,6 +37,14 @@ type ArtifactManager interface {
 	// return an error.
 	SetArchPref(pref []record.ArchType)
 
+	// RegisterRequest stores request record in storage. Mutations are made on behalf of stored requests.
+	RegisterRequest(request record.Request) (*record.Reference, error)
+
+	// GetCode returns code of provided code record according to architecture preferences.
+	//
+	// Code references are immutable, so VM can cache returned code by reference.
+	GetCode(codeRef record.Reference) ([]byte, error)
+
 	// GetExactObj returns code and memory of provided object/class state. Deactivation records should be ignored
 	// (e.g. object considered to be active).
 	//
@@ -44,6 +56,8 @@ type ArtifactManager interface {
 	// an error should be returned.
 	//
 	// Returned descriptors will provide methods for fetching migrations and appends relative to the provided states.
+	// Pending class migrations can be applied to the object before descriptors are returned. Class descriptor
+	// provides no migrations then.
 	GetLatestObj(objectRef, storedClassState, storedObjState record.Reference) (
 		*ClassDescriptor, *ObjectDescriptor, error,
 	)
@@ -92,26 +106,81 @@ type ArtifactManager interface {
 	// them to the new memory manually if its required.
 	UpdateObj(requestRef, objRef record.Reference, memory record.Memory) (*record.Reference, error)
 
+	// CompareAndUpdateObj works as UpdateObj, but object is updated only if stateRef is its latest state. Otherwise
+	// ErrConflict is returned and nothing is stored. It is used to store memory computed from the object state.
+	CompareAndUpdateObj(
+		requestRef, objRef, stateRef record.Reference, memory record.Memory,
+	) (*record.Reference, error)
+
 	// AppendObjDelegate creates append object record in storage. Provided reference should be a reference to the head
 	// of the object. Provided memory well be used as append delegate memory.
 	//
 	// Object's delegates will be provided by GetLatestObj. Any object update will nullify all the object's append
 	// delegates. VM is responsible for collecting all appends and adding them to the new memory manually if its
-	// required.
+	// required, or delegates can be folded into object memory by ConsolidateDelegates.
 	AppendObjDelegate(requestRef, objRef record.Reference, memory record.Memory) (*record.Reference, error)
+
+	// ConsolidateDelegates folds all append delegates of the object into a new amend record using provided merge
+	// function. Provided reference should be a reference to the head of the object. If the object has no delegates,
+	// nothing is stored and nil reference is returned.
+	ConsolidateDelegates(requestRef, objRef record.Reference, merge MergeFunc) (*record.Reference, error)
+
+	// GetHistory returns all records of object or class lifeline ordered from activation to the latest one. Provided
+	// reference should be a reference to the head of the object or the class.
+	GetHistory(headRef record.Reference) ([]HistoryEntry, error)
+
+	// GetObjAt returns descriptors for the state of the object and its class as of provided pulse (e.g. the latest
+	// states created in this pulse or before it). If the object is not activated yet or is deactivated as of this
+	// pulse, an error should be returned.
+	//
+	// Returned class descriptor provides no migrations, because it describes the exact class state.
+	GetObjAt(objectRef record.Reference, pn record.PulseNum) (*ClassDescriptor, *ObjectDescriptor, error)
 }
 
 // LedgerArtifactManager provides concrete API to storage for processing module
 type LedgerArtifactManager struct {
-	storer   storage.LedgerStorer
-	archPref []record.ArchType
+	storer          storage.LedgerStorer
+	archPref        []record.ArchType
+	migrationRunner MigrationRunner
+	delegateLimit   int
+	delegateMerge   MergeFunc
 }
 
-func (m *LedgerArtifactManager) checkRequestRecord(requestRef *record.Reference) error {
-	// TODO: implement request check
+// NewArtifactManager creates artifact manager on top of provided storage.
+func NewArtifactManager(storer storage.LedgerStorer) *LedgerArtifactManager {
+	return &LedgerArtifactManager{storer: storer}
+}
+
+// checkRequestRecord checks that provided request can produce a result. If targetRef is provided, the request should
+// target it. Request having no result yet is checked by storage when the result is stored (see
+// storage.Batch.SetRequestResult), so concurrent mutations with the same request can't both succeed.
+func (m *LedgerArtifactManager) checkRequestRecord(requestRef, targetRef *record.Reference) error {
+	rec, err := m.storer.GetRecord(requestRef)
+	if err == storage.ErrNotFound {
+		return ErrRequestNotFound
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to fetch request record")
+	}
+	request, ok := rec.(record.Request)
+	if !ok {
+		return ErrNotRequest
+	}
+	if targetRef != nil && request.TargetRef().IsNotEqual(*targetRef) {
+		return ErrRequestTargetMismatch
+	}
 	return nil
 }
 
+// indexError wraps error of fetching data referred by lifeline index. Data referred by index should exist, so
+// ErrNotFound is replaced with ErrInconsistentIndex.
+func indexError(err error, msg string) error {
+	if err == storage.ErrNotFound {
+		return errors.Wrap(ErrInconsistentIndex, msg)
+	}
+	return errors.Wrap(err, msg)
+}
+
 func (m *LedgerArtifactManager) getCodeRecord(codeRef record.Reference) (*record.CodeRecord, error) {
 	rec, err := m.storer.GetRecord(&codeRef)
 	if err != nil {
@@ -119,7 +188,7 @@ func (m *LedgerArtifactManager) getCodeRecord(codeRef record.Reference) (*record
 	}
 	codeRec, ok := rec.(*record.CodeRecord)
 	if !ok {
-		return nil, errors.New("provided reference is not a code reference")
+		return nil, errors.Wrap(ErrWrongRecordType, "provided reference is not a code reference")
 	}
 	return codeRec, nil
 }
@@ -129,7 +198,7 @@ func (m *LedgerArtifactManager) getCodeRecordCode(codeRef record.Reference) ([]b
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to retrieve code record")
 	}
-	code, err := codeRec.GetCode(m.archPref)
+	code, err := codeRec.GetCode(m.archPref, m.storer.GetBlob)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to retrieve code")
 	}
@@ -137,14 +206,6 @@ func (m *LedgerArtifactManager) getCodeRecordCode(codeRef record.Reference) ([]b
 	return code, nil
 }
 
-func (m *LedgerArtifactManager) storeRecord(rec record.Record) (*record.Reference, error) {
-	ref, err := m.storer.SetRecord(rec)
-	if err != nil {
-		return nil, errors.Wrap(err, "record store failed")
-	}
-	return ref, nil
-}
-
 func (m *LedgerArtifactManager) getActiveClass(classRef record.Reference) (
 	*record.ClassActivateRecord, *record.ClassAmendRecord, *index.ClassLifeline, error,
 ) {
@@ -154,22 +215,22 @@ func (m *LedgerArtifactManager) getActiveClass(classRef record.Reference) (
 	}
 	activateRec, isClassRec := classRecord.(*record.ClassActivateRecord)
 	if !isClassRec {
-		return nil, nil, nil, errors.New("provided reference is not a class record")
+		return nil, nil, nil, errors.Wrap(ErrWrongRecordType, "provided reference is not a class record")
 	}
 	classIndex, err := m.storer.GetClassIndex(&classRef)
 	if err != nil {
-		return nil, nil, nil, errors.Wrap(err, "inconsistent class index")
+		return nil, nil, nil, indexError(err, "class index is not found")
 	}
 	latestClassRecord, err := m.storer.GetRecord(&classIndex.LatestStateRef)
 	if err != nil {
-		return nil, nil, nil, errors.Wrap(err, "latest class record is not found")
+		return nil, nil, nil, indexError(err, "latest class record is not found")
 	}
 	if _, isDeactivated := latestClassRecord.(*record.DeactivationRecord); isDeactivated {
-		return nil, nil, nil, errors.New("class is deactivated")
+		return nil, nil, nil, errors.Wrap(ErrDeactivated, "class is deactivated")
 	}
 	amendRecord, isLatestAmend := latestClassRecord.(*record.ClassAmendRecord)
 	if classRef.IsNotEqual(classIndex.LatestStateRef) && !isLatestAmend {
-		return nil, nil, nil, errors.New("wrong index record")
+		return nil, nil, nil, errors.Wrap(ErrInconsistentIndex, "wrong index record")
 	}
 
 	return activateRec, amendRecord, classIndex, nil
@@ -184,23 +245,23 @@ func (m *LedgerArtifactManager) getActiveObject(objRef record.Reference) (
 	}
 	activateRec, isObjectRec := objRecord.(*record.ObjectActivateRecord)
 	if !isObjectRec {
-		return nil, nil, nil, errors.New("provided reference is not an object record")
+		return nil, nil, nil, errors.Wrap(ErrWrongRecordType, "provided reference is not an object record")
 	}
 
 	objIndex, err := m.storer.GetObjectIndex(&objRef)
 	if err != nil {
-		return nil, nil, nil, errors.Wrap(err, "inconsistent object index")
+		return nil, nil, nil, indexError(err, "object index is not found")
 	}
 	latestObjRecord, err := m.storer.GetRecord(&objIndex.LatestStateRef)
 	if err != nil {
-		return nil, nil, nil, errors.Wrap(err, "latest object record is not found")
+		return nil, nil, nil, indexError(err, "latest object record is not found")
 	}
 	if _, isDeactivated := latestObjRecord.(*record.DeactivationRecord); isDeactivated {
-		return nil, nil, nil, errors.New("object is deactivated")
+		return nil, nil, nil, errors.Wrap(ErrDeactivated, "object is deactivated")
 	}
 	amendRecord, isLatestAmend := latestObjRecord.(*record.ObjectAmendRecord)
 	if objRef.IsNotEqual(objIndex.LatestStateRef) && !isLatestAmend {
-		return nil, nil, nil, errors.New("wrong index record")
+		return nil, nil, nil, errors.Wrap(ErrInconsistentIndex, "wrong index record")
 	}
 
 	return activateRec, amendRecord, objIndex, nil
@@ -215,13 +276,30 @@ func (m *LedgerArtifactManager) SetArchPref(pref []record.ArchType) {
 	m.archPref = pref
 }
 
+// RegisterRequest stores request record in storage. Mutations are made on behalf of stored requests.
+func (m *LedgerArtifactManager) RegisterRequest(request record.Request) (*record.Reference, error) {
+	ref, err := m.storer.SetRecord(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to store request record")
+	}
+	return ref, nil
+}
+
+// GetCode returns code of provided code record according to architecture preferences.
+//
+// Code references are immutable, so VM can cache returned code by reference.
+func (m *LedgerArtifactManager) GetCode(codeRef record.Reference) ([]byte, error) {
+	return m.getCodeRecordCode(codeRef)
+}
+
 // DeployCode creates new code record in storage.
 //
-// Code records are used to activate class or as migration code for an object.
+// Code records are used to activate class or as migration code for an object. Code itself is stored in
+// content-addressed blobs, so deploying the same code again does not duplicate it in storage.
 func (m *LedgerArtifactManager) DeployCode(
 	requestRef record.Reference, codeMap map[record.ArchType][]byte,
 ) (*record.Reference, error) {
-	err := m.checkRequestRecord(&requestRef)
+	err := m.checkRequestRecord(&requestRef, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -234,9 +312,30 @@ func (m *LedgerArtifactManager) DeployCode(
 				},
 			},
 		},
-		TargetedCode: codeMap,
+		CodeBlobs: map[record.ArchType][]byte{},
+	}
+	var codeRef *record.Reference
+	err = m.storer.Update(func(batch storage.Batch) error {
+		for archType, code := range codeMap {
+			rec.CodeBlobs[archType], err = batch.SetBlob(code)
+			if err != nil {
+				return errors.Wrap(err, "failed to store code blob")
+			}
+		}
+		codeRef, err = batch.SetRecord(&rec)
+		if err != nil {
+			return errors.Wrap(err, "record store failed")
+		}
+		err = batch.SetRequestResult(&requestRef, codeRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store request result")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return m.storeRecord(&rec)
+	return codeRef, nil
 }
 
 // ActivateClass creates activate class record in storage. Provided code reference will be used as a class code
@@ -246,7 +345,7 @@ func (m *LedgerArtifactManager) DeployCode(
 func (m *LedgerArtifactManager) ActivateClass(
 	requestRef, codeRef record.Reference, memory record.Memory,
 ) (*record.Reference, error) {
-	err := m.checkRequestRecord(&requestRef)
+	err := m.checkRequestRecord(&requestRef, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -266,15 +365,26 @@ func (m *LedgerArtifactManager) ActivateClass(
 		CodeRecord:    codeRef,
 		DefaultMemory: memory,
 	}
-	classRef, err := m.storeRecord(&rec)
-	if err != nil {
-		return nil, err
-	}
-	err = m.storer.SetClassIndex(classRef, &index.ClassLifeline{
-		LatestStateRef: *classRef,
+	var classRef *record.Reference
+	err = m.storer.Update(func(batch storage.Batch) error {
+		classRef, err = batch.SetRecord(&rec)
+		if err != nil {
+			return errors.Wrap(err, "record store failed")
+		}
+		err = batch.SetRequestResult(&requestRef, classRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store request result")
+		}
+		err = batch.SetClassIndex(classRef, &index.ClassLifeline{
+			LatestStateRef: *classRef,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline index")
+		}
+		return nil
 	})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to store lifeline index")
+		return nil, err
 	}
 
 	return classRef, nil
@@ -287,7 +397,7 @@ func (m *LedgerArtifactManager) ActivateClass(
 func (m *LedgerArtifactManager) DeactivateClass(
 	requestRef, classRef record.Reference,
 ) (*record.Reference, error) {
-	err := m.checkRequestRecord(&requestRef)
+	err := m.checkRequestRecord(&requestRef, &classRef)
 	if err != nil {
 		return nil, err
 	}
@@ -311,15 +421,29 @@ func (m *LedgerArtifactManager) DeactivateClass(
 			},
 		},
 	}
-	deactivationRef, err := m.storeRecord(&rec)
-	if err != nil {
-		return nil, errors.New("failed to store deactivation record")
-	}
-	classIndex.LatestStateRef = *deactivationRef
-	err = m.storer.SetClassIndex(&classRef, classIndex)
+	var deactivationRef *record.Reference
+	err = m.storer.Update(func(batch storage.Batch) error {
+		deactivationRef, err = batch.SetRecord(&rec)
+		if err != nil {
+			return errors.Wrap(err, "failed to store deactivation record")
+		}
+		err = batch.SetRequestResult(&requestRef, deactivationRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store request result")
+		}
+		classIndex.LatestStateRef = *deactivationRef
+		err = batch.AppendHistory(&classRef, deactivationRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline history")
+		}
+		err = batch.SetClassIndex(&classRef, classIndex)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline index")
+		}
+		return nil
+	})
 	if err != nil {
-		// TODO: add transaction
-		return nil, errors.New("failed to store lifeline index")
+		return nil, err
 	}
 
 	return deactivationRef, nil
@@ -332,7 +456,7 @@ func (m *LedgerArtifactManager) DeactivateClass(
 func (m *LedgerArtifactManager) UpdateClass(
 	requestRef, classRef, codeRef record.Reference, migrationRefs []record.Reference,
 ) (*record.Reference, error) {
-	err := m.checkRequestRecord(&requestRef)
+	err := m.checkRequestRecord(&requestRef, &classRef)
 	if err != nil {
 		return nil, err
 	}
@@ -370,16 +494,30 @@ func (m *LedgerArtifactManager) UpdateClass(
 		Migrations: migrationRefs,
 	}
 
-	amendRef, err := m.storeRecord(&rec)
-	if err != nil {
-		return nil, errors.New("failed to store amend record")
-	}
-	classIndex.LatestStateRef = *amendRef
-	classIndex.AmendRefs = append(classIndex.AmendRefs, *amendRef)
-	err = m.storer.SetClassIndex(&classRef, classIndex)
+	var amendRef *record.Reference
+	err = m.storer.Update(func(batch storage.Batch) error {
+		amendRef, err = batch.SetRecord(&rec)
+		if err != nil {
+			return errors.Wrap(err, "failed to store amend record")
+		}
+		err = batch.SetRequestResult(&requestRef, amendRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store request result")
+		}
+		classIndex.LatestStateRef = *amendRef
+		classIndex.AmendRefs = append(classIndex.AmendRefs, *amendRef)
+		err = batch.AppendHistory(&classRef, amendRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline history")
+		}
+		err = batch.SetClassIndex(&classRef, classIndex)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline index")
+		}
+		return nil
+	})
 	if err != nil {
-		// TODO: add transaction
-		return nil, errors.New("failed to store lifeline index")
+		return nil, err
 	}
 
 	return amendRef, nil
@@ -392,12 +530,12 @@ func (m *LedgerArtifactManager) UpdateClass(
 func (m *LedgerArtifactManager) ActivateObj(
 	requestRef, classRef record.Reference, memory record.Memory,
 ) (*record.Reference, error) {
-	err := m.checkRequestRecord(&requestRef)
+	err := m.checkRequestRecord(&requestRef, &classRef)
 	if err != nil {
 		return nil, err
 	}
 
-	_, _, _, err = m.getActiveClass(classRef)
+	_, _, classIndex, err := m.getActiveClass(classRef)
 	if err != nil {
 		return nil, err
 	}
@@ -414,16 +552,28 @@ func (m *LedgerArtifactManager) ActivateObj(
 		Memory:              memory,
 	}
 
-	objRef, err := m.storeRecord(&rec)
-	if err != nil {
-		return nil, err
-	}
-	err = m.storer.SetObjectIndex(objRef, &index.ObjectLifeline{
-		ClassRef:       classRef,
-		LatestStateRef: *objRef,
+	var objRef *record.Reference
+	err = m.storer.Update(func(batch storage.Batch) error {
+		objRef, err = batch.SetRecord(&rec)
+		if err != nil {
+			return errors.Wrap(err, "record store failed")
+		}
+		err = batch.SetRequestResult(&requestRef, objRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store request result")
+		}
+		err = batch.SetObjectIndex(objRef, &index.ObjectLifeline{
+			ClassRef:       classRef,
+			LatestStateRef: *objRef,
+			ClassStateRef:  classIndex.LatestStateRef,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline index")
+		}
+		return nil
 	})
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to store lifeline index")
+		return nil, err
 	}
 
 	return objRef, nil
@@ -434,11 +584,24 @@ func (m *LedgerArtifactManager) ActivateObj(
 //
 // Deactivated object cannot be changed.
 func (m *LedgerArtifactManager) DeactivateObj(requestRef, objRef record.Reference) (*record.Reference, error) {
-	err := m.checkRequestRecord(&requestRef)
+	err := m.checkRequestRecord(&requestRef, &objRef)
+	if err != nil {
+		return nil, err
+	}
+	var deactivationRef *record.Reference
+	err = retryOnConflict(func() (err error) {
+		deactivationRef, err = m.deactivateObj(requestRef, objRef)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
+	return deactivationRef, nil
+}
 
+// deactivateObj stores object deactivation. ErrConflict is returned if the object lifeline was changed after it was
+// read.
+func (m *LedgerArtifactManager) deactivateObj(requestRef, objRef record.Reference) (*record.Reference, error) {
 	_, _, objIndex, err := m.getActiveObject(objRef)
 	if err != nil {
 		return nil, err
@@ -458,15 +621,33 @@ func (m *LedgerArtifactManager) DeactivateObj(requestRef, objRef record.Referenc
 			},
 		},
 	}
-	deactivationRef, err := m.storeRecord(&rec)
-	if err != nil {
-		return nil, errors.New("failed to store deactivation record")
-	}
-	objIndex.LatestStateRef = *deactivationRef
-	err = m.storer.SetObjectIndex(&objRef, objIndex)
+	var deactivationRef *record.Reference
+	err = m.storer.Update(func(batch storage.Batch) error {
+		err = batch.CheckObjectIndex(&objRef, objIndex)
+		if err != nil {
+			return err
+		}
+		deactivationRef, err = batch.SetRecord(&rec)
+		if err != nil {
+			return errors.Wrap(err, "failed to store deactivation record")
+		}
+		err = batch.SetRequestResult(&requestRef, deactivationRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store request result")
+		}
+		objIndex.LatestStateRef = *deactivationRef
+		err = batch.AppendHistory(&objRef, deactivationRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline history")
+		}
+		err = batch.SetObjectIndex(&objRef, objIndex)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline index")
+		}
+		return nil
+	})
 	if err != nil {
-		// TODO: add transaction
-		return nil, errors.New("failed to store lifeline index")
+		return nil, err
 	}
 	return deactivationRef, nil
 }
@@ -476,10 +657,53 @@ func (m *LedgerArtifactManager) DeactivateObj(requestRef, objRef record.Referenc
 //
 // This will nullify all the object's append delegates. VM is responsible for collecting all appends and adding
 // them to the new memory manually if its required.
+//
+// If object lifeline is changed concurrently, the amend is retried on the new latest state, so concurrent changes
+// are not lost. ErrConflict is returned if all retries conflicted.
 func (m *LedgerArtifactManager) UpdateObj(
 	requestRef, objRef record.Reference, memory record.Memory,
 ) (*record.Reference, error) {
-	err := m.checkRequestRecord(&requestRef)
+	var amendRef *record.Reference
+	err := retryOnConflict(func() (err error) {
+		amendRef, err = m.updateObj(requestRef, objRef, nil, memory)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return amendRef, nil
+}
+
+// CompareAndUpdateObj works as UpdateObj, but object is updated only if stateRef is its latest state. Otherwise
+// ErrConflict is returned and nothing is stored.
+func (m *LedgerArtifactManager) CompareAndUpdateObj(
+	requestRef, objRef, stateRef record.Reference, memory record.Memory,
+) (*record.Reference, error) {
+	return m.updateObj(requestRef, objRef, &stateRef, memory)
+}
+
+// maxConflictRetries is the number of attempts made by mutations retried on concurrent lifeline changes.
+const maxConflictRetries = 10
+
+// retryOnConflict calls f until it returns an error other than ErrConflict. ErrConflict is returned if all
+// maxConflictRetries attempts conflicted.
+func retryOnConflict(f func() error) error {
+	var err error
+	for i := 0; i < maxConflictRetries; i++ {
+		err = f()
+		if errors.Cause(err) != ErrConflict {
+			return err
+		}
+	}
+	return err
+}
+
+// updateObj stores object amend. If stateRef is provided, it should be the latest state of the object. Amend is
+// stored only if object lifeline is not changed after it was read, otherwise ErrConflict is returned.
+func (m *LedgerArtifactManager) updateObj(
+	requestRef, objRef record.Reference, stateRef *record.Reference, memory record.Memory,
+) (*record.Reference, error) {
+	err := m.checkRequestRecord(&requestRef, &objRef)
 	if err != nil {
 		return nil, err
 	}
@@ -488,6 +712,9 @@ func (m *LedgerArtifactManager) UpdateObj(
 	if err != nil {
 		return nil, err
 	}
+	if stateRef != nil && objIndex.LatestStateRef.IsNotEqual(*stateRef) {
+		return nil, errors.Wrap(ErrConflict, "object state is not the latest one")
+	}
 
 	rec := record.ObjectAmendRecord{
 		AmendRecord: record.AmendRecord{
@@ -505,16 +732,34 @@ func (m *LedgerArtifactManager) UpdateObj(
 		NewMemory: memory,
 	}
 
-	amendRef, err := m.storeRecord(&rec)
-	if err != nil {
-		return nil, errors.New("failed to store amend record")
-	}
-	objIndex.LatestStateRef = *amendRef
-	objIndex.AppendRefs = []record.Reference{}
-	err = m.storer.SetObjectIndex(&objRef, objIndex)
+	var amendRef *record.Reference
+	err = m.storer.Update(func(batch storage.Batch) error {
+		err = batch.CheckObjectIndex(&objRef, objIndex)
+		if err != nil {
+			return err
+		}
+		amendRef, err = batch.SetRecord(&rec)
+		if err != nil {
+			return errors.Wrap(err, "failed to store amend record")
+		}
+		err = batch.SetRequestResult(&requestRef, amendRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store request result")
+		}
+		objIndex.LatestStateRef = *amendRef
+		objIndex.AppendRefs = []record.Reference{}
+		err = batch.AppendHistory(&objRef, amendRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline history")
+		}
+		err = batch.SetObjectIndex(&objRef, objIndex)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline index")
+		}
+		return nil
+	})
 	if err != nil {
-		// TODO: add transaction
-		return nil, errors.New("failed to store lifeline index")
+		return nil, err
 	}
 	return amendRef, nil
 }
@@ -524,19 +769,46 @@ func (m *LedgerArtifactManager) UpdateObj(
 //
 // Object's delegates will be provided by GetLatestObj. Any object update will nullify all the object's append
 // delegates. VM is responsible for collecting all appends and adding them to the new memory manually if its
-// required.
+// required, or delegates can be folded into object memory by ConsolidateDelegates. If delegate limit is set (see
+// SetDelegateLimit), reaching it consolidates delegates automatically. Automatic consolidation is stored as a result
+// of a request made by the append record.
+//
+// If object lifeline is changed concurrently, the append is retried on the new latest state. ErrConflict is returned
+// if all retries conflicted.
 func (m *LedgerArtifactManager) AppendObjDelegate(
 	requestRef, objRef record.Reference, memory record.Memory,
 ) (*record.Reference, error) {
-	err := m.checkRequestRecord(&requestRef)
+	err := m.checkRequestRecord(&requestRef, &objRef)
 	if err != nil {
 		return nil, err
 	}
+	var appendRef *record.Reference
+	err = retryOnConflict(func() (err error) {
+		appendRef, err = m.appendObjDelegate(requestRef, objRef, memory)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appendRef, nil
+}
 
-	_, _, objIndex, err := m.getActiveObject(objRef)
+// appendObjDelegate stores object append and consolidation if delegate limit is reached. ErrConflict is returned if
+// the object lifeline was changed after it was read.
+func (m *LedgerArtifactManager) appendObjDelegate(
+	requestRef, objRef record.Reference, memory record.Memory,
+) (*record.Reference, error) {
+	objActivateRec, objStateRec, objIndex, err := m.getActiveObject(objRef)
 	if err != nil {
 		return nil, err
 	}
+	var merged record.Memory
+	if m.delegateLimit > 0 && len(objIndex.AppendRefs)+1 >= m.delegateLimit {
+		merged, err = m.mergeDelegates(m.delegateMerge, objActivateRec, objStateRec, objIndex, memory)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	rec := record.ObjectAppendRecord{
 		AmendRecord: record.AmendRecord{
@@ -554,15 +826,39 @@ func (m *LedgerArtifactManager) AppendObjDelegate(
 		AppendMemory: memory,
 	}
 
-	appendRef, err := m.storeRecord(&rec)
-	if err != nil {
-		return nil, errors.New("failed to store append record")
-	}
-	objIndex.AppendRefs = append(objIndex.AppendRefs, *appendRef)
-	err = m.storer.SetObjectIndex(&objRef, objIndex)
+	var appendRef *record.Reference
+	err = m.storer.Update(func(batch storage.Batch) error {
+		err = batch.CheckObjectIndex(&objRef, objIndex)
+		if err != nil {
+			return err
+		}
+		appendRef, err = batch.SetRecord(&rec)
+		if err != nil {
+			return errors.Wrap(err, "failed to store append record")
+		}
+		err = batch.SetRequestResult(&requestRef, appendRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store request result")
+		}
+		objIndex.AppendRefs = append(objIndex.AppendRefs, *appendRef)
+		err = batch.AppendHistory(&objRef, appendRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline history")
+		}
+		if merged != nil {
+			err = storeAutoConsolidation(batch, *appendRef, objRef, objIndex, merged)
+			if err != nil {
+				return err
+			}
+		}
+		err = batch.SetObjectIndex(&objRef, objIndex)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline index")
+		}
+		return nil
+	})
 	if err != nil {
-		// TODO: add transaction
-		return nil, errors.New("failed to store lifeline index")
+		return nil, err
 	}
 	return appendRef, nil
 }
@@ -588,7 +884,7 @@ func (m *LedgerArtifactManager) GetExactObj( // nolint: gocyclo
 		codeRef = rec.NewCode
 		classHeadRef = rec.HeadRecord
 	default:
-		return nil, nil, errors.New("wrong class reference")
+		return nil, nil, errors.Wrap(ErrWrongRecordType, "wrong class reference")
 	}
 	code, err := m.getCodeRecordCode(codeRef)
 	if err != nil {
@@ -609,15 +905,15 @@ func (m *LedgerArtifactManager) GetExactObj( // nolint: gocyclo
 		memory = rec.NewMemory
 		objectHeadRef = rec.HeadRecord
 	default:
-		return nil, nil, errors.New("wrong object reference")
+		return nil, nil, errors.Wrap(ErrWrongRecordType, "wrong object reference")
 	}
 	objectIndex, err := m.storer.GetObjectIndex(&objectHeadRef)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "object index not found")
+		return nil, nil, indexError(err, "object index is not found")
 	}
 
 	if objectIndex.ClassRef.IsNotEqual(classHeadRef) {
-		return nil, nil, errors.New("the object does not belong to the class")
+		return nil, nil, ErrClassMismatch
 	}
 
 	return code, memory, nil
@@ -628,21 +924,51 @@ func (m *LedgerArtifactManager) GetExactObj( // nolint: gocyclo
 // an error should be returned.
 //
 // Returned descriptors will provide methods for fetching migrations and appends relative to the provided states.
+//
+// If migration runner is set, pending class migrations are applied to the object before descriptors are returned
+// (see SetMigrationRunner).
 func (m *LedgerArtifactManager) GetLatestObj(
 	objectRef, storedClassState, storedObjState record.Reference,
 ) (*ClassDescriptor, *ObjectDescriptor, error) {
 	var (
 		class  *ClassDescriptor
 		object *ObjectDescriptor
-	)
 
-	objActivateRec, objStateRec, objIndex, err := m.getActiveObject(objectRef)
+		objActivateRec   *record.ObjectActivateRecord
+		objStateRec      *record.ObjectAmendRecord
+		objIndex         *index.ObjectLifeline
+		classActivateRec *record.ClassActivateRecord
+		classStateRec    *record.ClassAmendRecord
+		classIndex       *index.ClassLifeline
+		err              error
+	)
+	// Object is fetched again if it was changed concurrently with migration.
+	err = retryOnConflict(func() error {
+		objActivateRec, objStateRec, objIndex, err = m.getActiveObject(objectRef)
+		if err != nil {
+			return err
+		}
+		classActivateRec, classStateRec, classIndex, err = m.getActiveClass(objIndex.ClassRef)
+		if err != nil {
+			return err
+		}
+		migratedRec, err := m.migrateObject(objectRef, objActivateRec, objStateRec, objIndex, classIndex)
+		if err != nil {
+			return err
+		}
+		if migratedRec != nil {
+			objStateRec = migratedRec
+		}
+		return nil
+	})
 	if err != nil {
 		return nil, nil, err
 	}
-	classActivateRec, classStateRec, classIndex, err := m.getActiveClass(objIndex.ClassRef)
-	if err != nil {
-		return nil, nil, err
+
+	// Migrations applied by artifact manager should not be applied by VM again.
+	fromState := storedClassState
+	if m.migrationRunner != nil && objIndex.ClassStateRef.Record.Hash != nil {
+		fromState = classIndex.LatestStateRef
 	}
 
 	if storedClassState.IsNotEqual(classIndex.LatestStateRef) {
@@ -653,7 +979,7 @@ func (m *LedgerArtifactManager) GetLatestObj(
 			},
 
 			manager:           m,
-			fromState:         storedClassState,
+			fromState:         fromState,
 			activateRecord:    classActivateRec,
 			latestAmendRecord: classStateRec,
 			lifelineIndex:     classIndex,
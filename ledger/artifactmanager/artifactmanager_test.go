@@ -18,13 +18,15 @@ package artifactmanager
 
 import (
 	"math/rand"
-	"os"
+	"sync"
 	"testing"
 
+	"github.com/pkg/errors"
+
 	"github.com/insolar/insolar/ledger/index"
 	"github.com/insolar/insolar/ledger/record"
 	"github.com/insolar/insolar/ledger/storage"
-	"github.com/insolar/insolar/ledger/storage/leveldb"
+	"github.com/insolar/insolar/ledger/storage/memory"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -32,15 +34,52 @@ func genRandomRef() *record.Reference {
 	return &record.Reference{Domain: record.ID{Pulse: record.PulseNum(rand.Int())}}
 }
 
-func prepareTestArtifactManager() (storage.LedgerStorer, ArtifactManager, *record.Reference) {
-	if err := leveldb.DropDB(); err != nil {
-		os.Exit(1)
+// storeRequest stores new request record targeting provided reference.
+func storeRequest(ledger storage.LedgerStorer, target record.Reference) *record.Reference {
+	ref, err := ledger.SetRecord(&record.CallRequest{
+		RequestRecord: record.RequestRecord{Requester: *genRandomRef(), Target: target},
+	})
+	if err != nil {
+		panic(err)
 	}
+	return ref
+}
 
-	ledger, _ := leveldb.InitDB()
+func prepareTestArtifactManager() (storage.LedgerStorer, ArtifactManager, *record.Reference) {
+	ledger := memory.NewMemLedger()
 	manager := LedgerArtifactManager{storer: ledger}
 
-	return ledger, &manager, genRandomRef()
+	return ledger, &manager, storeRequest(ledger, record.Reference{})
+}
+
+func mustNoError(t *testing.T, err error) {
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+}
+
+// storedHistory returns lifeline history stored for the head.
+func storedHistory(t *testing.T, ledger storage.LedgerStorer, headRef *record.Reference) []record.Reference {
+	var refs []record.Reference
+	err := ledger.IterateHistory(headRef, func(ref *record.Reference) error {
+		refs = append(refs, *ref)
+		return nil
+	})
+	mustNoError(t, err)
+	return refs
+}
+
+// activateTestObject deploys code, activates class and its object with provided memory.
+func activateTestObject(
+	t *testing.T, ledger storage.LedgerStorer, manager ArtifactManager, memory record.Memory,
+) (classRef, objRef *record.Reference) {
+	codeRef, err := manager.DeployCode(*storeRequest(ledger, record.Reference{}), map[record.ArchType][]byte{1: {1}})
+	mustNoError(t, err)
+	classRef, err = manager.ActivateClass(*storeRequest(ledger, record.Reference{}), *codeRef, nil)
+	mustNoError(t, err)
+	objRef, err = manager.ActivateObj(*storeRequest(ledger, *classRef), *classRef, memory)
+	mustNoError(t, err)
+	return classRef, objRef
 }
 
 func TestLedgerArtifactManager_DeployCode(t *testing.T) {
@@ -58,8 +97,186 @@ func TestLedgerArtifactManager_DeployCode(t *testing.T) {
 				},
 			},
 		},
-		TargetedCode: codeMap,
+		CodeBlobs: map[record.ArchType][]byte{1: record.BlobHash([]byte{1})},
+	})
+}
+
+func TestLedgerArtifactManager_DeployCodeSharesBlobs(t *testing.T) {
+	ledger, manager, requestRef := prepareTestArtifactManager()
+	manager.SetArchPref([]record.ArchType{1})
+	codeMap := map[record.ArchType][]byte{1: {1, 2, 3}}
+	codeRef1, err := manager.DeployCode(*requestRef, codeMap)
+	assert.NoError(t, err)
+	codeRef2, err := manager.DeployCode(*storeRequest(ledger, record.Reference{}), codeMap)
+	assert.NoError(t, err)
+	assert.NotEqual(t, codeRef1.Key(), codeRef2.Key())
+
+	refs, err := ledger.GetBlobRefs(record.BlobHash(codeMap[1]))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(2), refs)
+
+	code, err := manager.(*LedgerArtifactManager).getCodeRecordCode(*codeRef1)
+	assert.NoError(t, err)
+	assert.Equal(t, codeMap[1], code)
+}
+
+func TestLedgerArtifactManager_RegisterRequest(t *testing.T) {
+	ledger, manager, _ := prepareTestArtifactManager()
+	request := record.CallRequest{
+		RequestRecord: record.RequestRecord{Target: *genRandomRef()},
+		ParamMemory:   record.Memory{1, 2},
+	}
+	requestRef, err := manager.RegisterRequest(&request)
+	assert.NoError(t, err)
+	rec, err := ledger.GetRecord(requestRef)
+	assert.NoError(t, err)
+	assert.Equal(t, &request, rec)
+	_, err = ledger.GetRequestResult(requestRef)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func TestLedgerArtifactManager_GetCode(t *testing.T) {
+	ledger, manager, requestRef := prepareTestArtifactManager()
+	manager.SetArchPref([]record.ArchType{2, 1})
+	codeRef, err := manager.DeployCode(*requestRef, map[record.ArchType][]byte{1: {1}, 2: {2}})
+	assert.NoError(t, err)
+	code, err := manager.GetCode(*codeRef)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{2}, code)
+
+	notCodeRef, _ := ledger.SetRecord(&record.ClassActivateRecord{})
+	_, err = manager.GetCode(*notCodeRef)
+	assert.Equal(t, ErrWrongRecordType, errors.Cause(err))
+}
+
+func TestLedgerArtifactManager_VerifiesRequestRecord(t *testing.T) {
+	ledger, manager, _ := prepareTestArtifactManager()
+	objRef, _ := ledger.SetRecord(&record.ObjectActivateRecord{})
+	ledger.SetObjectIndex(objRef, &index.ObjectLifeline{
+		LatestStateRef: *objRef,
+	})
+
+	_, err := manager.UpdateObj(*genRandomRef(), *objRef, nil)
+	assert.Equal(t, ErrRequestNotFound, err)
+	_, err = manager.UpdateObj(*objRef, *objRef, nil)
+	assert.Equal(t, ErrNotRequest, err)
+	_, err = manager.UpdateObj(*storeRequest(ledger, *genRandomRef()), *objRef, nil)
+	assert.Equal(t, ErrRequestTargetMismatch, err)
+
+	requestRef := storeRequest(ledger, *objRef)
+	updateRef, err := manager.UpdateObj(*requestRef, *objRef, nil)
+	assert.NoError(t, err)
+	resultRef, err := ledger.GetRequestResult(requestRef)
+	assert.NoError(t, err)
+	assert.Equal(t, updateRef.Key(), resultRef.Key())
+	_, err = manager.UpdateObj(*requestRef, *objRef, nil)
+	assert.Equal(t, ErrRequestHasResult, errors.Cause(err))
+}
+
+func TestLedgerArtifactManager_CompareAndUpdateObj(t *testing.T) {
+	ledger, manager, _ := prepareTestArtifactManager()
+	objRef, _ := ledger.SetRecord(&record.ObjectActivateRecord{})
+	ledger.SetObjectIndex(objRef, &index.ObjectLifeline{
+		LatestStateRef: *objRef,
 	})
+
+	amendRef, err := manager.CompareAndUpdateObj(*storeRequest(ledger, *objRef), *objRef, *objRef, record.Memory{1})
+	assert.NoError(t, err)
+	_, err = manager.CompareAndUpdateObj(*storeRequest(ledger, *objRef), *objRef, *objRef, record.Memory{2})
+	assert.Equal(t, ErrConflict, errors.Cause(err))
+	_, err = manager.CompareAndUpdateObj(*storeRequest(ledger, *objRef), *objRef, *amendRef, record.Memory{2})
+	assert.NoError(t, err)
+
+	assert.Len(t, storedHistory(t, ledger, objRef), 2)
+}
+
+func TestLedgerArtifactManager_ConcurrentUpdatesAreNotLost(t *testing.T) {
+	ledger, manager, _ := prepareTestArtifactManager()
+	objRef, _ := ledger.SetRecord(&record.ObjectActivateRecord{})
+	ledger.SetObjectIndex(objRef, &index.ObjectLifeline{
+		LatestStateRef: *objRef,
+	})
+
+	const workers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		requestRef := storeRequest(ledger, *objRef)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := manager.UpdateObj(*requestRef, *objRef, record.Memory{byte(i)})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Len(t, storedHistory(t, ledger, objRef), workers)
+}
+
+// conflictingStorer fails every object index check with ErrConflict and counts the checks.
+type conflictingStorer struct {
+	storage.LedgerStorer
+	checks int
+}
+
+func (s *conflictingStorer) Update(f func(storage.Batch) error) error {
+	return s.LedgerStorer.Update(func(batch storage.Batch) error {
+		return f(&conflictingBatch{Batch: batch, s: s})
+	})
+}
+
+type conflictingBatch struct {
+	storage.Batch
+	s *conflictingStorer
+}
+
+func (b *conflictingBatch) CheckObjectIndex(*record.Reference, *index.ObjectLifeline) error {
+	b.s.checks++
+	return ErrConflict
+}
+
+func TestLedgerArtifactManager_UpdateObjRetriesAreBounded(t *testing.T) {
+	ledger, am, _ := prepareTestArtifactManager()
+	_, objRef := activateTestObject(t, ledger, am, record.Memory{1})
+	s := &conflictingStorer{LedgerStorer: ledger}
+	manager := LedgerArtifactManager{storer: s}
+
+	_, err := manager.UpdateObj(*storeRequest(ledger, *objRef), *objRef, record.Memory{2})
+	assert.Equal(t, ErrConflict, errors.Cause(err))
+	assert.Equal(t, maxConflictRetries, s.checks)
+}
+
+func TestLedgerArtifactManager_ConcurrentMutationsWithSameRequest(t *testing.T) {
+	ledger, manager, _ := prepareTestArtifactManager()
+	objRef, _ := ledger.SetRecord(&record.ObjectActivateRecord{})
+	ledger.SetObjectIndex(objRef, &index.ObjectLifeline{
+		LatestStateRef: *objRef,
+	})
+	requestRef := storeRequest(ledger, *objRef)
+
+	const workers = 10
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := manager.UpdateObj(*requestRef, *objRef, record.Memory{byte(i)})
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.Equal(t, ErrRequestHasResult, errors.Cause(err))
+	}
+	assert.Equal(t, 1, succeeded)
 }
 
 func TestLedgerArtifactManager_ActivateClass_VerifiesRecord(t *testing.T) {
@@ -98,6 +315,7 @@ func TestLedgerArtifactManager_DeactivateClass_VerifiesRecord(t *testing.T) {
 	assert.NotNil(t, err)
 
 	notClassRef, _ := ledger.SetRecord(&record.CodeRecord{})
+	requestRef = storeRequest(ledger, *notClassRef)
 	_, err = manager.DeactivateClass(*requestRef, *notClassRef)
 	assert.NotNil(t, err)
 }
@@ -109,6 +327,7 @@ func TestLedgerArtifactManager_DeactivateClass_VerifiesClassIsActive(t *testing.
 	ledger.SetClassIndex(classRef, &index.ClassLifeline{
 		LatestStateRef: *deactivateRef,
 	})
+	requestRef = storeRequest(ledger, *classRef)
 	_, err := manager.DeactivateClass(*requestRef, *classRef)
 	assert.NotNil(t, err)
 }
@@ -120,6 +339,7 @@ func TestLedgerArtifactManager_DeactivateClass_CreatesCorrectRecord(t *testing.T
 		LatestStateRef: *classRef,
 	})
 
+	requestRef = storeRequest(ledger, *classRef)
 	deactivateRef, err := manager.DeactivateClass(*requestRef, *classRef)
 	assert.NoError(t, err)
 	deactivateRec, err := ledger.GetRecord(deactivateRef)
@@ -142,6 +362,7 @@ func TestLedgerArtifactManager_UpdateClass_VerifiesRecord(t *testing.T) {
 	_, err := manager.UpdateClass(*requestRef, record.Reference{}, record.Reference{}, nil)
 	assert.NotNil(t, err)
 	notClassRef, _ := ledger.SetRecord(&record.CodeRecord{})
+	requestRef = storeRequest(ledger, *notClassRef)
 	_, err = manager.UpdateClass(*requestRef, *notClassRef, record.Reference{}, nil)
 	assert.NotNil(t, err)
 }
@@ -154,6 +375,7 @@ func TestLedgerArtifactManager_UpdateClass_VerifiesClassIsActive(t *testing.T) {
 	ledger.SetClassIndex(classRef, &index.ClassLifeline{
 		LatestStateRef: *deactivateRef,
 	})
+	requestRef = storeRequest(ledger, *classRef)
 	_, err := manager.UpdateClass(*requestRef, *classRef, *codeRef, nil)
 	assert.NotNil(t, err)
 }
@@ -167,6 +389,7 @@ func TestLedgerArtifactManager_UpdateClass_CreatesCorrectRecord(t *testing.T) {
 	codeRef, _ := ledger.SetRecord(&record.CodeRecord{})
 	migrationRef, _ := ledger.SetRecord(&record.CodeRecord{SourceCode: "test"})
 	migrationRefs := []record.Reference{*migrationRef}
+	requestRef = storeRequest(ledger, *classRef)
 	updateRef, err := manager.UpdateClass(*requestRef, *classRef, *codeRef, migrationRefs)
 	assert.Nil(t, err)
 	updateRec, getErr := ledger.GetRecord(updateRef)
@@ -202,6 +425,7 @@ func TestLedgerArtifactManager_ActivateObj_CreatesCorrectRecord(t *testing.T) {
 	ledger.SetClassIndex(classRef, &index.ClassLifeline{
 		LatestStateRef: *classRef,
 	})
+	requestRef = storeRequest(ledger, *classRef)
 	activateRef, err := manager.ActivateObj(*requestRef, *classRef, memory)
 	assert.Nil(t, err)
 	activateRec, err := ledger.GetRecord(activateRef)
@@ -219,11 +443,89 @@ func TestLedgerArtifactManager_ActivateObj_CreatesCorrectRecord(t *testing.T) {
 	})
 }
 
+// failingIndexStorer fails every lifeline index write made through batch and remembers stored record references.
+type failingIndexStorer struct {
+	storage.LedgerStorer
+	storedRefs []*record.Reference
+}
+
+type failingIndexBatch struct {
+	storage.Batch
+	storer *failingIndexStorer
+}
+
+func (s *failingIndexStorer) Update(fn func(storage.Batch) error) error {
+	return s.LedgerStorer.Update(func(batch storage.Batch) error {
+		return fn(&failingIndexBatch{Batch: batch, storer: s})
+	})
+}
+
+func (b *failingIndexBatch) SetRecord(rec record.Record) (*record.Reference, error) {
+	ref, err := b.Batch.SetRecord(rec)
+	b.storer.storedRefs = append(b.storer.storedRefs, ref)
+	return ref, err
+}
+
+func (b *failingIndexBatch) SetClassIndex(*record.Reference, *index.ClassLifeline) error {
+	return errors.New("index write failed")
+}
+
+func (b *failingIndexBatch) SetObjectIndex(*record.Reference, *index.ObjectLifeline) error {
+	return errors.New("index write failed")
+}
+
+func TestLedgerArtifactManager_MutationsAreAtomic(t *testing.T) {
+	ledger := memory.NewMemLedger()
+	storer := &failingIndexStorer{LedgerStorer: ledger}
+	manager := LedgerArtifactManager{storer: storer}
+	requestRef := storeRequest(ledger, record.Reference{})
+
+	codeRef, _ := ledger.SetRecord(&record.CodeRecord{})
+	classRef, _ := ledger.SetRecord(&record.ClassActivateRecord{})
+	ledger.SetClassIndex(classRef, &index.ClassLifeline{LatestStateRef: *classRef})
+	objRef, _ := ledger.SetRecord(&record.ObjectActivateRecord{})
+	ledger.SetObjectIndex(objRef, &index.ObjectLifeline{ClassRef: *classRef, LatestStateRef: *objRef})
+
+	_, err := manager.ActivateClass(*requestRef, *codeRef, nil)
+	assert.Error(t, err)
+	requestRef = storeRequest(ledger, *classRef)
+	_, err = manager.UpdateClass(*requestRef, *classRef, *codeRef, nil)
+	assert.Error(t, err)
+	requestRef = storeRequest(ledger, *classRef)
+	_, err = manager.ActivateObj(*requestRef, *classRef, nil)
+	assert.Error(t, err)
+	requestRef = storeRequest(ledger, *objRef)
+	_, err = manager.UpdateObj(*requestRef, *objRef, nil)
+	assert.Error(t, err)
+	requestRef = storeRequest(ledger, *objRef)
+	_, err = manager.AppendObjDelegate(*requestRef, *objRef, nil)
+	assert.Error(t, err)
+	requestRef = storeRequest(ledger, *objRef)
+	_, err = manager.DeactivateObj(*requestRef, *objRef)
+	assert.Error(t, err)
+	requestRef = storeRequest(ledger, *classRef)
+	_, err = manager.DeactivateClass(*requestRef, *classRef)
+	assert.Error(t, err)
+
+	assert.Len(t, storer.storedRefs, 7)
+	for _, ref := range storer.storedRefs {
+		_, err = ledger.GetRecord(ref)
+		assert.Equal(t, storage.ErrNotFound, err)
+	}
+	classIndex, err := ledger.GetClassIndex(classRef)
+	assert.NoError(t, err)
+	assert.Equal(t, *classRef, classIndex.LatestStateRef)
+	objIndex, err := ledger.GetObjectIndex(objRef)
+	assert.NoError(t, err)
+	assert.Equal(t, *objRef, objIndex.LatestStateRef)
+}
+
 func TestLedgerArtifactManager_DeactivateObj_VerifiesRecord(t *testing.T) {
 	ledger, manager, requestRef := prepareTestArtifactManager()
 	_, err := manager.DeactivateClass(*requestRef, record.Reference{})
 	assert.NotNil(t, err)
 	notObjRef, _ := ledger.SetRecord(&record.ClassActivateRecord{})
+	requestRef = storeRequest(ledger, *notObjRef)
 	_, err = manager.DeactivateClass(*requestRef, *notObjRef)
 	assert.NotNil(t, err)
 }
@@ -235,6 +537,7 @@ func TestLedgerArtifactManager_DeactivateObj_VerifiesObjectIsActive(t *testing.T
 	ledger.SetObjectIndex(objRef, &index.ObjectLifeline{
 		LatestStateRef: *deactivateRef,
 	})
+	requestRef = storeRequest(ledger, *objRef)
 	_, err := manager.DeactivateObj(*requestRef, *objRef)
 	assert.NotNil(t, err)
 }
@@ -245,6 +548,7 @@ func TestLedgerArtifactManager_DeactivateObj_CreatesCorrectRecord(t *testing.T)
 	ledger.SetObjectIndex(objRef, &index.ObjectLifeline{
 		LatestStateRef: *objRef,
 	})
+	requestRef = storeRequest(ledger, *objRef)
 	deactivateRef, err := manager.DeactivateObj(*requestRef, *objRef)
 	assert.Nil(t, err)
 	deactivateRec, err := ledger.GetRecord(deactivateRef)
@@ -267,6 +571,7 @@ func TestLedgerArtifactManager_UpdateObj_VerifiesRecord(t *testing.T) {
 	_, err := manager.UpdateObj(*requestRef, record.Reference{}, nil)
 	assert.NotNil(t, err)
 	notObjRef, _ := ledger.SetRecord(&record.CodeRecord{})
+	requestRef = storeRequest(ledger, *notObjRef)
 	_, err = manager.UpdateObj(*requestRef, *notObjRef, nil)
 	assert.NotNil(t, err)
 }
@@ -278,6 +583,7 @@ func TestLedgerArtifactManager_UpdateObj_VerifiesObjectIsActive(t *testing.T) {
 	ledger.SetObjectIndex(objRef, &index.ObjectLifeline{
 		LatestStateRef: *deactivateRef,
 	})
+	requestRef = storeRequest(ledger, *objRef)
 	_, err := manager.UpdateObj(*requestRef, *objRef, nil)
 	assert.NotNil(t, err)
 }
@@ -289,6 +595,7 @@ func TestLedgerArtifactManager_UpdateObj_CreatesCorrectRecord(t *testing.T) {
 		LatestStateRef: *objRef,
 	})
 	memory := record.Memory{1, 2, 3}
+	requestRef = storeRequest(ledger, *objRef)
 	updateRef, err := manager.UpdateObj(*requestRef, *objRef, memory)
 	assert.Nil(t, err)
 	updateRec, err := ledger.GetRecord(updateRef)
@@ -312,6 +619,7 @@ func TestLedgerArtifactManager_AppendObjDelegate_VerifiesRecord(t *testing.T) {
 	_, err := manager.AppendObjDelegate(*requestRef, record.Reference{}, nil)
 	assert.NotNil(t, err)
 	notObjRef, _ := ledger.SetRecord(&record.CodeRecord{})
+	requestRef = storeRequest(ledger, *notObjRef)
 	_, err = manager.AppendObjDelegate(*requestRef, *notObjRef, nil)
 	assert.NotNil(t, err)
 }
@@ -323,6 +631,7 @@ func TestLedgerArtifactManager_AppendObjDelegate_VerifiesClassIsActive(t *testin
 	ledger.SetObjectIndex(objRef, &index.ObjectLifeline{
 		LatestStateRef: *deactivateRef,
 	})
+	requestRef = storeRequest(ledger, *objRef)
 	_, err := manager.AppendObjDelegate(*requestRef, *objRef, nil)
 	assert.NotNil(t, err)
 }
@@ -334,6 +643,7 @@ func TestLedgerArtifactManager_AppendObjDelegate_CreatesCorrectRecord(t *testing
 		LatestStateRef: *objRef,
 	})
 	memory := record.Memory{1, 2, 3}
+	requestRef = storeRequest(ledger, *objRef)
 	appendRef, err := manager.AppendObjDelegate(*requestRef, *objRef, memory)
 	assert.Nil(t, err)
 	appendRec, _ := ledger.GetRecord(appendRef)
@@ -502,3 +812,35 @@ func TestLedgerArtifactManager_GetExactObj_ReturnsCorrectData(t *testing.T) {
 	assert.Equal(t, []byte{1}, code)
 	assert.Equal(t, memoryRec, memory)
 }
+
+func TestLedgerArtifactManager_ErrorCauses(t *testing.T) {
+	ledger, manager, _ := prepareTestArtifactManager()
+	manager.SetArchPref([]record.ArchType{1})
+	codeRef, _ := ledger.SetRecord(&record.CodeRecord{TargetedCode: map[record.ArchType][]byte{2: {2}}})
+	classRef, _ := ledger.SetRecord(&record.ClassActivateRecord{CodeRecord: *codeRef})
+	ledger.SetClassIndex(classRef, &index.ClassLifeline{LatestStateRef: *classRef})
+	objRef, _ := ledger.SetRecord(&record.ObjectActivateRecord{})
+	ledger.SetObjectIndex(objRef, &index.ObjectLifeline{ClassRef: *classRef, LatestStateRef: *objRef})
+	deactivatedRef, _ := ledger.SetRecord(&record.ObjectActivateRecord{Memory: record.Memory{1}})
+	deactivateRef, _ := ledger.SetRecord(&record.DeactivationRecord{})
+	ledger.SetObjectIndex(deactivatedRef, &index.ObjectLifeline{LatestStateRef: *deactivateRef})
+	noIndexRef, _ := ledger.SetRecord(&record.ObjectActivateRecord{Memory: record.Memory{2}})
+	otherCodeRef, _ := ledger.SetRecord(&record.CodeRecord{TargetedCode: map[record.ArchType][]byte{1: {1}}})
+	otherClassRef, _ := ledger.SetRecord(&record.ClassActivateRecord{CodeRecord: *otherCodeRef})
+
+	_, err := manager.UpdateObj(*storeRequest(ledger, *classRef), *objRef, nil)
+	assert.Equal(t, ErrRequestTargetMismatch, errors.Cause(err))
+	target := *genRandomRef()
+	_, err = manager.UpdateObj(*storeRequest(ledger, target), target, nil)
+	assert.Equal(t, ErrNotFound, errors.Cause(err))
+	_, err = manager.UpdateObj(*storeRequest(ledger, *classRef), *classRef, nil)
+	assert.Equal(t, ErrWrongRecordType, errors.Cause(err))
+	_, err = manager.UpdateObj(*storeRequest(ledger, *deactivatedRef), *deactivatedRef, nil)
+	assert.Equal(t, ErrDeactivated, errors.Cause(err))
+	_, err = manager.UpdateObj(*storeRequest(ledger, *noIndexRef), *noIndexRef, nil)
+	assert.Equal(t, ErrInconsistentIndex, errors.Cause(err))
+	_, _, err = manager.GetExactObj(*classRef, *objRef)
+	assert.Equal(t, ErrArchUnavailable, errors.Cause(err))
+	_, _, err = manager.GetExactObj(*otherClassRef, *objRef)
+	assert.Equal(t, ErrClassMismatch, errors.Cause(err))
+}
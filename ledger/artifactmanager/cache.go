@@ -0,0 +1,331 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package artifactmanager
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// CacheConfig configures cache of decoded records, lifelines and code blobs.
+type CacheConfig struct {
+	// MaxSize is the maximum total size of cached entries in bytes. Entry size is the size of its serialized form.
+	// Entries which don't fit are not cached. Zero MaxSize disables cache.
+	MaxSize int
+}
+
+// CacheStats contains cache usage counters.
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Size is the current total size of cached entries in bytes.
+	Size int
+	// Entries is the current number of cached entries.
+	Entries int
+}
+
+// cache key prefixes
+const (
+	cacheScopeRecord         byte = 1
+	cacheScopeClassLifeline  byte = 2
+	cacheScopeObjectLifeline byte = 3
+	cacheScopeBlob           byte = 4
+)
+
+func cacheKey(scope byte, key []byte) string {
+	return string(append([]byte{scope}, key...))
+}
+
+type cacheEntry struct {
+	key   string
+	value interface{}
+	size  int
+}
+
+// lruCache is a size bounded cache which evicts least recently used entries first.
+type lruCache struct {
+	lock    sync.Mutex
+	maxSize int
+	size    int
+	order   *list.List // front is the most recently used
+	entries map[string]*list.Element
+	// version is incremented on every invalidation. Values loaded before invalidation are not cached.
+	version uint64
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func newLRUCache(maxSize int) *lruCache {
+	return &lruCache{
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// get returns cached value and current cache version. Version should be passed to put when the missing value is
+// loaded.
+func (c *lruCache) get(key string) (interface{}, uint64, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, c.version, false
+	}
+	c.hits++
+	c.order.MoveToFront(el)
+	return el.Value.(*cacheEntry).value, c.version, true
+}
+
+// put caches value loaded at provided cache version. Value is dropped if cache was invalidated after that.
+func (c *lruCache) put(key string, value interface{}, size int, version uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if version != c.version || size > c.maxSize {
+		return
+	}
+	if el, ok := c.entries[key]; ok {
+		c.remove(el)
+	}
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, value: value, size: size})
+	c.size += size
+	for c.size > c.maxSize {
+		c.remove(c.order.Back())
+		c.evictions++
+	}
+}
+
+func (c *lruCache) remove(el *list.Element) {
+	entry := c.order.Remove(el).(*cacheEntry)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+}
+
+// invalidate removes provided keys from cache.
+func (c *lruCache) invalidate(keys []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.version++
+	for _, key := range keys {
+		if el, ok := c.entries[key]; ok {
+			c.remove(el)
+		}
+	}
+}
+
+func (c *lruCache) stats() CacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Size:      c.size,
+		Entries:   len(c.entries),
+	}
+}
+
+// cachingStorer caches storage reads. Records and blobs are immutable, so they are never invalidated. Lifelines are
+// invalidated when they are written through the storer.
+//
+// Cached records are shared between callers and should not be modified. Object memory is copied by descriptors
+// before it is returned (see ObjectDescriptor.GetMemory), other record fields like code are returned as is. Lifelines
+// are copied, because artifact manager modifies them in place before storing.
+type cachingStorer struct {
+	storage.LedgerStorer
+	cache *lruCache
+}
+
+// GetRecord returns cached record or fetches it from storage.
+func (s *cachingStorer) GetRecord(ref *record.Reference) (record.Record, error) {
+	key := cacheKey(cacheScopeRecord, ref.Key())
+	value, version, ok := s.cache.get(key)
+	if ok {
+		return value.(record.Record), nil
+	}
+	rs, ok := s.LedgerStorer.(storage.RawStorer)
+	if !ok {
+		return s.LedgerStorer.GetRecord(ref)
+	}
+	// Stored raw record is fetched to get its size without encoding the record again.
+	raw, err := rs.GetRawRecord(ref)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := raw.Decode()
+	if err != nil {
+		return nil, errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
+	}
+	s.cache.put(key, rec, len(raw.Data), version)
+	return rec, nil
+}
+
+// GetClassIndex returns copy of cached class lifeline or fetches it from storage.
+func (s *cachingStorer) GetClassIndex(ref *record.Reference) (*index.ClassLifeline, error) {
+	key := cacheKey(cacheScopeClassLifeline, ref.Key())
+	value, version, ok := s.cache.get(key)
+	if ok {
+		return copyClassLifeline(value.(*index.ClassLifeline)), nil
+	}
+	idx, err := s.LedgerStorer.GetClassIndex(ref)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := index.EncodeClassLifeline(idx)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(key, copyClassLifeline(idx), len(buf), version)
+	return idx, nil
+}
+
+// GetObjectIndex returns copy of cached object lifeline or fetches it from storage.
+func (s *cachingStorer) GetObjectIndex(ref *record.Reference) (*index.ObjectLifeline, error) {
+	key := cacheKey(cacheScopeObjectLifeline, ref.Key())
+	value, version, ok := s.cache.get(key)
+	if ok {
+		return copyObjectLifeline(value.(*index.ObjectLifeline)), nil
+	}
+	idx, err := s.LedgerStorer.GetObjectIndex(ref)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := index.EncodeObjectLifeline(idx)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(key, copyObjectLifeline(idx), len(buf), version)
+	return idx, nil
+}
+
+// GetBlob returns cached blob or fetches it from storage.
+func (s *cachingStorer) GetBlob(hash []byte) ([]byte, error) {
+	key := cacheKey(cacheScopeBlob, hash)
+	value, version, ok := s.cache.get(key)
+	if ok {
+		return value.([]byte), nil
+	}
+	blob, err := s.LedgerStorer.GetBlob(hash)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.put(key, blob, len(blob), version)
+	return blob, nil
+}
+
+// SetClassIndex stores class lifeline and invalidates its cached copy.
+func (s *cachingStorer) SetClassIndex(ref *record.Reference, idx *index.ClassLifeline) error {
+	defer s.cache.invalidate([]string{cacheKey(cacheScopeClassLifeline, ref.Key())})
+	return s.LedgerStorer.SetClassIndex(ref, idx)
+}
+
+// SetObjectIndex stores object lifeline and invalidates its cached copy.
+func (s *cachingStorer) SetObjectIndex(ref *record.Reference, idx *index.ObjectLifeline) error {
+	defer s.cache.invalidate([]string{cacheKey(cacheScopeObjectLifeline, ref.Key())})
+	return s.LedgerStorer.SetObjectIndex(ref, idx)
+}
+
+// Update applies batch and invalidates cached copies of lifelines written by it.
+func (s *cachingStorer) Update(fn func(storage.Batch) error) error {
+	var keys []string
+	defer func() {
+		s.cache.invalidate(keys)
+	}()
+	return s.LedgerStorer.Update(func(batch storage.Batch) error {
+		return fn(&cachingBatch{Batch: batch, keys: &keys})
+	})
+}
+
+// cachingBatch collects cache keys of written and checked lifelines.
+type cachingBatch struct {
+	storage.Batch
+	keys *[]string
+}
+
+func (b *cachingBatch) SetClassIndex(ref *record.Reference, idx *index.ClassLifeline) error {
+	*b.keys = append(*b.keys, cacheKey(cacheScopeClassLifeline, ref.Key()))
+	return b.Batch.SetClassIndex(ref, idx)
+}
+
+func (b *cachingBatch) SetObjectIndex(ref *record.Reference, idx *index.ObjectLifeline) error {
+	*b.keys = append(*b.keys, cacheKey(cacheScopeObjectLifeline, ref.Key()))
+	return b.Batch.SetObjectIndex(ref, idx)
+}
+
+// CheckObjectIndex invalidates checked lifeline too, so it is read from storage again after a conflict.
+func (b *cachingBatch) CheckObjectIndex(ref *record.Reference, idx *index.ObjectLifeline) error {
+	*b.keys = append(*b.keys, cacheKey(cacheScopeObjectLifeline, ref.Key()))
+	return b.Batch.CheckObjectIndex(ref, idx)
+}
+
+func copyRefs(refs []record.Reference) []record.Reference {
+	if refs == nil {
+		return nil
+	}
+	return append([]record.Reference{}, refs...)
+}
+
+func copyMemory(memory record.Memory) record.Memory {
+	if memory == nil {
+		return nil
+	}
+	return append(record.Memory{}, memory...)
+}
+
+func copyClassLifeline(idx *index.ClassLifeline) *index.ClassLifeline {
+	cp := *idx
+	cp.AmendRefs = copyRefs(idx.AmendRefs)
+	return &cp
+}
+
+func copyObjectLifeline(idx *index.ObjectLifeline) *index.ObjectLifeline {
+	cp := *idx
+	cp.AppendRefs = copyRefs(idx.AppendRefs)
+	return &cp
+}
+
+// ConfigureCache enables cache of decoded records, lifelines and code blobs with provided configuration. Zero
+// MaxSize disables cache. Reconfiguring drops cached entries and resets stats.
+//
+// Cache is invalidated by writes made through the manager only, so storage should not be modified bypassing it while
+// cache is enabled. ConfigureCache is not safe to call concurrently with other manager methods.
+func (m *LedgerArtifactManager) ConfigureCache(cfg CacheConfig) {
+	if cs, ok := m.storer.(*cachingStorer); ok {
+		m.storer = cs.LedgerStorer
+	}
+	if cfg.MaxSize > 0 {
+		m.storer = &cachingStorer{LedgerStorer: m.storer, cache: newLRUCache(cfg.MaxSize)}
+	}
+}
+
+// CacheStats returns cache usage counters. Zero stats are returned if cache is disabled.
+func (m *LedgerArtifactManager) CacheStats() CacheStats {
+	if cs, ok := m.storer.(*cachingStorer); ok {
+		return cs.cache.stats()
+	}
+	return CacheStats{}
+}
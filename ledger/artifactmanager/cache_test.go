@@ -0,0 +1,94 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package artifactmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/record"
+)
+
+func TestLRUCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRUCache(10)
+	_, version, _ := c.get("a")
+	c.put("a", 1, 4, version)
+	c.put("b", 2, 4, version)
+	_, _, ok := c.get("a")
+	assert.True(t, ok)
+
+	c.put("c", 3, 4, version)
+	_, _, ok = c.get("b")
+	assert.False(t, ok)
+	value, _, ok := c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	// Entries bigger than cache are not cached.
+	c.put("d", 4, 11, version)
+	_, _, ok = c.get("d")
+	assert.False(t, ok)
+
+	assert.Equal(t, CacheStats{Hits: 2, Misses: 3, Evictions: 1, Size: 8, Entries: 2}, c.stats())
+}
+
+func TestLRUCache_DropsValuesLoadedBeforeInvalidation(t *testing.T) {
+	c := newLRUCache(10)
+	_, version, _ := c.get("a")
+	c.invalidate([]string{"a"})
+	c.put("a", 1, 1, version)
+	_, _, ok := c.get("a")
+	assert.False(t, ok)
+}
+
+func TestLedgerArtifactManager_CachesReads(t *testing.T) {
+	ledger, am, _ := prepareTestArtifactManager()
+	manager := am.(*LedgerArtifactManager)
+	manager.SetArchPref([]record.ArchType{1})
+	manager.ConfigureCache(CacheConfig{MaxSize: 1 << 20})
+
+	_, objRef := activateTestObject(t, ledger, manager, record.Memory{1})
+
+	getMemory := func() record.Memory {
+		_, obj, err := manager.GetLatestObj(*objRef, record.Reference{}, record.Reference{})
+		mustNoError(t, err)
+		memory, err := obj.GetMemory()
+		mustNoError(t, err)
+		return memory
+	}
+	assert.Equal(t, record.Memory{1}, getMemory())
+	misses := manager.CacheStats().Misses
+	assert.Equal(t, record.Memory{1}, getMemory())
+	stats := manager.CacheStats()
+	assert.Equal(t, misses, stats.Misses)
+	assert.NotZero(t, stats.Hits)
+	assert.NotZero(t, stats.Entries)
+
+	// Memory is copied, so modifying it doesn't change cached records.
+	getMemory()[0] = 3
+	assert.Equal(t, record.Memory{1}, getMemory())
+
+	// Writes invalidate cached lifelines.
+	_, err := manager.UpdateObj(*storeRequest(ledger, *objRef), *objRef, record.Memory{2})
+	mustNoError(t, err)
+	assert.Equal(t, record.Memory{2}, getMemory())
+
+	manager.ConfigureCache(CacheConfig{})
+	assert.Equal(t, CacheStats{}, manager.CacheStats())
+	assert.Equal(t, record.Memory{2}, getMemory())
+}
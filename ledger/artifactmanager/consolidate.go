@@ -0,0 +1,214 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package artifactmanager
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// MergeFunc folds append delegates into object memory. It receives the latest object memory and memory of delegates
+// in order of creation and returns the new object memory.
+type MergeFunc func(memory record.Memory, delegates []record.Memory) (record.Memory, error)
+
+// SetDelegateLimit enables automatic consolidation of append delegates.
+//
+// When AppendObjDelegate makes the number of object delegates reach the limit, all delegates are folded by merge into
+// a new object amend record, which is stored along with the append record. Zero limit disables automatic
+// consolidation. ErrNoMergeFunc is returned if limit is set without merge function.
+func (m *LedgerArtifactManager) SetDelegateLimit(limit int, merge MergeFunc) error {
+	if limit > 0 && merge == nil {
+		return ErrNoMergeFunc
+	}
+	m.delegateLimit = limit
+	m.delegateMerge = merge
+	return nil
+}
+
+func (m *LedgerArtifactManager) getDelegates(objIndex *index.ObjectLifeline) ([]record.Memory, error) {
+	var delegates []record.Memory
+	for _, appendRef := range objIndex.AppendRefs {
+		rec, err := m.storer.GetRecord(&appendRef)
+		if err != nil {
+			return nil, indexError(err, "invalid append reference in object index")
+		}
+		appendRec, ok := rec.(*record.ObjectAppendRecord)
+		if !ok {
+			return nil, errors.Wrap(ErrInconsistentIndex, "invalid append reference in object index")
+		}
+		delegates = append(delegates, copyMemory(appendRec.AppendMemory))
+	}
+
+	return delegates, nil
+}
+
+// mergeDelegates folds object delegates and provided new delegates into the latest object memory.
+func (m *LedgerArtifactManager) mergeDelegates(
+	merge MergeFunc,
+	activateRec *record.ObjectActivateRecord,
+	stateRec *record.ObjectAmendRecord,
+	objIndex *index.ObjectLifeline,
+	newDelegates ...record.Memory,
+) (record.Memory, error) {
+	if merge == nil {
+		return nil, ErrNoMergeFunc
+	}
+	delegates, err := m.getDelegates(objIndex)
+	if err != nil {
+		return nil, err
+	}
+	delegates = append(delegates, newDelegates...)
+	memory := activateRec.Memory
+	if stateRec != nil {
+		memory = stateRec.NewMemory
+	}
+	merged, err := merge(memory, delegates)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to merge delegates")
+	}
+	if merged == nil {
+		// Nil memory can't be told apart from no consolidation.
+		merged = record.Memory{}
+	}
+	return merged, nil
+}
+
+// storeConsolidation stores amend record with merged memory and updates provided object index. Index itself is not
+// stored.
+func storeConsolidation(
+	batch storage.Batch, requestRef, objRef record.Reference, objIndex *index.ObjectLifeline, merged record.Memory,
+) error {
+	rec := record.ObjectAmendRecord{
+		AmendRecord: record.AmendRecord{
+			StatefulResult: record.StatefulResult{
+				ResultRecord: record.ResultRecord{
+					RequestRecord: requestRef,
+				},
+			},
+			HeadRecord: objRef,
+			AmendedRecord: record.Reference{
+				Domain: objRef.Domain,
+				Record: objIndex.LatestStateRef.Record,
+			},
+		},
+		NewMemory: merged,
+	}
+	amendRef, err := batch.SetRecord(&rec)
+	if err != nil {
+		return errors.Wrap(err, "failed to store amend record")
+	}
+	objIndex.LatestStateRef = *amendRef
+	objIndex.AppendRefs = []record.Reference{}
+	err = batch.AppendHistory(&objRef, amendRef)
+	if err != nil {
+		return errors.Wrap(err, "failed to store lifeline history")
+	}
+	return nil
+}
+
+// storeAutoConsolidation stores consolidation caused by the append record. Consolidation is the result of a request
+// made by the append, so results refer to stored requests as usual.
+func storeAutoConsolidation(
+	batch storage.Batch, appendRef, objRef record.Reference, objIndex *index.ObjectLifeline, merged record.Memory,
+) error {
+	objState := objIndex.LatestStateRef
+	requestRef, err := batch.SetRecord(&record.CallRequest{
+		RequestRecord: record.RequestRecord{Requester: appendRef, Target: objRef},
+		ObjectState:   &objState,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to store consolidation request")
+	}
+	err = storeConsolidation(batch, *requestRef, objRef, objIndex, merged)
+	if err != nil {
+		return err
+	}
+	err = batch.SetRequestResult(requestRef, &objIndex.LatestStateRef)
+	if err != nil {
+		return errors.Wrap(err, "failed to store request result")
+	}
+	return nil
+}
+
+// ConsolidateDelegates folds all append delegates of the object into a new amend record using provided merge
+// function. Provided reference should be a reference to the head of the object. If the object has no delegates,
+// nothing is stored and nil reference is returned.
+//
+// If object lifeline is changed concurrently, delegates are merged again from the new latest state, so merge can be
+// called several times. ErrConflict is returned if all retries conflicted.
+func (m *LedgerArtifactManager) ConsolidateDelegates(
+	requestRef, objRef record.Reference, merge MergeFunc,
+) (*record.Reference, error) {
+	err := m.checkRequestRecord(&requestRef, &objRef)
+	if err != nil {
+		return nil, err
+	}
+	var amendRef *record.Reference
+	err = retryOnConflict(func() (err error) {
+		amendRef, err = m.consolidateDelegates(requestRef, objRef, merge)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return amendRef, nil
+}
+
+// consolidateDelegates stores consolidation of object delegates. ErrConflict is returned if the object lifeline was
+// changed after it was read.
+func (m *LedgerArtifactManager) consolidateDelegates(
+	requestRef, objRef record.Reference, merge MergeFunc,
+) (*record.Reference, error) {
+	objActivateRec, objStateRec, objIndex, err := m.getActiveObject(objRef)
+	if err != nil {
+		return nil, err
+	}
+	if len(objIndex.AppendRefs) == 0 {
+		return nil, nil
+	}
+	merged, err := m.mergeDelegates(merge, objActivateRec, objStateRec, objIndex)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.storer.Update(func(batch storage.Batch) error {
+		err := batch.CheckObjectIndex(&objRef, objIndex)
+		if err != nil {
+			return err
+		}
+		err = storeConsolidation(batch, requestRef, objRef, objIndex, merged)
+		if err != nil {
+			return err
+		}
+		err = batch.SetRequestResult(&requestRef, &objIndex.LatestStateRef)
+		if err != nil {
+			return errors.Wrap(err, "failed to store request result")
+		}
+		err = batch.SetObjectIndex(&objRef, objIndex)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline index")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &objIndex.LatestStateRef, nil
+}
@@ -0,0 +1,166 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package artifactmanager
+
+import (
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+func concatMerge(memory record.Memory, delegates []record.Memory) (record.Memory, error) {
+	merged := append(record.Memory{}, memory...)
+	for _, d := range delegates {
+		merged = append(merged, d...)
+	}
+	return merged, nil
+}
+
+func prepareConsolidationTest(t *testing.T) (storage.LedgerStorer, *LedgerArtifactManager, *record.Reference) {
+	ledger, am, _ := prepareTestArtifactManager()
+	_, objRef := activateTestObject(t, ledger, am, record.Memory{0})
+	return ledger, am.(*LedgerArtifactManager), objRef
+}
+
+func latestMemory(t *testing.T, manager *LedgerArtifactManager, objRef *record.Reference) (record.Memory, int) {
+	_, obj, err := manager.GetLatestObj(*objRef, record.Reference{}, record.Reference{})
+	mustNoError(t, err)
+	memory, err := obj.GetMemory()
+	mustNoError(t, err)
+	delegates, err := obj.GetDelegates()
+	mustNoError(t, err)
+	return memory, len(delegates)
+}
+
+func TestLedgerArtifactManager_ConsolidateDelegates(t *testing.T) {
+	ledger, manager, objRef := prepareConsolidationTest(t)
+
+	ref, err := manager.ConsolidateDelegates(*storeRequest(ledger, *objRef), *objRef, concatMerge)
+	assert.NoError(t, err)
+	assert.Nil(t, ref)
+
+	for i := byte(1); i <= 3; i++ {
+		_, err = manager.AppendObjDelegate(*storeRequest(ledger, *objRef), *objRef, record.Memory{i})
+		mustNoError(t, err)
+	}
+	requestRef := storeRequest(ledger, *objRef)
+	ref, err = manager.ConsolidateDelegates(*requestRef, *objRef, concatMerge)
+	mustNoError(t, err)
+	memory, delegates := latestMemory(t, manager, objRef)
+	assert.Equal(t, record.Memory{0, 1, 2, 3}, memory)
+	assert.Equal(t, 0, delegates)
+
+	rec, err := ledger.GetRecord(ref)
+	mustNoError(t, err)
+	assert.Equal(t, record.Memory{0, 1, 2, 3}, rec.(*record.ObjectAmendRecord).NewMemory)
+	resultRef, err := ledger.GetRequestResult(requestRef)
+	mustNoError(t, err)
+	assert.Equal(t, ref.Key(), resultRef.Key())
+}
+
+func TestLedgerArtifactManager_ConsolidateDelegates_ReportsMergeError(t *testing.T) {
+	ledger, manager, objRef := prepareConsolidationTest(t)
+	_, err := manager.AppendObjDelegate(*storeRequest(ledger, *objRef), *objRef, record.Memory{1})
+	mustNoError(t, err)
+
+	_, err = manager.ConsolidateDelegates(*storeRequest(ledger, *objRef), *objRef, nil)
+	assert.Equal(t, ErrNoMergeFunc, errors.Cause(err))
+	mergeErr := errors.New("merge failed")
+	_, err = manager.ConsolidateDelegates(
+		*storeRequest(ledger, *objRef), *objRef,
+		func(record.Memory, []record.Memory) (record.Memory, error) { return nil, mergeErr },
+	)
+	assert.Equal(t, mergeErr, errors.Cause(err))
+	memory, delegates := latestMemory(t, manager, objRef)
+	assert.Equal(t, record.Memory{0}, memory)
+	assert.Equal(t, 1, delegates)
+}
+
+func TestLedgerArtifactManager_AppendObjDelegate_ConsolidatesOnLimit(t *testing.T) {
+	ledger, manager, objRef := prepareConsolidationTest(t)
+	assert.Equal(t, ErrNoMergeFunc, manager.SetDelegateLimit(2, nil))
+	mustNoError(t, manager.SetDelegateLimit(2, concatMerge))
+
+	_, err := manager.AppendObjDelegate(*storeRequest(ledger, *objRef), *objRef, record.Memory{1})
+	mustNoError(t, err)
+	memory, delegates := latestMemory(t, manager, objRef)
+	assert.Equal(t, record.Memory{0}, memory)
+	assert.Equal(t, 1, delegates)
+
+	appendRef, err := manager.AppendObjDelegate(*storeRequest(ledger, *objRef), *objRef, record.Memory{2})
+	mustNoError(t, err)
+	memory, delegates = latestMemory(t, manager, objRef)
+	assert.Equal(t, record.Memory{0, 1, 2}, memory)
+	assert.Equal(t, 0, delegates)
+
+	// Both the append and the consolidation are in object history.
+	history, err := manager.GetHistory(*objRef)
+	mustNoError(t, err)
+	assert.Len(t, history, 4)
+	assert.Equal(t, appendRef.Key(), history[2].Ref.Key())
+
+	// Consolidation is the result of a stored request made by the append.
+	requestRef := history[3].Record.(*record.ObjectAmendRecord).RequestRecord
+	rec, err := ledger.GetRecord(&requestRef)
+	mustNoError(t, err)
+	request := rec.(*record.CallRequest)
+	assert.Equal(t, appendRef.Key(), request.Requester.Key())
+	assert.Equal(t, objRef.Key(), request.Target.Key())
+	resultRef, err := ledger.GetRequestResult(&requestRef)
+	mustNoError(t, err)
+	assert.Equal(t, history[3].Ref.Key(), resultRef.Key())
+}
+
+func TestLedgerArtifactManager_ConcurrentAppendsAndConsolidations(t *testing.T) {
+	ledger, manager, objRef := prepareConsolidationTest(t)
+
+	const appends, consolidations = 5, 3
+	var wg sync.WaitGroup
+	for i := 1; i <= appends; i++ {
+		requestRef := storeRequest(ledger, *objRef)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, err := manager.AppendObjDelegate(*requestRef, *objRef, record.Memory{byte(i)})
+			assert.NoError(t, err)
+		}(i)
+	}
+	for i := 0; i < consolidations; i++ {
+		requestRef := storeRequest(ledger, *objRef)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := manager.ConsolidateDelegates(*requestRef, *objRef, concatMerge)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	_, err := manager.ConsolidateDelegates(*storeRequest(ledger, *objRef), *objRef, concatMerge)
+	mustNoError(t, err)
+
+	// No append is lost.
+	memory, delegates := latestMemory(t, manager, objRef)
+	assert.Equal(t, 0, delegates)
+	sort.Slice(memory, func(i, j int) bool { return memory[i] < memory[j] })
+	assert.Equal(t, record.Memory{0, 1, 2, 3, 4, 5}, memory)
+}
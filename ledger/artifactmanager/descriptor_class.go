@@ -17,14 +17,18 @@ type ClassDescriptor struct {
 	lifelineIndex     *index.ClassLifeline
 }
 
+// GetCodeRef returns reference of the latest class code record known to storage.
+func (d *ClassDescriptor) GetCodeRef() record.Reference {
+	if d.latestAmendRecord != nil {
+		return d.latestAmendRecord.NewCode
+	}
+	return d.activateRecord.CodeRecord
+}
+
 // GetCode fetches the latest class code known to storage. Code will be fetched according to architecture preferences
 // set via SetArchPref in artifact manager. If preferences are not provided, an error will be returned.
 func (d *ClassDescriptor) GetCode() ([]byte, error) {
-	codeRef := d.activateRecord.CodeRecord
-	if d.latestAmendRecord != nil {
-		codeRef = d.latestAmendRecord.NewCode
-	}
-	code, err := d.manager.getCodeRecordCode(codeRef)
+	code, err := d.manager.getCodeRecordCode(d.GetCodeRef())
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +37,8 @@ func (d *ClassDescriptor) GetCode() ([]byte, error) {
 }
 
 // GetMigrations fetches all migrations from provided to artifact manager state to the last state known to storage. VM
-// is responsible for applying these migrations and updating objects.
+// is responsible for applying these migrations and updating objects. Migrations already applied to the object by
+// artifact manager (see SetMigrationRunner) are not returned.
 func (d *ClassDescriptor) GetMigrations() ([][]byte, error) {
 	var amends []*record.ClassAmendRecord
 	// Search for provided state in class amends from the end of the list.
@@ -45,11 +50,11 @@ func (d *ClassDescriptor) GetMigrations() ([][]byte, error) {
 		}
 		rec, err := d.manager.storer.GetRecord(&amendRef)
 		if err != nil {
-			return nil, errors.Wrap(err, "inconsistent class index")
+			return nil, indexError(err, "invalid amend reference in class index")
 		}
 		amendRec, ok := rec.(*record.ClassAmendRecord)
 		if !ok {
-			return nil, errors.New("inconsistent class index")
+			return nil, errors.Wrap(ErrInconsistentIndex, "invalid amend reference in class index")
 		}
 		amends = append(amends, amendRec)
 	}
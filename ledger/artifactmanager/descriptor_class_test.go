@@ -1,23 +1,19 @@
 package artifactmanager
 
 import (
-	"os"
 	"testing"
 
 	"github.com/insolar/insolar/ledger/index"
 	"github.com/insolar/insolar/ledger/record"
 	"github.com/insolar/insolar/ledger/storage"
-	"github.com/insolar/insolar/ledger/storage/leveldb"
+	"github.com/insolar/insolar/ledger/storage/memory"
 	"github.com/stretchr/testify/assert"
 )
 
 func prepareClassDescriptorTest() (
 	storage.LedgerStorer, *LedgerArtifactManager, *record.ClassActivateRecord, *record.Reference,
 ) {
-	if err := leveldb.DropDB(); err != nil {
-		os.Exit(1)
-	}
-	ledger, _ := leveldb.InitDB()
+	ledger := memory.NewMemLedger()
 	manager := LedgerArtifactManager{
 		storer:   ledger,
 		archPref: []record.ArchType{1},
@@ -47,6 +43,7 @@ func TestClassDescriptor_GetCode(t *testing.T) {
 		lifelineIndex:     &idx,
 	}
 
+	assert.Equal(t, *codeRef, desc.GetCodeRef())
 	code, err := desc.GetCode()
 	assert.NoError(t, err)
 	assert.Equal(t, []byte{1, 2, 3}, code)
@@ -1,22 +1,18 @@
 package artifactmanager
 
 import (
-	"os"
 	"testing"
 
 	"github.com/insolar/insolar/ledger/index"
 	"github.com/insolar/insolar/ledger/record"
-	"github.com/insolar/insolar/ledger/storage/leveldb"
+	"github.com/insolar/insolar/ledger/storage/memory"
 	"github.com/stretchr/testify/assert"
 )
 
 func prepareObjectDescriptorTest() (
-	*leveldb.LevelLedger, *LedgerArtifactManager, *record.ObjectActivateRecord, *record.Reference,
+	*memory.MemLedger, *LedgerArtifactManager, *record.ObjectActivateRecord, *record.Reference,
 ) {
-	if err := leveldb.DropDB(); err != nil {
-		os.Exit(1)
-	}
-	ledger, _ := leveldb.InitDB()
+	ledger := memory.NewMemLedger()
 	manager := LedgerArtifactManager{
 		storer:   ledger,
 		archPref: []record.ArchType{1},
@@ -0,0 +1,66 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package artifactmanager
+
+import (
+	"errors"
+
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// Errors returned by artifact manager are wrapped with context. Use errors.Cause to get one of the values below.
+var (
+	// ErrNotFound returns if requested record or lifeline is not found. It is the same value as storage.ErrNotFound.
+	ErrNotFound = storage.ErrNotFound
+
+	// ErrWrongRecordType returns if provided reference points to a record of unexpected type.
+	ErrWrongRecordType = errors.New("record has unexpected type")
+
+	// ErrDeactivated returns if object or class is deactivated.
+	ErrDeactivated = errors.New("object or class is deactivated")
+
+	// ErrInconsistentIndex returns if lifeline index does not match stored records.
+	ErrInconsistentIndex = errors.New("lifeline index is inconsistent")
+
+	// ErrClassMismatch returns if object does not belong to provided class.
+	ErrClassMismatch = errors.New("object does not belong to the class")
+
+	// ErrArchUnavailable returns if code is not available for any of preferred architectures. It is the same value as
+	// record.ErrArchUnavailable.
+	ErrArchUnavailable = record.ErrArchUnavailable
+
+	// ErrRequestNotFound returns if mutation request record is not found in storage.
+	ErrRequestNotFound = errors.New("request record is not found")
+
+	// ErrNotRequest returns if provided request reference points to a record which is not a request.
+	ErrNotRequest = errors.New("provided reference is not a request record")
+
+	// ErrRequestTargetMismatch returns if request targets a record other than the mutated one.
+	ErrRequestTargetMismatch = errors.New("request does not target the mutated record")
+
+	// ErrNoMergeFunc returns if delegates should be consolidated, but merge function is not provided.
+	ErrNoMergeFunc = errors.New("merge function is not provided")
+
+	// ErrConflict returns if object was changed concurrently with the mutation. It is the same value as
+	// storage.ErrConflict.
+	ErrConflict = storage.ErrConflict
+
+	// ErrRequestHasResult returns if request already produced a result. It is the same value as
+	// storage.ErrRequestHasResult.
+	ErrRequestHasResult = storage.ErrRequestHasResult
+)
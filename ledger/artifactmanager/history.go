@@ -0,0 +1,196 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package artifactmanager
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+)
+
+// HistoryEntry is a single record of object or class lifeline.
+type HistoryEntry struct {
+	Ref    record.Reference
+	Pulse  record.PulseNum
+	Record record.Record
+}
+
+// historyRefs returns references of all lifeline records except the head.
+//
+// Lifelines created before history tracking have no stored history. For them only the latest state and current
+// appends are known.
+func historyRefs(headRef, latestStateRef record.Reference, history, appends []record.Reference) []record.Reference {
+	if len(history) > 0 {
+		return history
+	}
+	var refs []record.Reference
+	if latestStateRef.IsNotEqual(headRef) {
+		refs = append(refs, latestStateRef)
+	}
+	return append(refs, appends...)
+}
+
+func (m *LedgerArtifactManager) getLifelineRefs(headRef record.Reference) (record.Record, []record.Reference, error) {
+	headRec, err := m.storer.GetRecord(&headRef)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "head record is not found")
+	}
+	var history []record.Reference
+	err = m.storer.IterateHistory(&headRef, func(ref *record.Reference) error {
+		history = append(history, *ref)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to fetch lifeline history")
+	}
+	switch headRec.(type) {
+	case *record.ClassActivateRecord:
+		classIndex, err := m.storer.GetClassIndex(&headRef)
+		if err != nil {
+			return nil, nil, indexError(err, "class index is not found")
+		}
+		return headRec, historyRefs(headRef, classIndex.LatestStateRef, history, nil), nil
+	case *record.ObjectActivateRecord:
+		objIndex, err := m.storer.GetObjectIndex(&headRef)
+		if err != nil {
+			return nil, nil, indexError(err, "object index is not found")
+		}
+		refs := historyRefs(headRef, objIndex.LatestStateRef, history, objIndex.AppendRefs)
+		return headRec, refs, nil
+	}
+	return nil, nil, errors.Wrap(ErrWrongRecordType, "provided reference is not a class or an object record")
+}
+
+// GetHistory returns all records of object or class lifeline ordered from activation to the latest one. Provided
+// reference should be a reference to the head of the object or the class.
+func (m *LedgerArtifactManager) GetHistory(headRef record.Reference) ([]HistoryEntry, error) {
+	headRec, refs, err := m.getLifelineRefs(headRef)
+	if err != nil {
+		return nil, err
+	}
+	history := []HistoryEntry{{Ref: headRef, Pulse: headRef.Record.Pulse, Record: headRec}}
+	for _, ref := range refs {
+		rec, err := m.storer.GetRecord(&ref)
+		if err != nil {
+			return nil, indexError(err, "invalid history reference in lifeline index")
+		}
+		history = append(history, HistoryEntry{Ref: ref, Pulse: ref.Record.Pulse, Record: rec})
+	}
+	return history, nil
+}
+
+// historyAt returns lifeline history as of provided pulse.
+func (m *LedgerArtifactManager) historyAt(headRef record.Reference, pn record.PulseNum) ([]HistoryEntry, error) {
+	if headRef.Record.Pulse > pn {
+		return nil, errors.Wrap(ErrNotFound, "lifeline is not activated as of provided pulse")
+	}
+	history, err := m.GetHistory(headRef)
+	if err != nil {
+		return nil, err
+	}
+	for i, entry := range history {
+		if entry.Pulse > pn {
+			return history[:i], nil
+		}
+	}
+	return history, nil
+}
+
+func (m *LedgerArtifactManager) getClassAt(classRef record.Reference, pn record.PulseNum) (*ClassDescriptor, error) {
+	history, err := m.historyAt(classRef, pn)
+	if err != nil {
+		return nil, err
+	}
+	activateRec, ok := history[0].Record.(*record.ClassActivateRecord)
+	if !ok {
+		return nil, errors.Wrap(ErrWrongRecordType, "provided reference is not a class record")
+	}
+	desc := ClassDescriptor{
+		StateRef:       classRef,
+		manager:        m,
+		activateRecord: activateRec,
+		lifelineIndex:  &index.ClassLifeline{LatestStateRef: classRef},
+	}
+	for _, entry := range history[1:] {
+		switch rec := entry.Record.(type) {
+		case *record.ClassAmendRecord:
+			desc.StateRef = entry.Ref
+			desc.latestAmendRecord = rec
+			desc.lifelineIndex.AmendRefs = append(desc.lifelineIndex.AmendRefs, entry.Ref)
+		case *record.DeactivationRecord:
+			return nil, errors.Wrap(ErrDeactivated, "class is deactivated as of provided pulse")
+		default:
+			return nil, errors.Wrap(ErrInconsistentIndex, "unexpected record in class history")
+		}
+	}
+	desc.lifelineIndex.LatestStateRef = desc.StateRef
+	desc.fromState = desc.StateRef
+	return &desc, nil
+}
+
+func (m *LedgerArtifactManager) getObjectAt(objRef record.Reference, pn record.PulseNum) (*ObjectDescriptor, error) {
+	history, err := m.historyAt(objRef, pn)
+	if err != nil {
+		return nil, err
+	}
+	activateRec, ok := history[0].Record.(*record.ObjectActivateRecord)
+	if !ok {
+		return nil, errors.Wrap(ErrWrongRecordType, "provided reference is not an object record")
+	}
+	desc := ObjectDescriptor{
+		StateRef:       objRef,
+		manager:        m,
+		activateRecord: activateRec,
+		lifelineIndex:  &index.ObjectLifeline{ClassRef: activateRec.ClassActivateRecord},
+	}
+	for _, entry := range history[1:] {
+		switch rec := entry.Record.(type) {
+		case *record.ObjectAmendRecord:
+			desc.StateRef = entry.Ref
+			desc.latestAmendRecord = rec
+			desc.lifelineIndex.AppendRefs = nil
+		case *record.ObjectAppendRecord:
+			desc.lifelineIndex.AppendRefs = append(desc.lifelineIndex.AppendRefs, entry.Ref)
+		case *record.DeactivationRecord:
+			return nil, errors.Wrap(ErrDeactivated, "object is deactivated as of provided pulse")
+		default:
+			return nil, errors.Wrap(ErrInconsistentIndex, "unexpected record in object history")
+		}
+	}
+	desc.lifelineIndex.LatestStateRef = desc.StateRef
+	return &desc, nil
+}
+
+// GetObjAt returns descriptors for the state of the object and its class as of provided pulse (e.g. the latest
+// states created in this pulse or before it). If the object is not activated yet or is deactivated as of this
+// pulse, an error should be returned.
+//
+// Returned class descriptor provides no migrations, because it describes the exact class state.
+func (m *LedgerArtifactManager) GetObjAt(
+	objectRef record.Reference, pn record.PulseNum,
+) (*ClassDescriptor, *ObjectDescriptor, error) {
+	object, err := m.getObjectAt(objectRef, pn)
+	if err != nil {
+		return nil, nil, err
+	}
+	class, err := m.getClassAt(object.lifelineIndex.ClassRef, pn)
+	if err != nil {
+		return nil, nil, err
+	}
+	return class, object, nil
+}
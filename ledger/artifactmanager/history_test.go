@@ -0,0 +1,145 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package artifactmanager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/pulse"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage/memory"
+)
+
+type historyFixture struct {
+	manager   *LedgerArtifactManager
+	classRef  *record.Reference
+	objRef    *record.Reference
+	amendRefs []*record.Reference
+	appendRef *record.Reference
+}
+
+// prepareHistory creates object lifeline spread over pulses 1-4:
+//  1: class and object activation
+//  2: object amend and append
+//  3: class amend and object amend
+//  4: object deactivation
+func prepareHistory(t *testing.T) *historyFixture {
+	ledger := memory.NewMemLedger()
+	pulses := pulse.NewManual(1)
+	ledger.SetPulseProvider(pulses)
+	manager := &LedgerArtifactManager{storer: ledger, archPref: []record.ArchType{1}}
+	f := historyFixture{manager: manager}
+	request := func(target record.Reference) record.Reference {
+		return *storeRequest(ledger, target)
+	}
+
+	f.classRef, f.objRef = activateTestObject(t, ledger, manager, record.Memory{1})
+
+	pulses.Set(2)
+	amendRef, err := manager.UpdateObj(request(*f.objRef), *f.objRef, record.Memory{2})
+	assert.NoError(t, err)
+	f.amendRefs = append(f.amendRefs, amendRef)
+	f.appendRef, err = manager.AppendObjDelegate(request(*f.objRef), *f.objRef, record.Memory{22})
+	assert.NoError(t, err)
+
+	pulses.Set(3)
+	newCodeRef, err := manager.DeployCode(request(record.Reference{}), map[record.ArchType][]byte{1: {3}})
+	assert.NoError(t, err)
+	_, err = manager.UpdateClass(request(*f.classRef), *f.classRef, *newCodeRef, nil)
+	assert.NoError(t, err)
+	amendRef, err = manager.UpdateObj(request(*f.objRef), *f.objRef, record.Memory{3})
+	assert.NoError(t, err)
+	f.amendRefs = append(f.amendRefs, amendRef)
+
+	pulses.Set(4)
+	_, err = manager.DeactivateObj(request(*f.objRef), *f.objRef)
+	assert.NoError(t, err)
+	return &f
+}
+
+func TestLedgerArtifactManager_GetHistory(t *testing.T) {
+	f := prepareHistory(t)
+
+	history, err := f.manager.GetHistory(*f.objRef)
+	assert.NoError(t, err)
+	var pulses []record.PulseNum
+	var types []record.TypeID
+	for _, entry := range history {
+		pulses = append(pulses, entry.Pulse)
+		types = append(types, record.TypeIDOf(entry.Record))
+	}
+	assert.Equal(t, []record.PulseNum{1, 2, 2, 3, 4}, pulses)
+	assert.Equal(t, []record.TypeID{
+		record.TypeIDOf(&record.ObjectActivateRecord{}),
+		record.TypeIDOf(&record.ObjectAmendRecord{}),
+		record.TypeIDOf(&record.ObjectAppendRecord{}),
+		record.TypeIDOf(&record.ObjectAmendRecord{}),
+		record.TypeIDOf(&record.DeactivationRecord{}),
+	}, types)
+	assert.Equal(t, *f.appendRef, history[2].Ref)
+
+	classHistory, err := f.manager.GetHistory(*f.classRef)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(classHistory))
+	assert.IsType(t, &record.ClassAmendRecord{}, classHistory[1].Record)
+
+	_, err = f.manager.GetHistory(*f.amendRefs[0])
+	assert.Error(t, err)
+}
+
+func TestLedgerArtifactManager_GetObjAt(t *testing.T) {
+	f := prepareHistory(t)
+
+	cases := []struct {
+		pulse     record.PulseNum
+		code      []byte
+		memory    record.Memory
+		delegates []record.Memory
+	}{
+		{1, []byte{1}, record.Memory{1}, nil},
+		{2, []byte{1}, record.Memory{2}, []record.Memory{{22}}},
+		{3, []byte{3}, record.Memory{3}, nil},
+	}
+	for _, c := range cases {
+		classDesc, objDesc, err := f.manager.GetObjAt(*f.objRef, c.pulse)
+		if !assert.NoError(t, err) {
+			continue
+		}
+		code, err := classDesc.GetCode()
+		assert.NoError(t, err)
+		assert.Equal(t, c.code, code)
+		migrations, err := classDesc.GetMigrations()
+		assert.NoError(t, err)
+		assert.Empty(t, migrations)
+		memory, err := objDesc.GetMemory()
+		assert.NoError(t, err)
+		assert.Equal(t, c.memory, memory)
+		delegates, err := objDesc.GetDelegates()
+		assert.NoError(t, err)
+		assert.Equal(t, c.delegates, delegates)
+	}
+	_, objDesc, err := f.manager.GetObjAt(*f.objRef, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, *f.amendRefs[0], objDesc.StateRef)
+
+	_, _, err = f.manager.GetObjAt(*f.objRef, 0)
+	assert.Error(t, err)
+	_, _, err = f.manager.GetObjAt(*f.objRef, 4)
+	assert.Error(t, err)
+}
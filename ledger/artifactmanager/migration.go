@@ -0,0 +1,164 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package artifactmanager
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// MigrationRunner executes class migrations on object memory. It is implemented by logic runners.
+type MigrationRunner interface {
+	// RunMigration executes migration code referred by codeRef on provided memory and returns migrated memory.
+	RunMigration(codeRef record.Reference, memory record.Memory) (record.Memory, error)
+}
+
+// SetMigrationRunner sets runner used to migrate objects.
+//
+// Object lifeline remembers the class state object memory conforms to. When GetLatestObj finds that the class was
+// amended after that state, migrations of all newer class amends are executed by the runner in order of creation and
+// migrated memory is stored as a new object amend record. Without runner objects are returned as is.
+//
+// Migration is a mutation made on behalf of the latest class state. It stores a call request from the class state to
+// the object, which refers to the migrated object state, and the object amend is stored as its result. Migration is
+// stored only if object lifeline was not changed since it was read, otherwise the object is read and migrated again,
+// so concurrent readers store the migration once and concurrent mutations are not lost.
+func (m *LedgerArtifactManager) SetMigrationRunner(runner MigrationRunner) {
+	m.migrationRunner = runner
+}
+
+// pendingMigrations returns migrations of class amends made after provided class state in order of creation.
+func pendingMigrations(
+	classIndex *index.ClassLifeline, classRef, classState record.Reference,
+) ([]record.Reference, error) {
+	first := 0
+	if classState.IsNotEqual(classRef) {
+		first = -1
+		for i, amendRef := range classIndex.AmendRefs {
+			if amendRef.IsEqual(classState) {
+				first = i + 1
+				break
+			}
+		}
+		if first < 0 {
+			return nil, errors.Wrap(ErrInconsistentIndex, "object class state is not found in class index")
+		}
+	}
+	return classIndex.AmendRefs[first:], nil
+}
+
+// migrateObject applies pending class migrations to the object. It returns stored amend record or nil if object
+// memory was not changed. Provided object index is updated to the stored one. ErrConflict is returned if the object
+// lifeline was changed after provided index was read.
+func (m *LedgerArtifactManager) migrateObject(
+	objRef record.Reference,
+	activateRec *record.ObjectActivateRecord,
+	stateRec *record.ObjectAmendRecord,
+	objIndex *index.ObjectLifeline,
+	classIndex *index.ClassLifeline,
+) (*record.ObjectAmendRecord, error) {
+	classState := objIndex.ClassStateRef
+	if m.migrationRunner == nil || classState.Record.Hash == nil || classState.IsEqual(classIndex.LatestStateRef) {
+		return nil, nil
+	}
+	amendRefs, err := pendingMigrations(classIndex, objIndex.ClassRef, classState)
+	if err != nil {
+		return nil, err
+	}
+
+	memory := activateRec.Memory
+	if stateRec != nil {
+		memory = stateRec.NewMemory
+	}
+	migrated := false
+	for _, amendRef := range amendRefs {
+		rec, err := m.storer.GetRecord(&amendRef)
+		if err != nil {
+			return nil, indexError(err, "invalid amend reference in class index")
+		}
+		amendRec, ok := rec.(*record.ClassAmendRecord)
+		if !ok {
+			return nil, errors.Wrap(ErrInconsistentIndex, "invalid amend reference in class index")
+		}
+		for _, codeRef := range amendRec.Migrations {
+			memory, err = m.migrationRunner.RunMigration(codeRef, memory)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to migrate object")
+			}
+			migrated = true
+		}
+	}
+
+	var rec *record.ObjectAmendRecord
+	err = m.storer.Update(func(batch storage.Batch) error {
+		err := batch.CheckObjectIndex(&objRef, objIndex)
+		if err != nil {
+			return err
+		}
+		if migrated {
+			objState := objIndex.LatestStateRef
+			requestRef, err := batch.SetRecord(&record.CallRequest{
+				RequestRecord: record.RequestRecord{Requester: classIndex.LatestStateRef, Target: objRef},
+				ObjectState:   &objState,
+			})
+			if err != nil {
+				return errors.Wrap(err, "failed to store migration request")
+			}
+			rec = &record.ObjectAmendRecord{
+				AmendRecord: record.AmendRecord{
+					StatefulResult: record.StatefulResult{
+						ResultRecord: record.ResultRecord{
+							RequestRecord: *requestRef,
+						},
+					},
+					HeadRecord: objRef,
+					AmendedRecord: record.Reference{
+						Domain: objRef.Domain,
+						Record: objState.Record,
+					},
+				},
+				NewMemory: memory,
+			}
+			amendRef, err := batch.SetRecord(rec)
+			if err != nil {
+				return errors.Wrap(err, "failed to store amend record")
+			}
+			err = batch.SetRequestResult(requestRef, amendRef)
+			if err != nil {
+				return errors.Wrap(err, "failed to store request result")
+			}
+			objIndex.LatestStateRef = *amendRef
+			err = batch.AppendHistory(&objRef, amendRef)
+			if err != nil {
+				return errors.Wrap(err, "failed to store lifeline history")
+			}
+		}
+		objIndex.ClassStateRef = classIndex.LatestStateRef
+		err = batch.SetObjectIndex(&objRef, objIndex)
+		if err != nil {
+			return errors.Wrap(err, "failed to store lifeline index")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
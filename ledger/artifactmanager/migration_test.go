@@ -0,0 +1,221 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package artifactmanager
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// appendingRunner appends a byte assigned to migration code to memory.
+type appendingRunner struct {
+	lock  sync.Mutex
+	codes map[string]byte
+	calls []record.Reference
+	err   error
+	// hook is called once on the next migration.
+	hook func()
+}
+
+func (r *appendingRunner) RunMigration(codeRef record.Reference, memory record.Memory) (record.Memory, error) {
+	r.lock.Lock()
+	hook := r.hook
+	r.hook = nil
+	r.lock.Unlock()
+	if hook != nil {
+		hook()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.err != nil {
+		return nil, r.err
+	}
+	r.calls = append(r.calls, codeRef)
+	return append(append(record.Memory{}, memory...), r.codes[string(codeRef.Key())]), nil
+}
+
+type migrationFixture struct {
+	ledger  storage.LedgerStorer
+	manager *LedgerArtifactManager
+	runner  *appendingRunner
+	class   *record.Reference
+	obj     *record.Reference
+}
+
+func prepareMigrationTest(t *testing.T) *migrationFixture {
+	ledger, am, _ := prepareTestArtifactManager()
+	f := &migrationFixture{
+		ledger:  ledger,
+		manager: am.(*LedgerArtifactManager),
+		runner:  &appendingRunner{codes: map[string]byte{}},
+	}
+	f.manager.SetMigrationRunner(f.runner)
+	f.class, f.obj = activateTestObject(t, ledger, f.manager, record.Memory{0})
+	return f
+}
+
+// updateClass amends the class with migrations appending provided bytes to object memory.
+func (f *migrationFixture) updateClass(t *testing.T, migrations ...byte) {
+	var migrationRefs []record.Reference
+	for _, b := range migrations {
+		codeRef, err := f.manager.DeployCode(*storeRequest(f.ledger, record.Reference{}), map[record.ArchType][]byte{
+			1: {b},
+		})
+		mustNoError(t, err)
+		f.runner.codes[string(codeRef.Key())] = b
+		migrationRefs = append(migrationRefs, *codeRef)
+	}
+	codeRef, err := f.manager.DeployCode(*storeRequest(f.ledger, record.Reference{}), map[record.ArchType][]byte{
+		1: {0},
+	})
+	mustNoError(t, err)
+	_, err = f.manager.UpdateClass(*storeRequest(f.ledger, *f.class), *f.class, *codeRef, migrationRefs)
+	mustNoError(t, err)
+}
+
+func TestLedgerArtifactManager_GetLatestObj_RunsPendingMigrations(t *testing.T) {
+	f := prepareMigrationTest(t)
+	f.updateClass(t, 1, 2)
+	f.updateClass(t)
+	f.updateClass(t, 3)
+
+	class, obj, err := f.manager.GetLatestObj(*f.obj, record.Reference{}, *f.obj)
+	mustNoError(t, err)
+	assert.NotNil(t, obj)
+	memory, err := obj.GetMemory()
+	mustNoError(t, err)
+	assert.Equal(t, record.Memory{0, 1, 2, 3}, memory)
+	assert.Len(t, f.runner.calls, 3)
+	// Applied migrations are not returned to VM.
+	migrations, err := class.GetMigrations()
+	mustNoError(t, err)
+	assert.Empty(t, migrations)
+
+	// Migrated memory is stored as object amend.
+	objIndex, err := f.ledger.GetObjectIndex(f.obj)
+	mustNoError(t, err)
+	amend, err := f.ledger.GetRecord(&objIndex.LatestStateRef)
+	mustNoError(t, err)
+	assert.Equal(t, record.Memory{0, 1, 2, 3}, amend.(*record.ObjectAmendRecord).NewMemory)
+	assert.Len(t, storedHistory(t, f.ledger, f.obj), 1)
+
+	// Amend is the result of migration request made by the latest class state.
+	requestRef := amend.(*record.ObjectAmendRecord).RequestRecord
+	request, err := f.ledger.GetRecord(&requestRef)
+	mustNoError(t, err)
+	classIndex, err := f.ledger.GetClassIndex(f.class)
+	mustNoError(t, err)
+	assert.Equal(t, classIndex.LatestStateRef, request.(*record.CallRequest).Requester)
+	assert.Equal(t, *f.obj, request.(*record.CallRequest).Target)
+	assert.Equal(t, f.obj, request.(*record.CallRequest).ObjectState)
+	resultRef, err := f.ledger.GetRequestResult(&requestRef)
+	mustNoError(t, err)
+	assert.Equal(t, objIndex.LatestStateRef.Key(), resultRef.Key())
+
+	// Migrations are applied only once.
+	_, _, err = f.manager.GetLatestObj(*f.obj, record.Reference{}, *f.obj)
+	mustNoError(t, err)
+	assert.Len(t, f.runner.calls, 3)
+}
+
+func TestLedgerArtifactManager_GetLatestObj_RunsOnlyNewMigrations(t *testing.T) {
+	f := prepareMigrationTest(t)
+	f.updateClass(t, 1)
+	_, _, err := f.manager.GetLatestObj(*f.obj, record.Reference{}, *f.obj)
+	mustNoError(t, err)
+
+	f.updateClass(t, 2)
+	_, obj, err := f.manager.GetLatestObj(*f.obj, record.Reference{}, *f.obj)
+	mustNoError(t, err)
+	memory, err := obj.GetMemory()
+	mustNoError(t, err)
+	assert.Equal(t, record.Memory{0, 1, 2}, memory)
+	assert.Len(t, f.runner.calls, 2)
+}
+
+func TestLedgerArtifactManager_GetLatestObj_SkipsMigrationsOfOlderAmends(t *testing.T) {
+	f := prepareMigrationTest(t)
+	f.updateClass(t, 1)
+	objRef, err := f.manager.ActivateObj(*storeRequest(f.ledger, *f.class), *f.class, record.Memory{0})
+	mustNoError(t, err)
+
+	_, obj, err := f.manager.GetLatestObj(*objRef, record.Reference{}, record.Reference{})
+	mustNoError(t, err)
+	memory, err := obj.GetMemory()
+	mustNoError(t, err)
+	assert.Equal(t, record.Memory{0}, memory)
+	assert.Empty(t, f.runner.calls)
+}
+
+func TestLedgerArtifactManager_GetLatestObj_ReportsMigrationError(t *testing.T) {
+	f := prepareMigrationTest(t)
+	f.updateClass(t, 1)
+	f.runner.err = errors.New("migration failed")
+
+	_, _, err := f.manager.GetLatestObj(*f.obj, record.Reference{}, *f.obj)
+	assert.Equal(t, f.runner.err, errors.Cause(err))
+	objIndex, err := f.ledger.GetObjectIndex(f.obj)
+	mustNoError(t, err)
+	assert.Equal(t, f.obj.Key(), objIndex.LatestStateRef.Key())
+}
+
+func TestLedgerArtifactManager_GetLatestObj_ConcurrentMigrations(t *testing.T) {
+	f := prepareMigrationTest(t)
+	f.updateClass(t, 1)
+
+	const readers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, obj, err := f.manager.GetLatestObj(*f.obj, record.Reference{}, *f.obj)
+			assert.NoError(t, err)
+			memory, err := obj.GetMemory()
+			assert.NoError(t, err)
+			assert.Equal(t, record.Memory{0, 1}, memory)
+		}()
+	}
+	wg.Wait()
+
+	// Migration is stored once.
+	assert.Len(t, storedHistory(t, f.ledger, f.obj), 1)
+}
+
+func TestLedgerArtifactManager_GetLatestObj_KeepsConcurrentUpdates(t *testing.T) {
+	f := prepareMigrationTest(t)
+	f.updateClass(t, 1)
+	// Object is updated while migration is running.
+	f.runner.hook = func() {
+		_, err := f.manager.UpdateObj(*storeRequest(f.ledger, *f.obj), *f.obj, record.Memory{5})
+		assert.NoError(t, err)
+	}
+
+	_, obj, err := f.manager.GetLatestObj(*f.obj, record.Reference{}, *f.obj)
+	mustNoError(t, err)
+	memory, err := obj.GetMemory()
+	mustNoError(t, err)
+	assert.Equal(t, record.Memory{5, 1}, memory)
+	assert.Len(t, storedHistory(t, f.ledger, f.obj), 2)
+}
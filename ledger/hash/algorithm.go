@@ -0,0 +1,101 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package hash
+
+import (
+	"fmt"
+	"hash"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+)
+
+// Algorithm identifies hash function used for record hashing.
+//
+// Algorithm identifiers are stored with record IDs, so existing values must never change.
+type Algorithm byte
+
+// Supported hash algorithms.
+const (
+	// SHA3224 is SHA3-224 hash. It is the default algorithm, all legacy records use it.
+	SHA3224 Algorithm = iota
+	// SHA3256 is SHA3-256 hash.
+	SHA3256
+	// BLAKE2b256 is BLAKE2b-256 hash.
+	BLAKE2b256
+)
+
+// MaxSize is the largest hash size in bytes among supported algorithms.
+const MaxSize = 32
+
+type algorithmInfo struct {
+	name string
+	size int
+	new  func() hash.Hash
+}
+
+var algorithms = map[Algorithm]algorithmInfo{
+	SHA3224: {name: "SHA3-224", size: 28, new: sha3.New224},
+	SHA3256: {name: "SHA3-256", size: 32, new: sha3.New256},
+	BLAKE2b256: {name: "BLAKE2b-256", size: 32, new: func() hash.Hash {
+		h, err := blake2b.New256(nil)
+		if err != nil {
+			panic(err)
+		}
+		return h
+	}},
+}
+
+// IsValid checks if algorithm is supported.
+func (a Algorithm) IsValid() bool {
+	_, ok := algorithms[a]
+	return ok
+}
+
+// Size returns hash size in bytes. It panics if algorithm is not supported.
+func (a Algorithm) Size() int {
+	return a.info().size
+}
+
+// New returns new hash.Hash of the algorithm. It panics if algorithm is not supported.
+func (a Algorithm) New() hash.Hash {
+	return a.info().new()
+}
+
+// Sum returns hash calculated on data received from Writers.
+func (a Algorithm) Sum(hw ...Writer) []byte {
+	h := a.New()
+	for _, w := range hw {
+		w.WriteHash(h)
+	}
+	return h.Sum(nil)
+}
+
+func (a Algorithm) String() string {
+	if info, ok := algorithms[a]; ok {
+		return info.name
+	}
+	return fmt.Sprintf("unknown algorithm %d", byte(a))
+}
+
+func (a Algorithm) info() algorithmInfo {
+	info, ok := algorithms[a]
+	if !ok {
+		panic(fmt.Sprintf("unsupported hash algorithm %d", byte(a)))
+	}
+	return info
+}
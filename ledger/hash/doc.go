@@ -14,7 +14,9 @@
  *    limitations under the License.
  */
 
-// Package hash contains Writer interface and SHA3 hasher.
+// Package hash contains Writer interface and hash algorithms used for records. SHA3-224 is the default algorithm.
+//
+// Merkle trees built with any of the algorithms are used to prove that a record belongs to a pulse.
 //
 // hash.Writer intended to be implemented by records for proper hashing.
 package hash
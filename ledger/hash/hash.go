@@ -18,8 +18,6 @@ package hash
 
 import (
 	"io"
-
-	"golang.org/x/crypto/sha3"
 )
 
 // Writer is the interface that wraps the WriteHash method.
@@ -31,9 +29,5 @@ type Writer interface {
 
 // SHA3hash224 returns SHA3 hash calculated on data received from Writer.
 func SHA3hash224(hw ...Writer) []byte {
-	h := sha3.New224()
-	for _, w := range hw {
-		w.WriteHash(h)
-	}
-	return h.Sum(nil)
+	return SHA3224.Sum(hw...)
 }
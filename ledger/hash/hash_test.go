@@ -180,3 +180,21 @@ func Test_sha3hash224_IfChangedNotTheSame(t *testing.T) {
 		})
 	}
 }
+
+func TestAlgorithm(t *testing.T) {
+	data := &aRec{A1: 1, A2: "a"}
+	hashes := map[string]bool{}
+	for _, alg := range []Algorithm{SHA3224, SHA3256, BLAKE2b256} {
+		assert.True(t, alg.IsValid())
+		sum := alg.Sum(data)
+		assert.Len(t, sum, alg.Size(), alg.String())
+		assert.Equal(t, sum, alg.Sum(data))
+		hashes[string(sum)] = true
+	}
+	assert.Len(t, hashes, 3)
+	assert.Equal(t, SHA3hash224(data), SHA3224.Sum(data))
+
+	invalid := Algorithm(100)
+	assert.False(t, invalid.IsValid())
+	assert.Panics(t, func() { invalid.Sum(data) })
+}
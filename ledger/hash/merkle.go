@@ -0,0 +1,124 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package hash
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// Merkle tree nodes are hashed with different prefixes, so a leaf can't be presented as an inner node.
+const (
+	merkleLeafPrefix = 0
+	merkleNodePrefix = 1
+)
+
+type merkleBytes []byte
+
+func (b merkleBytes) WriteHash(w io.Writer) {
+	_, err := w.Write(b)
+	if err != nil {
+		panic(err)
+	}
+}
+
+// MerkleStep is a step of Merkle proof: hash of sibling node on the path from leaf to root.
+type MerkleStep struct {
+	Hash []byte
+	// Left is true if sibling node is on the left side.
+	Left bool
+}
+
+// MerkleProof is a path from leaf to root of Merkle tree.
+type MerkleProof []MerkleStep
+
+func (a Algorithm) merkleLeaf(leaf []byte) []byte {
+	return a.Sum(merkleBytes{merkleLeafPrefix}, merkleBytes(leaf))
+}
+
+func (a Algorithm) merkleNode(left, right []byte) []byte {
+	return a.Sum(merkleBytes{merkleNodePrefix}, merkleBytes(left), merkleBytes(right))
+}
+
+// merkleLevels returns all tree levels starting from leaf hashes. The last level contains only root.
+//
+// Nodes are paired from left to right. The last node of a level with odd number of nodes is promoted to the next
+// level as is.
+func (a Algorithm) merkleLevels(leaves [][]byte) [][][]byte {
+	level := make([][]byte, len(leaves))
+	for i, leaf := range leaves {
+		level[i] = a.merkleLeaf(leaf)
+	}
+	levels := [][][]byte{level}
+	for len(level) > 1 {
+		next := make([][]byte, 0, (len(level)+1)/2)
+		for i := 0; i < len(level); i += 2 {
+			if i+1 == len(level) {
+				next = append(next, level[i])
+				continue
+			}
+			next = append(next, a.merkleNode(level[i], level[i+1]))
+		}
+		levels = append(levels, next)
+		level = next
+	}
+	return levels
+}
+
+// MerkleRoot returns root of Merkle tree built over provided leaves in their order. Root of empty tree is the hash of
+// empty input.
+func (a Algorithm) MerkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		return a.Sum()
+	}
+	levels := a.merkleLevels(leaves)
+	return levels[len(levels)-1][0]
+}
+
+// MerkleProof returns inclusion proof of the leaf with provided index.
+func (a Algorithm) MerkleProof(leaves [][]byte, index int) (MerkleProof, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, errors.Errorf("leaf index %d is out of range [0, %d)", index, len(leaves))
+	}
+	var proof MerkleProof
+	levels := a.merkleLevels(leaves)
+	for _, level := range levels[:len(levels)-1] {
+		switch {
+		case index%2 == 1:
+			proof = append(proof, MerkleStep{Hash: level[index-1], Left: true})
+		case index+1 < len(level):
+			proof = append(proof, MerkleStep{Hash: level[index+1]})
+		}
+		index /= 2
+	}
+	return proof, nil
+}
+
+// VerifyMerkleProof checks that proof connects leaf with Merkle tree root.
+func (a Algorithm) VerifyMerkleProof(root, leaf []byte, proof MerkleProof) bool {
+	h := a.merkleLeaf(leaf)
+	for _, step := range proof {
+		if step.Left {
+			h = a.merkleNode(step.Hash, h)
+		} else {
+			h = a.merkleNode(h, step.Hash)
+		}
+	}
+	return bytes.Equal(h, root)
+}
@@ -0,0 +1,63 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package hash
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAlgorithm_MerkleProof(t *testing.T) {
+	for _, alg := range []Algorithm{SHA3224, SHA3256, BLAKE2b256} {
+		for n := 1; n <= 9; n++ {
+			leaves := make([][]byte, n)
+			for i := range leaves {
+				leaves[i] = []byte(fmt.Sprintf("leaf %d", i))
+			}
+			root := alg.MerkleRoot(leaves)
+			assert.Len(t, root, alg.Size())
+			for i, leaf := range leaves {
+				proof, err := alg.MerkleProof(leaves, i)
+				assert.NoError(t, err)
+				assert.True(t, alg.VerifyMerkleProof(root, leaf, proof), "%v: leaf %d of %d", alg, i, n)
+				assert.False(t, alg.VerifyMerkleProof(root, []byte("other"), proof))
+				if len(proof) > 0 {
+					proof[0].Left = !proof[0].Left
+					assert.False(t, alg.VerifyMerkleProof(root, leaf, proof))
+				}
+			}
+			_, err := alg.MerkleProof(leaves, n)
+			assert.Error(t, err)
+		}
+	}
+}
+
+func TestAlgorithm_MerkleRoot(t *testing.T) {
+	leaves := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	ab := SHA3224.merkleNode(SHA3224.merkleLeaf(leaves[0]), SHA3224.merkleLeaf(leaves[1]))
+	assert.Equal(t, SHA3224.merkleNode(ab, SHA3224.merkleLeaf(leaves[2])), SHA3224.MerkleRoot(leaves))
+	assert.Equal(t, SHA3224.merkleLeaf(leaves[0]), SHA3224.MerkleRoot(leaves[:1]))
+	assert.Equal(t, SHA3224.Sum(), SHA3224.MerkleRoot(nil))
+
+	// leaf can't be presented as an inner node
+	node := append(SHA3224.merkleLeaf(leaves[0]), SHA3224.merkleLeaf(leaves[1])...)
+	assert.NotEqual(t, SHA3224.MerkleRoot(leaves[:2]), SHA3224.MerkleRoot([][]byte{node}))
+	// order of leaves matters
+	assert.NotEqual(t, SHA3224.MerkleRoot(leaves), SHA3224.MerkleRoot([][]byte{leaves[1], leaves[0], leaves[2]}))
+}
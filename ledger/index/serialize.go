@@ -20,6 +20,8 @@ import (
 	"bytes"
 
 	"github.com/ugorji/go/codec"
+
+	"github.com/insolar/insolar/ledger/record"
 )
 
 // EncodeClassLifeline converts lifeline index into binary format
@@ -65,3 +67,26 @@ func DecodeObjectLifeline(buf []byte) (*ObjectLifeline, error) {
 	}
 	return &index, nil
 }
+
+// EncodeHistoryRef converts reference of lifeline history record into binary format. Unlike reference keys, encoded
+// references are decoded exactly as they were.
+func EncodeHistoryRef(ref *record.Reference) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := codec.NewEncoder(&buf, &codec.CborHandle{})
+	err := enc.Encode(ref)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecodeHistoryRef converts byte array into reference of lifeline history record.
+func DecodeHistoryRef(buf []byte) (*record.Reference, error) {
+	dec := codec.NewDecoder(bytes.NewReader(buf), &codec.CborHandle{})
+	var ref record.Reference
+	err := dec.Decode(&ref)
+	if err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
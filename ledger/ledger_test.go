@@ -55,6 +55,9 @@ func TestLedger_LevelDB_Init(t *testing.T) {
 
 func levelDBInit() storage.LedgerStorer {
 	// ledger, err := newLedger()
+	if err := leveldb.DropDB(); err != nil {
+		panic(err)
+	}
 	store, err := leveldb.InitDB()
 	if err != nil {
 		panic(err)
@@ -0,0 +1,184 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package pulse
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/insolar/insolar/ledger/record"
+)
+
+// ErrPulseNotIncreasing is returned when received pulse number is not greater than the current one.
+var ErrPulseNotIncreasing = errors.New("pulse number should increase")
+
+// Provider is the interface that wraps the Current method.
+//
+// Current returns current pulse number. Implementations should be safe for concurrent use.
+type Provider interface {
+	Current() record.PulseNum
+}
+
+// Manual is a pulse source controlled by the caller. It is intended for tests and tools.
+type Manual struct {
+	current uint32
+}
+
+// NewManual creates Manual provider starting from provided pulse number.
+func NewManual(start record.PulseNum) *Manual {
+	return &Manual{current: uint32(start)}
+}
+
+// Current returns current pulse number.
+func (m *Manual) Current() record.PulseNum {
+	return record.PulseNum(atomic.LoadUint32(&m.current))
+}
+
+// Set sets current pulse number. Pulse numbers with reserved bits set are rejected with record.ErrReservedPulse.
+func (m *Manual) Set(pn record.PulseNum) error {
+	if !pn.IsValid() {
+		return record.ErrReservedPulse
+	}
+	atomic.StoreUint32(&m.current, uint32(pn))
+	return nil
+}
+
+// Next increments current pulse number and returns the new value. Current pulse number is not changed and
+// record.ErrReservedPulse is returned if it is record.MaxPulseNum already.
+func (m *Manual) Next() (record.PulseNum, error) {
+	for {
+		current := atomic.LoadUint32(&m.current)
+		next := record.PulseNum(current + 1)
+		if !next.IsValid() {
+			return record.PulseNum(current), record.ErrReservedPulse
+		}
+		if atomic.CompareAndSwapUint32(&m.current, current, uint32(next)) {
+			return next, nil
+		}
+	}
+}
+
+// Ticker is a local pulse source which increments pulse number with fixed interval.
+type Ticker struct {
+	Manual
+
+	interval time.Duration
+	lock     sync.Mutex
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// NewTicker creates Ticker starting from provided pulse number. Call Start to begin generating pulses.
+func NewTicker(start record.PulseNum, interval time.Duration) *Ticker {
+	return &Ticker{
+		Manual:   Manual{current: uint32(start)},
+		interval: interval,
+	}
+}
+
+// Start begins pulse generation in a separate goroutine. Calling Start on running ticker does nothing.
+func (t *Ticker) Start() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.stop != nil {
+		return
+	}
+	t.stop = make(chan struct{})
+	t.done = make(chan struct{})
+
+	go func(stop, done chan struct{}) {
+		defer close(done)
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				// Pulse numbers are exhausted, so the ticker stays at the last one.
+				_, _ = t.Next()
+			case <-stop:
+				return
+			}
+		}
+	}(t.stop, t.done)
+}
+
+// Stop stops pulse generation and waits for the generating goroutine to exit.
+func (t *Ticker) Stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.stop == nil {
+		return
+	}
+	close(t.stop)
+	<-t.done
+	t.stop = nil
+	t.done = nil
+}
+
+// Network is a pulse source which receives pulses from the network layer.
+//
+// OnPulse should be registered as a handler of network pulse messages.
+type Network struct {
+	lock     sync.Mutex
+	current  record.PulseNum
+	handlers []func(record.PulseNum)
+}
+
+// NewNetwork creates Network provider starting from provided pulse number.
+func NewNetwork(start record.PulseNum) *Network {
+	return &Network{current: start}
+}
+
+// Current returns the latest pulse number received from network.
+func (n *Network) Current() record.PulseNum {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	return n.current
+}
+
+// OnPulse receives new pulse number from the network. Pulse numbers should strictly increase, otherwise
+// ErrPulseNotIncreasing is returned and the pulse is ignored. Pulse numbers with reserved bits set are ignored with
+// record.ErrReservedPulse.
+//
+// Subscribed handlers are called synchronously with the new pulse number.
+func (n *Network) OnPulse(pn record.PulseNum) error {
+	if !pn.IsValid() {
+		return record.ErrReservedPulse
+	}
+	n.lock.Lock()
+	if pn <= n.current {
+		n.lock.Unlock()
+		return ErrPulseNotIncreasing
+	}
+	n.current = pn
+	handlers := n.handlers
+	n.lock.Unlock()
+
+	for _, handler := range handlers {
+		handler(pn)
+	}
+	return nil
+}
+
+// Subscribe registers a handler which will be called on every accepted pulse.
+func (n *Network) Subscribe(handler func(record.PulseNum)) {
+	n.lock.Lock()
+	defer n.lock.Unlock()
+	n.handlers = append(n.handlers, handler)
+}
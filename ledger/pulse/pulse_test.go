@@ -0,0 +1,85 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package pulse
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/record"
+)
+
+func TestManual(t *testing.T) {
+	m := NewManual(10)
+	assert.Equal(t, record.PulseNum(10), m.Current())
+	next, err := m.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, record.PulseNum(11), next)
+	assert.Equal(t, record.PulseNum(11), m.Current())
+	assert.NoError(t, m.Set(5))
+	assert.Equal(t, record.PulseNum(5), m.Current())
+
+	assert.Equal(t, record.ErrReservedPulse, m.Set(1<<31))
+	assert.Equal(t, record.PulseNum(5), m.Current())
+	assert.NoError(t, m.Set(record.MaxPulseNum))
+	next, err = m.Next()
+	assert.Equal(t, record.ErrReservedPulse, err)
+	assert.Equal(t, record.MaxPulseNum, next)
+	assert.Equal(t, record.MaxPulseNum, m.Current())
+}
+
+func TestTicker(t *testing.T) {
+	ticker := NewTicker(100, time.Millisecond)
+	assert.Equal(t, record.PulseNum(100), ticker.Current())
+
+	ticker.Start()
+	ticker.Start()
+	for i := 0; ticker.Current() < 103 && i < 1000; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	ticker.Stop()
+	ticker.Stop()
+
+	stopped := ticker.Current()
+	assert.True(t, stopped >= 103)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, stopped, ticker.Current())
+}
+
+func TestNetwork(t *testing.T) {
+	n := NewNetwork(1)
+	var lock sync.Mutex
+	var received []record.PulseNum
+	n.Subscribe(func(pn record.PulseNum) {
+		lock.Lock()
+		defer lock.Unlock()
+		received = append(received, pn)
+	})
+
+	assert.NoError(t, n.OnPulse(5))
+	assert.Equal(t, record.PulseNum(5), n.Current())
+	assert.Equal(t, ErrPulseNotIncreasing, n.OnPulse(5))
+	assert.Equal(t, ErrPulseNotIncreasing, n.OnPulse(3))
+	assert.NoError(t, n.OnPulse(6))
+	assert.Equal(t, record.PulseNum(6), n.Current())
+	assert.Equal(t, record.ErrReservedPulse, n.OnPulse(1<<31))
+	assert.Equal(t, record.PulseNum(6), n.Current())
+	assert.Equal(t, []record.PulseNum{5, 6}, received)
+}
@@ -0,0 +1,32 @@
+//go:build gofuzz
+// +build gofuzz
+
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package record
+
+// Fuzz is an entry point for go-fuzz. It checks that every parsed reference is in canonical form.
+func Fuzz(data []byte) int {
+	ref, err := ParseReference(string(data))
+	if err != nil {
+		return 0
+	}
+	if ref.String() != string(data) {
+		panic("parsed reference is not canonical: " + string(data))
+	}
+	return 1
+}
@@ -20,6 +20,8 @@ import (
 	"encoding/binary"
 	"io"
 
+	"github.com/pkg/errors"
+
 	"github.com/insolar/insolar/ledger/hash"
 )
 
@@ -28,10 +30,14 @@ const (
 	HashSize = 28
 	// PulseNumSize - 4 bytes is a PulseNum size (uint32)
 	PulseNumSize = 4
-	// IDSize is the size in bytes of ID binary representation.
+	// IDSize is the size in bytes of ID binary representation with default hash algorithm.
 	IDSize = PulseNumSize + HashSize
-	// RefIDSize is the size in bytes of Reference binary representation.
+	// RefIDSize is the size in bytes of Reference binary representation with default hash algorithm.
 	RefIDSize = IDSize * 2
+	// MaxIDSize is the maximum size in bytes of ID binary representation.
+	MaxIDSize = PulseNumSize + 1 + hash.MaxSize
+	// MaxRefIDSize is the maximum size in bytes of Reference binary representation.
+	MaxRefIDSize = MaxIDSize * 2
 )
 
 // ProjectionType is a "view filter" for record.
@@ -51,6 +57,18 @@ type PulseNum uint32
 // It is only allowed for Storage.
 const SpecialPulseNumber PulseNum = 65536
 
+// MaxPulseNum is the greatest PulseNum with reserved bits unset.
+const MaxPulseNum PulseNum = 1<<30 - 1
+
+// ErrReservedPulse is returned when PulseNum has reserved bits set. Such pulse can't be used for records, because
+// the upper bit of binary ID is the hash algorithm flag (see ID2Bytes).
+var ErrReservedPulse = errors.New("pulse number has reserved bits set")
+
+// IsValid checks that reserved bits of PulseNum are unset.
+func (pn PulseNum) IsValid() bool {
+	return pn <= MaxPulseNum
+}
+
 // ArchType is a virtual machine runtime type
 type ArchType uint32
 
@@ -59,10 +77,12 @@ type TypeID uint32
 
 // ID is a composite identifier for records.
 //
-// Hash is a bytes slice here to avoid copy of Hash array.
+// Hash is a bytes slice here to avoid copy of Hash array. Algorithm is the hash function used to calculate Hash,
+// zero value is SHA3-224 which is used by all legacy records.
 type ID struct {
-	Pulse PulseNum
-	Hash  []byte
+	Pulse     PulseNum
+	Hash      []byte
+	Algorithm hash.Algorithm `codec:",omitempty"`
 }
 
 // Record is base interface for all records.
@@ -70,10 +90,13 @@ type Record interface {
 	Domain() ID
 }
 
-// SHA3Hash224 hashes Record by it's CBOR representation and type identifier.
+// SHA3Hash224 hashes Record by it's CBOR representation, type identifier and schema version.
 func SHA3Hash224(rec Record) []byte {
-	cborBlob := MustEncode(rec)
-	return hash.SHA3hash224(getTypeIDbyRecord(rec), hashableBytes(cborBlob))
+	raw, err := EncodeToRaw(rec)
+	if err != nil {
+		panic(err)
+	}
+	return raw.Hash()
 }
 
 // ID evaluates record ID on PulseNum for Record.
@@ -104,6 +127,9 @@ func (id ID) WriteHash(w io.Writer) {
 
 // IsEqual checks equality of IDs.
 func (id ID) IsEqual(id2 ID) bool {
+	if id.Algorithm != id2.Algorithm {
+		return false
+	}
 	if (id.Hash == nil) != (id2.Hash == nil) {
 		return false
 	}
@@ -133,13 +159,10 @@ type Reference struct {
 }
 
 // Key generates Reference byte representation (key without prefix).
+//
+// Key has RefIDSize length if both IDs use default hash algorithm. Use ParseRefKey to read it back.
 func (ref *Reference) Key() []byte {
-	b := make([]byte, RefIDSize)
-	dk := ID2Bytes(ref.Domain)
-	rk := ID2Bytes(ref.Record)
-	_ = copy(b[:IDSize], dk)
-	_ = copy(b[IDSize:], rk)
-	return b
+	return append(ID2Bytes(ref.Domain), ID2Bytes(ref.Record)...)
 }
 
 // IsEqual checks equality of References.
@@ -35,18 +35,46 @@ func TestCodeRecord_GetCode(t *testing.T) {
 		},
 	}
 
-	_, err := rec.GetCode([]ArchType{15})
+	_, err := rec.GetCode([]ArchType{15}, nil)
 	assert.Error(t, err)
 
-	code, err := rec.GetCode([]ArchType{3, 2, 1})
+	code, err := rec.GetCode([]ArchType{3, 2, 1}, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, []byte{2}, code)
 
-	code, err = rec.GetCode([]ArchType{1})
+	code, err = rec.GetCode([]ArchType{1}, nil)
 	assert.NoError(t, err)
 	assert.Equal(t, []byte{1}, code)
 }
 
+func TestCodeRecord_GetCodeFromBlob(t *testing.T) {
+	blobs := map[string][]byte{}
+	getBlob := func(hash []byte) ([]byte, error) {
+		return blobs[string(hash)], nil
+	}
+	blob := []byte{3}
+	blobs[string(BlobHash(blob))] = blob
+
+	rec := CodeRecord{
+		TargetedCode: map[ArchType][]byte{
+			1: {1},
+		},
+		CodeBlobs: map[ArchType][]byte{
+			3: BlobHash(blob),
+		},
+	}
+
+	code, err := rec.GetCode([]ArchType{3, 1}, getBlob)
+	assert.NoError(t, err)
+	assert.Equal(t, blob, code)
+
+	_, err = rec.GetCode([]ArchType{3}, nil)
+	assert.Equal(t, ErrArchUnavailable, err)
+
+	assert.Equal(t, BlobHash([]byte{3}), BlobHash([]byte{3}))
+	assert.NotEqual(t, BlobHash([]byte{3}), BlobHash([]byte{4}))
+}
+
 func TestPulseNumID(t *testing.T) {
 	pulse0 := PulseNum(0)
 	pulse1 := PulseNum(1)
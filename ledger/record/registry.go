@@ -0,0 +1,184 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package record
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/ugorji/go/codec"
+)
+
+// SchemaVersion is a version of record type layout. Zero version is the layout records had before schema versions
+// were introduced.
+type SchemaVersion uint32
+
+// WriteHash implements hash.Writer interface.
+func (v SchemaVersion) WriteHash(w io.Writer) {
+	err := binary.Write(w, binary.BigEndian, v)
+	if err != nil {
+		panic("binary.Write failed:" + err.Error())
+	}
+}
+
+// Upgrade converts record fields from previous schema version to the next one in place. Fields are decoded from
+// CBOR into generic values, e.g. nested records are maps and byte strings are []byte.
+type Upgrade func(fields map[string]interface{}) error
+
+// recordType is a registry entry of record type.
+type recordType struct {
+	id  TypeID
+	typ reflect.Type
+	// upgrades[i] converts schema version i to i+1.
+	upgrades []Upgrade
+}
+
+// version returns current schema version of the type.
+func (rt *recordType) version() SchemaVersion {
+	return SchemaVersion(len(rt.upgrades))
+}
+
+var (
+	typesByID   = map[TypeID]*recordType{}
+	typesByType = map[reflect.Type]*recordType{}
+)
+
+// register adds record type to registry. It panics if type or its id is already registered.
+//
+// Changing record layout requires a new upgrade to be appended to the type's upgrades, so stored records of older
+// versions can still be decoded. Current schema version of the type equals the number of its upgrades.
+func register(id TypeID, proto Record, upgrades ...Upgrade) {
+	typ := reflect.TypeOf(proto)
+	if typ.Kind() != reflect.Ptr {
+		panic(fmt.Sprintf("record type %T should be a pointer", proto))
+	}
+	if _, ok := typesByID[id]; ok {
+		panic(fmt.Sprintf("record type id %d is already registered", id))
+	}
+	if _, ok := typesByType[typ]; ok {
+		panic(fmt.Sprintf("record type %T is already registered", proto))
+	}
+	rt := &recordType{id: id, typ: typ.Elem(), upgrades: upgrades}
+	typesByID[id] = rt
+	typesByType[typ] = rt
+}
+
+func init() {
+	// request records
+	register(requestRecordID, &RequestRecord{})
+	register(callRequestID, &CallRequest{})
+	register(lockUnlockRequestID, &LockUnlockRequest{})
+	register(readRecordRequestID, &ReadRecordRequest{})
+	register(readObjectID, &ReadObject{})
+	register(readObjectCompositeID, &ReadObjectComposite{})
+	// result records
+	register(resultRecordID, &ResultRecord{})
+	register(wipeOutRecordID, &WipeOutRecord{})
+	register(readRecordResultID, &ReadRecordResult{})
+	register(statelessCallResultID, &StatelessCallResult{})
+	register(statelessExceptionResultID, &StatelessExceptionResult{})
+	register(readObjectResultID, &ReadObjectResult{})
+	register(specialResultID, &SpecialResult{})
+	register(lockUnlockResultID, &LockUnlockResult{})
+	register(rejectionResultID, &RejectionResult{})
+	register(activationRecordID, &ActivationRecord{})
+	register(classActivateRecordID, &ClassActivateRecord{})
+	register(objectActivateRecordID, &ObjectActivateRecord{})
+	register(codeRecordID, &CodeRecord{})
+	register(amendRecordID, &AmendRecord{})
+	register(classAmendRecordID, &ClassAmendRecord{})
+	register(deactivationRecordID, &DeactivationRecord{})
+	register(objectAmendRecordID, &ObjectAmendRecord{})
+	register(statefulCallResultID, &StatefulCallResult{})
+	register(statefulExceptionResultID, &StatefulExceptionResult{})
+	register(enforcedObjectAmendRecordID, &EnforcedObjectAmendRecord{})
+	register(objectAppendRecordID, &ObjectAppendRecord{})
+}
+
+// getRecordByTypeID returns Record interface with concrete record type under the hood.
+// This is useful with deserialization cases.
+func getRecordByTypeID(id TypeID) Record {
+	rt, ok := typesByID[id]
+	if !ok {
+		panic(fmt.Errorf("unknown record type id %v", id))
+	}
+	return reflect.New(rt.typ).Interface().(Record)
+}
+
+// getTypeIDbyRecord returns record's TypeID based on concrete record type of Record interface.
+func getTypeIDbyRecord(rec Record) TypeID {
+	return typeOf(rec).id
+}
+
+func typeOf(rec Record) *recordType {
+	rt, ok := typesByType[reflect.TypeOf(rec)]
+	if !ok {
+		panic(fmt.Errorf("can't find record id by type %T", rec))
+	}
+	return rt
+}
+
+// Decode decodes Raw to Record. Records of older schema versions are upgraded to the current version.
+func (raw *Raw) Decode() (Record, error) {
+	rt, ok := typesByID[raw.Type]
+	if !ok {
+		return nil, errors.Errorf("unknown record type id %v", raw.Type)
+	}
+	if raw.Version > rt.version() {
+		return nil, errors.Errorf(
+			"schema version %d of record type %d is newer than supported %d", raw.Version, raw.Type, rt.version(),
+		)
+	}
+	data := raw.Data
+	if raw.Version < rt.version() {
+		var err error
+		data, err = rt.upgrade(raw.Version, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rec := reflect.New(rt.typ).Interface().(Record)
+	err := codec.NewDecoderBytes(data, &codec.CborHandle{}).Decode(rec)
+	if err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// upgrade converts record data from provided schema version to the current one.
+func (rt *recordType) upgrade(from SchemaVersion, data []byte) ([]byte, error) {
+	fields := map[string]interface{}{}
+	err := codec.NewDecoderBytes(data, &codec.CborHandle{}).Decode(&fields)
+	if err != nil {
+		return nil, err
+	}
+	for v := from; v < rt.version(); v++ {
+		if err := rt.upgrades[v](fields); err != nil {
+			return nil, errors.Wrapf(err, "failed to upgrade record type %d from schema version %d", rt.id, v)
+		}
+	}
+	var b bytes.Buffer
+	err = codec.NewEncoder(&b, &codec.CborHandle{}).Encode(fields)
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
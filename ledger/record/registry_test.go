@@ -0,0 +1,143 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package record
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+
+	"github.com/insolar/insolar/ledger/hash"
+)
+
+// testVersionedRecord is a record type which was changed twice: version 0 had Title field instead of Name, version 1
+// had no Count field.
+type testVersionedRecord struct {
+	Name  string
+	Count uint64
+}
+
+func (r *testVersionedRecord) Domain() ID {
+	return ID{}
+}
+
+type testBrokenRecord struct {
+	Name string
+}
+
+func (r *testBrokenRecord) Domain() ID {
+	return ID{}
+}
+
+const (
+	testVersionedRecordID TypeID = 1000
+	testBrokenRecordID    TypeID = 1001
+)
+
+func init() {
+	register(testVersionedRecordID, &testVersionedRecord{},
+		func(fields map[string]interface{}) error {
+			fields["Name"] = fields["Title"]
+			delete(fields, "Title")
+			return nil
+		},
+		func(fields map[string]interface{}) error {
+			fields["Count"] = uint64(1)
+			return nil
+		},
+	)
+	register(testBrokenRecordID, &testBrokenRecord{}, func(map[string]interface{}) error {
+		return errors.New("broken")
+	})
+}
+
+func encodeTestData(t *testing.T, v interface{}) []byte {
+	var b []byte
+	err := codec.NewEncoderBytes(&b, &codec.CborHandle{}).Encode(v)
+	assert.NoError(t, err)
+	return b
+}
+
+func TestRaw_DecodeUpgradesOldVersions(t *testing.T) {
+	v0 := &Raw{Type: testVersionedRecordID, Data: encodeTestData(t, &struct{ Title string }{"old"})}
+	rec, err := v0.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, &testVersionedRecord{Name: "old", Count: 1}, rec)
+
+	v1 := &Raw{Type: testVersionedRecordID, Version: 1, Data: encodeTestData(t, &struct{ Name string }{"v1"})}
+	rec, err = v1.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, &testVersionedRecord{Name: "v1", Count: 1}, rec)
+
+	current := &testVersionedRecord{Name: "current", Count: 5}
+	raw, err := EncodeToRaw(current)
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion(2), raw.Version)
+	rec, err = raw.Decode()
+	assert.NoError(t, err)
+	assert.Equal(t, current, rec)
+
+	decoded, err := DecodeToRaw(MustEncodeRaw(raw))
+	assert.NoError(t, err)
+	assert.Equal(t, raw.Version, decoded.Version)
+}
+
+func TestRaw_DecodeErrors(t *testing.T) {
+	_, err := (&Raw{Type: 0}).Decode()
+	assert.Error(t, err)
+
+	newer := &Raw{Type: testVersionedRecordID, Version: 3, Data: encodeTestData(t, &testVersionedRecord{})}
+	_, err = newer.Decode()
+	assert.Error(t, err)
+
+	broken := &Raw{Type: testBrokenRecordID, Data: encodeTestData(t, &testBrokenRecord{})}
+	_, err = broken.Decode()
+	assert.Error(t, err)
+	assert.Panics(t, func() { broken.ToRecord() })
+}
+
+func TestRaw_VersionHashing(t *testing.T) {
+	raw, err := EncodeToRaw(&CallRequest{ParamMemory: []byte{1, 2, 3}})
+	assert.NoError(t, err)
+	assert.Equal(t, SchemaVersion(0), raw.Version)
+	// records of zero version are hashed the same way as before schema versions were introduced
+	assert.Equal(t, hash.SHA3hash224(raw.Type, hashableBytes(raw.Data)), raw.Hash())
+
+	versioned := *raw
+	versioned.Version = 1
+	assert.NotEqual(t, raw.Hash(), versioned.Hash())
+}
+
+func TestRegister_RejectsDuplicates(t *testing.T) {
+	assert.Panics(t, func() { register(callRequestID, &testBrokenRecord{}) })
+	assert.Panics(t, func() { register(2000, &CallRequest{}) })
+}
+
+func TestRegistry_AllTypesRoundTrip(t *testing.T) {
+	for id, rt := range typesByID {
+		rec := getRecordByTypeID(id)
+		assert.Equal(t, id, TypeIDOf(rec))
+		raw, err := EncodeToRaw(rec)
+		assert.NoError(t, err)
+		assert.Equal(t, rt.version(), raw.Version)
+		decoded, err := raw.Decode()
+		assert.NoError(t, err)
+		assert.Equal(t, rec, decoded)
+	}
+}
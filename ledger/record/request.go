@@ -20,12 +20,24 @@ import (
 	"time"
 )
 
+// Request is implemented by all request records.
+type Request interface {
+	Record
+	// TargetRef returns reference of the record request is addressed to.
+	TargetRef() Reference
+}
+
 // RequestRecord is common type for all requests.
 type RequestRecord struct {
 	Requester Reference
 	Target    Reference
 }
 
+// TargetRef implements Request interface.
+func (rec *RequestRecord) TargetRef() Reference {
+	return rec.Target
+}
+
 // Domain implements Record interface
 func (rec *RequestRecord) Domain() ID {
 	// FIXME: return proper domain ID
@@ -40,6 +52,12 @@ type CallRequest struct {
 	CallInterface       Reference
 	CallMethodSignature uint32
 	ParamMemory         Memory
+
+	// Method is a name of the called method.
+	Method string `codec:",omitempty"`
+	// ObjectState is a reference to the object state the call is made on. Requests of identical calls made on
+	// different states are different records.
+	ObjectState *Reference `codec:",omitempty"`
 }
 
 // CallMethod is a contract method number to call.
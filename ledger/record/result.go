@@ -144,19 +144,32 @@ type CodeRecord struct {
 
 	Interfaces   []Reference
 	TargetedCode map[ArchType][]byte // []MachineBinaryCode
+	CodeBlobs    map[ArchType][]byte `codec:",omitempty"` // content hashes of code stored as blobs
 	SourceCode   string              // ObjectSourceCode
 }
 
+// BlobGetter fetches code blob by its content hash.
+type BlobGetter func(hash []byte) ([]byte, error)
+
+// ErrArchUnavailable returns if code record does not contain code for any of preferred architectures.
+var ErrArchUnavailable = errors.New("code for preferred architectures not found")
+
 // GetCode returns class code according to provided architecture preferences. If preferences are not provided or the
 // record does not contain code for any of provided architectures an error will be returned.
-func (r *CodeRecord) GetCode(archPref []ArchType) ([]byte, error) {
+//
+// Code referenced by content hash is fetched with getBlob. It can be nil if the record stores code inline only.
+func (r *CodeRecord) GetCode(archPref []ArchType, getBlob BlobGetter) ([]byte, error) {
 	for _, archType := range archPref {
 		code, ok := r.TargetedCode[archType]
 		if ok {
 			return code, nil
 		}
+		blobHash, ok := r.CodeBlobs[archType]
+		if ok && getBlob != nil {
+			return getBlob(blobHash)
+		}
 	}
-	return nil, errors.New("code for preferred architectures not found")
+	return nil, ErrArchUnavailable
 }
 
 // AmendRecord is produced when we modify another record in ledger.
@@ -19,19 +19,24 @@ package record
 import (
 	"bytes"
 	"encoding/binary"
-	"fmt"
 	"io"
 
 	"github.com/insolar/insolar/ledger/hash"
+	"github.com/pkg/errors"
 	"github.com/ugorji/go/codec"
 )
 
 // Raw struct contains raw serialized record.
 // We need raw blob to not have dependency on record structure changes in future,
 // and have ability of consistent hash checking on old records.
+//
+// Version is the schema version of record type Data is encoded with (see Upgrade). Signature is optional and is not
+// covered by record hash.
 type Raw struct {
-	Type TypeID
-	Data []byte
+	Type      TypeID
+	Version   SchemaVersion `codec:",omitempty"`
+	Data      []byte
+	Signature *Signature `codec:",omitempty"`
 }
 
 // DecodeToRaw decodes bytes to Raw struct from CBOR.
@@ -79,45 +84,113 @@ func (b hashableBytes) WriteHash(w io.Writer) {
 	}
 }
 
-// Hash generates hash for Raw record.
+// Hash generates hash for Raw record with default SHA3-224 algorithm.
 func (raw *Raw) Hash() []byte {
-	return hash.SHA3hash224(raw.Type, hashableBytes(raw.Data))
+	return raw.Sum(hash.SHA3224)
 }
 
-// ToRecord decodes Raw to Record.
+// Sum generates hash for Raw record with provided algorithm. Zero schema version is not hashed, so records encoded
+// before schema versions were introduced keep their hashes.
+func (raw *Raw) Sum(alg hash.Algorithm) []byte {
+	if raw.Version == 0 {
+		return alg.Sum(raw.Type, hashableBytes(raw.Data))
+	}
+	return alg.Sum(raw.Type, raw.Version, hashableBytes(raw.Data))
+}
+
+// BlobHash returns content hash of code blob. Equal blobs always have equal hashes.
+func BlobHash(data []byte) []byte {
+	return hash.SHA3hash224(hashableBytes(data))
+}
+
+// ToRecord wraps Decode, panics on decode errors.
 func (raw *Raw) ToRecord() Record {
-	cborH := &codec.CborHandle{}
-	rec := getRecordByTypeID(raw.Type)
-	dec := codec.NewDecoder(bytes.NewReader(raw.Data), cborH)
-	err := dec.Decode(rec)
+	rec, err := raw.Decode()
 	if err != nil {
 		panic(err)
 	}
 	return rec
 }
 
-// Bytes2ID converts ID from byte representation to struct.
+// algorithmFlag is set in the upper (reserved) bit of binary pulse number if ID hash algorithm is not the default
+// one. Algorithm identifier follows pulse number in this case.
+const algorithmFlag = 1 << 31
+
+// Bytes2ID converts ID from byte representation to struct. It panics on malformed input, use ParseID to get an error.
+//
+// Returned ID hash shares memory with b.
 func Bytes2ID(b []byte) ID {
-	return ID{
-		Pulse: PulseNum(binary.BigEndian.Uint32(b[:PulseNumSize])),
-		Hash:  b[PulseNumSize:],
+	id, _, err := ParseID(b)
+	if err != nil {
+		panic(err)
 	}
+	return id
+}
+
+// ParseID reads ID from the beginning of b and returns it with number of bytes consumed.
+//
+// Legacy IDs are IDSize bytes long: big endian pulse number followed by SHA3-224 hash. If upper bit of pulse number
+// is set, the next byte is hash algorithm identifier and hash has its size.
+// Returned ID hash shares memory with b.
+func ParseID(b []byte) (ID, int, error) {
+	if len(b) < PulseNumSize {
+		return ID{}, 0, errors.Errorf("ID is too short: %d bytes", len(b))
+	}
+	pulse := binary.BigEndian.Uint32(b[:PulseNumSize])
+	if pulse&algorithmFlag == 0 {
+		if len(b) < IDSize {
+			return ID{}, 0, errors.Errorf("ID is too short: %d bytes", len(b))
+		}
+		return ID{Pulse: PulseNum(pulse), Hash: b[PulseNumSize:IDSize]}, IDSize, nil
+	}
+	if len(b) < PulseNumSize+1 {
+		return ID{}, 0, errors.Errorf("ID is too short: %d bytes", len(b))
+	}
+	alg := hash.Algorithm(b[PulseNumSize])
+	if alg == hash.SHA3224 || !alg.IsValid() {
+		return ID{}, 0, errors.Errorf("wrong ID hash algorithm %d", byte(alg))
+	}
+	size := PulseNumSize + 1 + alg.Size()
+	if len(b) < size {
+		return ID{}, 0, errors.Errorf("ID is too short: %d bytes", len(b))
+	}
+	return ID{
+		Pulse:     PulseNum(pulse &^ algorithmFlag),
+		Hash:      b[PulseNumSize+1 : size],
+		Algorithm: alg,
+	}, size, nil
 }
 
 // ID2Bytes converts ID struct to it's byte representation.
+//
+// IDs with default hash algorithm are encoded in legacy IDSize format. Hash shorter than algorithm's hash size is
+// padded with zeros.
 func ID2Bytes(id ID) []byte {
-	var err error
-	var b = make([]byte, IDSize)
-	buf := bytes.NewBuffer(b[:0])
-	err = binary.Write(buf, binary.BigEndian, id.Pulse)
+	if id.Algorithm == hash.SHA3224 {
+		b := make([]byte, IDSize)
+		binary.BigEndian.PutUint32(b, uint32(id.Pulse))
+		_ = copy(b[PulseNumSize:], id.Hash)
+		return b
+	}
+	b := make([]byte, PulseNumSize+1+id.Algorithm.Size())
+	binary.BigEndian.PutUint32(b, uint32(id.Pulse)|algorithmFlag)
+	b[PulseNumSize] = byte(id.Algorithm)
+	_ = copy(b[PulseNumSize+1:], id.Hash)
+	return b
+}
+
+// ParseRefKey reads Reference in Reference.Key format from the beginning of b and returns it with number of bytes
+// consumed. Returned reference IDs share memory with b.
+func ParseRefKey(b []byte) (*Reference, int, error) {
+	domain, dn, err := ParseID(b)
 	if err != nil {
-		panic("binary.Write failed to write PulseNum:" + err.Error())
+		return nil, 0, errors.Wrap(err, "domain")
 	}
-	err = binary.Write(buf, binary.BigEndian, id.Hash)
+	rec, rn, err := ParseID(b[dn:])
 	if err != nil {
-		panic("binary.Write failed to write Hash:" + err.Error())
+		return nil, 0, errors.Wrap(err, "record")
 	}
-	return b
+	return &Reference{Domain: domain, Record: rec}, dn + rn, nil
 }
 
 // record type ids for record types
@@ -156,132 +229,9 @@ const (
 	objectAppendRecordID        TypeID = 27
 )
 
-// getRecordByTypeID returns Record interface with concrete record type under the hood.
-// This is useful with deserialization cases.
-func getRecordByTypeID(id TypeID) Record { // nolint: gocyclo
-	switch id {
-	// request records
-	case requestRecordID:
-		return &RequestRecord{}
-	case callRequestID:
-		return &CallRequest{}
-	case lockUnlockRequestID:
-		return &LockUnlockRequest{}
-	case readRecordRequestID:
-		return &ReadRecordRequest{}
-	case readObjectID:
-		return &ReadObject{}
-	case readObjectCompositeID:
-		return &ReadObjectComposite{}
-	// result records
-	// case resultRecordID:
-	case wipeOutRecordID:
-		return &WipeOutRecord{}
-	case readRecordResultID:
-		return &ReadRecordResult{}
-	case statelessCallResultID:
-		return &StatelessCallResult{}
-	case statelessExceptionResultID:
-		return &StatelessExceptionResult{}
-	case readObjectResultID:
-		return &ReadObjectResult{}
-	case specialResultID:
-		return &SpecialResult{}
-	case lockUnlockResultID:
-		return &LockUnlockResult{}
-	case rejectionResultID:
-		return &RejectionResult{}
-	case activationRecordID:
-		return &ActivationRecord{}
-	case classActivateRecordID:
-		return &ClassActivateRecord{}
-	case objectActivateRecordID:
-		return &ObjectActivateRecord{}
-	case codeRecordID:
-		return &CodeRecord{}
-	case amendRecordID:
-		return &AmendRecord{}
-	case classAmendRecordID:
-		return &ClassAmendRecord{}
-	case deactivationRecordID:
-		return &DeactivationRecord{}
-	case objectAmendRecordID:
-		return &ObjectAmendRecord{}
-	case statefulCallResultID:
-		return &StatefulCallResult{}
-	case statefulExceptionResultID:
-		return &StatefulExceptionResult{}
-	case enforcedObjectAmendRecordID:
-		return &EnforcedObjectAmendRecord{}
-	case objectAppendRecordID:
-		return &ObjectAppendRecord{}
-	default:
-		panic(fmt.Errorf("unknown record type id %v", id))
-	}
-}
-
-// getRecordByTypeID returns record's TypeID based on concrete record type of Record interface.
-func getTypeIDbyRecord(rec Record) TypeID { // nolint: gocyclo, megacheck
-	switch v := rec.(type) {
-	// request records
-	case *RequestRecord:
-		return requestRecordID
-	case *CallRequest:
-		return callRequestID
-	case *LockUnlockRequest:
-		return lockUnlockRequestID
-	case *ReadRecordRequest:
-		return readRecordRequestID
-	case *ReadObject:
-		return readObjectID
-	case *ReadObjectComposite:
-		return readObjectCompositeID
-	// result records
-	case *ResultRecord:
-		return resultRecordID
-	case *WipeOutRecord:
-		return wipeOutRecordID
-	case *ReadRecordResult:
-		return readRecordResultID
-	case *StatelessCallResult:
-		return statelessCallResultID
-	case *StatelessExceptionResult:
-		return statelessExceptionResultID
-	case *ReadObjectResult:
-		return readObjectResultID
-	case *SpecialResult:
-		return specialResultID
-	case *LockUnlockResult:
-		return lockUnlockResultID
-	case *RejectionResult:
-		return rejectionResultID
-	case *ActivationRecord:
-		return activationRecordID
-	case *ClassActivateRecord:
-		return classActivateRecordID
-	case *ObjectActivateRecord:
-		return objectActivateRecordID
-	case *CodeRecord:
-		return codeRecordID
-	case *AmendRecord:
-		return amendRecordID
-	case *ClassAmendRecord:
-		return classAmendRecordID
-	case *DeactivationRecord:
-		return deactivationRecordID
-	case *ObjectAmendRecord:
-		return objectAmendRecordID
-	case *StatefulCallResult:
-		return statefulCallResultID
-	case *StatefulExceptionResult:
-		return statefulExceptionResultID
-	case *EnforcedObjectAmendRecord:
-		return enforcedObjectAmendRecordID
-	case *ObjectAppendRecord:
-		return objectAppendRecordID
-	default:
-		panic(fmt.Errorf("can't find record id by type %T", v))
-	}
+// TypeIDOf returns TypeID of provided record. It panics if record type is unknown.
+func TypeIDOf(rec Record) TypeID {
+	return getTypeIDbyRecord(rec)
 }
 
 // Encode serializes record to CBOR.
@@ -308,8 +258,10 @@ func EncodeToRaw(rec Record) (*Raw, error) {
 	if err != nil {
 		panic(err)
 	}
+	rt := typeOf(rec)
 	return &Raw{
-		Type: getTypeIDbyRecord(rec),
-		Data: b,
+		Type:    rt.id,
+		Version: rt.version(),
+		Data:    b,
 	}, nil
 }
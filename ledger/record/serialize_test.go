@@ -18,9 +18,12 @@ package record
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/hash"
 )
 
 var convertTests = []struct {
@@ -45,6 +48,86 @@ func Test_KeyBytesConversion(t *testing.T) {
 	}
 }
 
+func TestParseID_Algorithms(t *testing.T) {
+	legacy := ID{Pulse: 10, Hash: str2Bytes("21853428b06925493bf23d2c5ba76ee86e3e3c1a13fe164307250193")}
+	assert.Equal(t, convertTests[0].b, ID2Bytes(legacy))
+
+	for _, alg := range []hash.Algorithm{hash.SHA3224, hash.SHA3256, hash.BLAKE2b256} {
+		t.Run(alg.String(), func(t *testing.T) {
+			id := ID{Pulse: 10, Hash: alg.Sum(hashableBytes("data")), Algorithm: alg}
+			b := ID2Bytes(id)
+			got, n, err := ParseID(append(b, 1, 2, 3))
+			assert.NoError(t, err)
+			assert.Equal(t, len(b), n)
+			assert.Equal(t, id, got)
+			assert.True(t, id.IsEqual(got))
+
+			ref := Reference{Domain: legacy, Record: id}
+			gotRef, n, err := ParseRefKey(ref.Key())
+			assert.NoError(t, err)
+			assert.Equal(t, len(ref.Key()), n)
+			assert.Equal(t, ref, *gotRef)
+		})
+	}
+
+	// the same hash bytes with different algorithms are different IDs
+	assert.False(t, ID{Hash: make([]byte, 32), Algorithm: hash.SHA3256}.IsEqual(
+		ID{Hash: make([]byte, 32), Algorithm: hash.BLAKE2b256}))
+}
+
+func TestParseID_RejectsMalformed(t *testing.T) {
+	cases := map[string][]byte{
+		"Empty":          nil,
+		"ShortLegacy":    str2Bytes("0000000a" + "2185"),
+		"ShortExtended":  str2Bytes("8000000a" + "01" + "2185"),
+		"NoAlgorithm":    str2Bytes("8000000a"),
+		"FlaggedDefault": str2Bytes("8000000a" + "00" + strings.Repeat("00", HashSize)),
+		"UnknownAlg":     str2Bytes("8000000a" + "ff" + strings.Repeat("00", 64)),
+	}
+	for name, b := range cases {
+		_, _, err := ParseID(b)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestID_LegacyEncodingUnchanged(t *testing.T) {
+	rec := &ClassAmendRecord{NewCode: Reference{Record: ID{Pulse: 1, Hash: []byte{1}}}}
+	raw, err := EncodeToRaw(rec)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(raw.Data), "Algorithm")
+
+	rec.NewCode.Record.Algorithm = hash.SHA3256
+	raw, err = EncodeToRaw(rec)
+	assert.NoError(t, err)
+	assert.Equal(t, hash.SHA3256, raw.ToRecord().(*ClassAmendRecord).NewCode.Record.Algorithm)
+}
+
+func TestCallRequest_LegacyEncodingUnchanged(t *testing.T) {
+	rec := &CallRequest{ParamMemory: Memory{1}}
+	raw, err := EncodeToRaw(rec)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(raw.Data), "Method\"")
+	assert.NotContains(t, string(raw.Data), "ObjectState")
+
+	rec.Method = "Echo"
+	rec.ObjectState = &Reference{Record: ID{Pulse: 1, Hash: []byte{1}}}
+	raw, err = EncodeToRaw(rec)
+	assert.NoError(t, err)
+	assert.Equal(t, rec, raw.ToRecord())
+}
+
+func TestCodeRecord_LegacyEncodingUnchanged(t *testing.T) {
+	rec := &CodeRecord{TargetedCode: map[ArchType][]byte{1: {1}}}
+	raw, err := EncodeToRaw(rec)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(raw.Data), "CodeBlobs")
+
+	rec.CodeBlobs = map[ArchType][]byte{2: BlobHash([]byte{2})}
+	raw, err = EncodeToRaw(rec)
+	assert.NoError(t, err)
+	assert.Equal(t, rec, raw.ToRecord())
+}
+
 func Test_RecordByTypeIDPanic(t *testing.T) {
 	assert.Panics(t, func() { getRecordByTypeID(0) })
 }
@@ -62,7 +145,7 @@ var type2idTests = []struct {
 	{"ReadObjectComposite", &ReadObjectComposite{}, readObjectCompositeID},
 
 	// result records
-	// case resultRecordID:
+	{"ResultRecord", &ResultRecord{}, resultRecordID},
 	{"WipeOutRecord", &WipeOutRecord{}, wipeOutRecordID},
 	{"ReadRecordResult", &ReadRecordResult{}, readRecordResultID},
 	{"StatelessCallResult", &StatelessCallResult{}, statelessCallResultID},
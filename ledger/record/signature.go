@@ -0,0 +1,126 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package record
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/insolar/insolar/ledger/hash"
+)
+
+var (
+	// ErrNotSigned returns if record has no signature.
+	ErrNotSigned = errors.New("record is not signed")
+	// ErrUnknownSigner returns if record is signed by a key which is unknown to verifier.
+	ErrUnknownSigner = errors.New("record is signed by unknown key")
+	// ErrInvalidSignature returns if record signature does not match record ID.
+	ErrInvalidSignature = errors.New("invalid record signature")
+)
+
+// Signature is an optional envelope of stored record which attributes the record to its author.
+//
+// Signature is not a part of record hash, so signed and unsigned records have the same reference.
+type Signature struct {
+	// KeyID identifies signer's public key (see KeyID).
+	KeyID []byte
+	// Sig is ed25519 signature of record ID bytes (see ID2Bytes), so it is bound to record pulse and hash algorithm.
+	Sig []byte
+}
+
+// Verify checks that signature is made by one of provided keys over provided record ID. Nil
+// signature is valid receiver, ErrNotSigned is returned for it.
+func (sig *Signature) Verify(id ID, keys *KeyRing) error {
+	if sig == nil {
+		return ErrNotSigned
+	}
+	pub, ok := keys.Get(sig.KeyID)
+	if !ok {
+		return errors.Wrapf(ErrUnknownSigner, "key %x", sig.KeyID)
+	}
+	if !ed25519.Verify(pub, ID2Bytes(id), sig.Sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// KeyID returns identifier of public key. It is SHA3-224 hash of the key.
+func KeyID(pub ed25519.PublicKey) []byte {
+	return hash.SHA3224.Sum(hashableBytes(pub))
+}
+
+// Signer signs records on behalf of some key.
+type Signer interface {
+	// Sign returns signature of record with provided ID.
+	Sign(id ID) *Signature
+}
+
+type ed25519Signer struct {
+	key   ed25519.PrivateKey
+	keyID []byte
+}
+
+// NewEd25519Signer creates Signer which signs records with provided ed25519 private key.
+func NewEd25519Signer(key ed25519.PrivateKey) Signer {
+	return &ed25519Signer{
+		key:   key,
+		keyID: KeyID(key.Public().(ed25519.PublicKey)),
+	}
+}
+
+func (s *ed25519Signer) Sign(id ID) *Signature {
+	return &Signature{KeyID: s.keyID, Sig: ed25519.Sign(s.key, ID2Bytes(id))}
+}
+
+// KeyRing is a set of public keys trusted by signature verifier. It is safe for concurrent use.
+type KeyRing struct {
+	lock sync.RWMutex
+	keys map[string]ed25519.PublicKey
+}
+
+// NewKeyRing creates KeyRing containing provided keys.
+func NewKeyRing(keys ...ed25519.PublicKey) (*KeyRing, error) {
+	kr := &KeyRing{keys: map[string]ed25519.PublicKey{}}
+	for _, pub := range keys {
+		if _, err := kr.Add(pub); err != nil {
+			return nil, err
+		}
+	}
+	return kr, nil
+}
+
+// Add adds public key to the key ring and returns its identifier.
+func (kr *KeyRing) Add(pub ed25519.PublicKey) ([]byte, error) {
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, errors.Errorf("wrong public key size %d", len(pub))
+	}
+	id := KeyID(pub)
+	kr.lock.Lock()
+	defer kr.lock.Unlock()
+	kr.keys[string(id)] = append(ed25519.PublicKey{}, pub...)
+	return id, nil
+}
+
+// Get returns public key by its identifier.
+func (kr *KeyRing) Get(keyID []byte) (ed25519.PublicKey, bool) {
+	kr.lock.RLock()
+	defer kr.lock.RUnlock()
+	pub, ok := kr.keys[string(keyID)]
+	return pub, ok
+}
@@ -0,0 +1,84 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package record
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+	"golang.org/x/crypto/ed25519"
+)
+
+func testKey(seed byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+func TestSignature_Verify(t *testing.T) {
+	key := testKey(1)
+	other := testKey(2)
+	keys, err := NewKeyRing(key.Public().(ed25519.PublicKey))
+	assert.NoError(t, err)
+
+	id := PulseNum(10).ID(&CallRequest{ParamMemory: []byte{1, 2, 3}})
+	sig := NewEd25519Signer(key).Sign(id)
+	assert.Equal(t, KeyID(key.Public().(ed25519.PublicKey)), sig.KeyID)
+	assert.NoError(t, sig.Verify(id, keys))
+
+	otherID := PulseNum(10).ID(&CallRequest{ParamMemory: []byte{3, 2, 1}})
+	assert.Equal(t, ErrInvalidSignature, sig.Verify(otherID, keys))
+	// signature can't be replayed under other pulse
+	assert.Equal(t, ErrInvalidSignature, sig.Verify(PulseNum(11).ID(&CallRequest{ParamMemory: []byte{1, 2, 3}}), keys))
+
+	otherSig := NewEd25519Signer(other).Sign(id)
+	assert.Equal(t, ErrUnknownSigner, errors.Cause(otherSig.Verify(id, keys)))
+
+	var noSig *Signature
+	assert.Equal(t, ErrNotSigned, noSig.Verify(id, keys))
+}
+
+func TestKeyRing_RejectsMalformedKeys(t *testing.T) {
+	_, err := NewKeyRing(ed25519.PublicKey{1, 2, 3})
+	assert.Error(t, err)
+}
+
+func TestRaw_SignatureIsNotHashed(t *testing.T) {
+	raw, err := EncodeToRaw(&CallRequest{ParamMemory: []byte{1, 2, 3}})
+	assert.NoError(t, err)
+	unsigned := MustEncodeRaw(raw)
+	h := raw.Hash()
+
+	raw.Signature = NewEd25519Signer(testKey(1)).Sign(ID{Hash: h})
+	assert.Equal(t, h, raw.Hash())
+	signed := MustEncodeRaw(raw)
+	assert.NotEqual(t, unsigned, signed)
+
+	decoded, err := DecodeToRaw(signed)
+	assert.NoError(t, err)
+	assert.Equal(t, raw, decoded)
+
+	// unsigned records are encoded as before signatures were introduced
+	var legacy []byte
+	err = codec.NewEncoderBytes(&legacy, &codec.CborHandle{}).Encode(&struct {
+		Type TypeID
+		Data []byte
+	}{Type: raw.Type, Data: raw.Data})
+	assert.NoError(t, err)
+	assert.Equal(t, legacy, unsigned)
+}
@@ -0,0 +1,181 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package record
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/ugorji/go/codec"
+
+	"github.com/insolar/insolar/ledger/hash"
+)
+
+// ErrInvalidReference is returned when string can't be parsed as a reference.
+var ErrInvalidReference = errors.New("invalid reference")
+
+// base58Alphabet is the Bitcoin base58 alphabet.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var bigRadix = big.NewInt(58)
+
+func encodeBase58(b []byte) string {
+	x := new(big.Int).SetBytes(b)
+	mod := new(big.Int)
+	var out []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, bigRadix, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	// Every leading zero byte is encoded as the first alphabet symbol.
+	for i := 0; i < len(b) && b[i] == 0; i++ {
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func decodeBase58(s string) ([]byte, error) {
+	x := new(big.Int)
+	for _, c := range []byte(s) {
+		digit := strings.IndexByte(base58Alphabet, c)
+		if digit < 0 {
+			return nil, errors.Errorf("invalid base58 symbol %q", c)
+		}
+		x.Mul(x, bigRadix)
+		x.Add(x, big.NewInt(int64(digit)))
+	}
+	zeros := 0
+	for zeros < len(s) && s[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), x.Bytes()...), nil
+}
+
+func parseID(s string) (ID, error) {
+	b, err := decodeBase58(s)
+	if err != nil {
+		return ID{}, err
+	}
+	// ID without hash, see ID.String.
+	if len(b) == PulseNumSize && binary.BigEndian.Uint32(b)&algorithmFlag == 0 {
+		return ID{Pulse: PulseNum(binary.BigEndian.Uint32(b))}, nil
+	}
+	if len(b) == PulseNumSize+1 && binary.BigEndian.Uint32(b)&algorithmFlag != 0 {
+		alg := hash.Algorithm(b[PulseNumSize])
+		if alg == hash.SHA3224 || !alg.IsValid() {
+			return ID{}, errors.Errorf("wrong ID hash algorithm %d", byte(alg))
+		}
+		return ID{Pulse: PulseNum(binary.BigEndian.Uint32(b) &^ algorithmFlag), Algorithm: alg}, nil
+	}
+	id, n, err := ParseID(b)
+	if err != nil {
+		return ID{}, err
+	}
+	if n != len(b) {
+		return ID{}, errors.Errorf("wrong ID size %d", len(b))
+	}
+	return id, nil
+}
+
+// String returns base58 encoded binary representation of ID (pulse number followed by hash).
+//
+// Nil hash is omitted, so it is distinguished from zero filled one. E.g. domain of records stored without domain has
+// nil hash.
+func (id ID) String() string {
+	b := ID2Bytes(id)
+	if id.Hash == nil {
+		b = b[:len(b)-id.Algorithm.Size()]
+	}
+	return encodeBase58(b)
+}
+
+// String returns canonical textual form of reference. It consists of record and domain IDs separated by dot, each
+// encoded by ID.String.
+//
+// ParseReference parses it back to the equal reference (see Reference.IsEqual).
+func (ref Reference) String() string {
+	return ref.Record.String() + "." + ref.Domain.String()
+}
+
+// ParseReference parses reference from its canonical textual form produced by Reference.String.
+func ParseReference(s string) (Reference, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 {
+		return Reference{}, errors.Wrapf(ErrInvalidReference, "%q: wrong number of parts", s)
+	}
+	recordID, err := parseID(parts[0])
+	if err != nil {
+		return Reference{}, errors.Wrapf(ErrInvalidReference, "%q: record: %v", s, err)
+	}
+	domainID, err := parseID(parts[1])
+	if err != nil {
+		return Reference{}, errors.Wrapf(ErrInvalidReference, "%q: domain: %v", s, err)
+	}
+	return Reference{Domain: domainID, Record: recordID}, nil
+}
+
+// MarshalText implements encoding.TextMarshaler. Reference is marshaled in its canonical textual form.
+func (ref Reference) MarshalText() ([]byte, error) {
+	return []byte(ref.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (ref *Reference) UnmarshalText(text []byte) error {
+	parsed, err := ParseReference(string(text))
+	if err != nil {
+		return err
+	}
+	*ref = parsed
+	return nil
+}
+
+// MarshalJSON encodes reference as JSON string in its canonical textual form.
+func (ref Reference) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + ref.String() + `"`), nil
+}
+
+// UnmarshalJSON decodes reference from JSON string.
+func (ref *Reference) UnmarshalJSON(data []byte) error {
+	if len(data) < 2 || data[0] != '"' || data[len(data)-1] != '"' {
+		return errors.Wrap(ErrInvalidReference, "reference should be a JSON string")
+	}
+	return ref.UnmarshalText(data[1 : len(data)-1])
+}
+
+// MarshalCBOR encodes reference as CBOR text string in its canonical textual form. It is intended for APIs, records
+// keep structural encoding of references, so record hashes don't depend on this method.
+func (ref Reference) MarshalCBOR() ([]byte, error) {
+	var buf bytes.Buffer
+	err := codec.NewEncoder(&buf, &codec.CborHandle{}).Encode(ref.String())
+	return buf.Bytes(), err
+}
+
+// UnmarshalCBOR decodes reference from CBOR text string.
+func (ref *Reference) UnmarshalCBOR(data []byte) error {
+	var s string
+	err := codec.NewDecoderBytes(data, &codec.CborHandle{}).Decode(&s)
+	if err != nil {
+		return errors.Wrap(ErrInvalidReference, err.Error())
+	}
+	return ref.UnmarshalText([]byte(s))
+}
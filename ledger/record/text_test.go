@@ -0,0 +1,161 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package record
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/hash"
+)
+
+func randID(r *rand.Rand) ID {
+	hash := make([]byte, HashSize)
+	r.Read(hash)
+	// Upper pulse number bits are reserved.
+	return ID{Pulse: PulseNum(r.Uint32() >> 2), Hash: hash}
+}
+
+func TestBase58(t *testing.T) {
+	assert.Equal(t, "2NEpo7TZRRrLZSi2U", encodeBase58([]byte("Hello World!")))
+	assert.Equal(t, "11", encodeBase58([]byte{0, 0}))
+	assert.Equal(t, "112", encodeBase58([]byte{0, 0, 1}))
+
+	b, err := decodeBase58("112")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0, 0, 1}, b)
+	_, err = decodeBase58("0OIl")
+	assert.Error(t, err)
+}
+
+func TestReference_StringRoundTrip(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100; i++ {
+		ref := Reference{Domain: randID(r), Record: randID(r)}
+		parsed, err := ParseReference(ref.String())
+		assert.NoError(t, err)
+		assert.Equal(t, ref.Key(), parsed.Key())
+		assert.Equal(t, ref.String(), parsed.String())
+	}
+
+	var zero Reference
+	parsed, err := ParseReference(zero.String())
+	assert.NoError(t, err)
+	assert.Equal(t, zero, parsed)
+}
+
+func TestReference_StringRoundTripNilHash(t *testing.T) {
+	refs := []Reference{
+		{Record: ID{Pulse: 1, Hash: make([]byte, HashSize)}},
+		{Record: ID{Pulse: 1, Hash: make([]byte, HashSize)}, Domain: ID{Pulse: 2, Hash: make([]byte, HashSize)}},
+		{Record: ID{Pulse: 1, Algorithm: hash.SHA3256}, Domain: ID{Pulse: 2, Algorithm: hash.BLAKE2b256}},
+	}
+	for _, ref := range refs {
+		parsed, err := ParseReference(ref.String())
+		assert.NoError(t, err)
+		assert.True(t, ref.IsEqual(parsed), ref.String())
+		assert.Equal(t, ref.String(), parsed.String())
+	}
+	assert.NotEqual(t, refs[0].String(), refs[1].String())
+}
+
+func TestParseReference_RejectsInvalidStrings(t *testing.T) {
+	ref := Reference{Domain: ID{Pulse: 1, Hash: make([]byte, HashSize)}, Record: ID{Pulse: 2, Hash: []byte{1}}}
+	valid := ref.String()
+	cases := []string{
+		"",
+		".",
+		valid + "." + ref.Domain.String(),
+		ref.Record.String(),
+		"1" + valid,
+		"0" + valid[1:],
+		valid[:len(valid)-1],
+	}
+	for _, s := range cases {
+		_, err := ParseReference(s)
+		assert.Equal(t, ErrInvalidReference, errors.Cause(err), s)
+	}
+}
+
+func TestReference_JSON(t *testing.T) {
+	r := rand.New(rand.NewSource(2))
+	type message struct {
+		Ref Reference
+	}
+	in := message{Ref: Reference{Domain: randID(r), Record: randID(r)}}
+	data, err := json.Marshal(in)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"Ref":"`+in.Ref.String()+`"}`, string(data))
+
+	var out message
+	assert.NoError(t, json.Unmarshal(data, &out))
+	assert.Equal(t, in.Ref.Key(), out.Ref.Key())
+
+	assert.Error(t, json.Unmarshal([]byte(`{"Ref":1}`), &out))
+}
+
+func TestReference_CBOR(t *testing.T) {
+	r := rand.New(rand.NewSource(3))
+	ref := Reference{Domain: randID(r), Record: randID(r)}
+	data, err := ref.MarshalCBOR()
+	assert.NoError(t, err)
+
+	var decoded Reference
+	assert.NoError(t, decoded.UnmarshalCBOR(data))
+	assert.Equal(t, ref.Key(), decoded.Key())
+	assert.Error(t, decoded.UnmarshalCBOR([]byte{0xff}))
+}
+
+func TestReference_TextDoesNotChangeRecordEncoding(t *testing.T) {
+	r := rand.New(rand.NewSource(4))
+	rec := &ClassAmendRecord{NewCode: Reference{Domain: randID(r), Record: randID(r)}}
+	raw, err := EncodeToRaw(rec)
+	assert.NoError(t, err)
+	decoded := raw.ToRecord().(*ClassAmendRecord)
+	assert.Equal(t, rec.NewCode.Key(), decoded.NewCode.Key())
+	assert.NotContains(t, string(raw.Data), rec.NewCode.String())
+}
+
+// TestParseReference_Fuzz feeds random and mutated strings to ParseReference. Every successfully parsed string
+// should be canonical. Use Fuzz with go-fuzz for deeper coverage.
+func TestParseReference_Fuzz(t *testing.T) {
+	r := rand.New(rand.NewSource(5))
+	valid := Reference{Domain: randID(r), Record: randID(r)}.String()
+	alphabet := base58Alphabet + ".0Il "
+	for i := 0; i < 2000; i++ {
+		var s []byte
+		if i%2 == 0 {
+			s = []byte(valid)
+			s[r.Intn(len(s))] = alphabet[r.Intn(len(alphabet))]
+		} else {
+			s = make([]byte, r.Intn(100))
+			for j := range s {
+				s[j] = alphabet[r.Intn(len(alphabet))]
+			}
+		}
+		ref, err := ParseReference(string(s))
+		if err != nil {
+			assert.Equal(t, ErrInvalidReference, errors.Cause(err))
+			continue
+		}
+		assert.Equal(t, string(s), ref.String())
+	}
+}
@@ -0,0 +1,450 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package archive
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash"
+	"io"
+
+	"github.com/pkg/errors"
+	"github.com/ugorji/go/codec"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// Version is the current archive format version. Version 2 adds blob entries, version 3 allows record IDs with
+// non-default hash algorithms, version 4 adds lifeline history entries. Archives of older versions can still be
+// imported.
+const Version uint32 = 4
+
+// maxPayloadSize limits entry size, so corrupted length can't cause huge allocations.
+const maxPayloadSize = 64 << 20
+
+var magic = []byte("INSLEDGR")
+
+// entry kinds
+const (
+	kindRecord         byte = 1
+	kindClassLifeline  byte = 2
+	kindObjectLifeline byte = 3
+	kindMetadata       byte = 4
+	kindEnd            byte = 5
+	kindRequestResult  byte = 6
+	kindBlob           byte = 7
+	kindHistory        byte = 8
+)
+
+// Source is a storage which can be exported.
+type Source interface {
+	storage.LedgerStorer
+	storage.RawStorer
+}
+
+// Metadata describes archive content.
+type Metadata struct {
+	// GenesisRef is a key of genesis record reference of exported ledger.
+	GenesisRef []byte
+	// LastPulse is the newest pulse of exported records.
+	LastPulse record.PulseNum
+	// Records is the number of exported records.
+	Records uint64
+	// Lifelines is the number of exported lifeline indexes of both types.
+	Lifelines uint64
+	// Results is the number of exported request results.
+	Results uint64
+	// Blobs is the number of exported blobs.
+	Blobs uint64
+	// History is the number of exported lifeline history records of all lifelines.
+	History uint64
+}
+
+type writer struct {
+	w   io.Writer
+	sum hash.Hash
+}
+
+func (aw *writer) write(b []byte) error {
+	_, err := aw.w.Write(b)
+	if err != nil {
+		return err
+	}
+	_, err = aw.sum.Write(b)
+	return err
+}
+
+func (aw *writer) writeEntry(kind byte, payload ...[]byte) error {
+	size := 0
+	for _, p := range payload {
+		size += len(p)
+	}
+	head := make([]byte, 5)
+	head[0] = kind
+	binary.BigEndian.PutUint32(head[1:], uint32(size))
+	if err := aw.write(head); err != nil {
+		return err
+	}
+	for _, p := range payload {
+		if err := aw.write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Export writes all records and lifeline indexes of src into w.
+//
+// Records are exported exactly as they are stored. Type of lifeline index is determined by the type of record it
+// belongs to, so every lifeline should have its activation record in the same storage.
+func Export(w io.Writer, src Source) (*Metadata, error) {
+	aw := &writer{w: w, sum: sha3.New256()}
+	header := make([]byte, len(magic)+4)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[len(magic):], Version)
+	if err := aw.write(header); err != nil {
+		return nil, err
+	}
+
+	meta := &Metadata{GenesisRef: genesisKey()}
+	err := src.IterateRawRecords(func(ref *record.Reference, raw *record.Raw) error {
+		buf, err := record.EncodeRaw(raw)
+		if err != nil {
+			return err
+		}
+		if ref.Record.Pulse > meta.LastPulse {
+			meta.LastPulse = ref.Record.Pulse
+		}
+		meta.Records++
+		return aw.writeEntry(kindRecord, ref.Key(), buf)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to export records")
+	}
+
+	err = src.IterateLifelineRefs(func(ref *record.Reference) error {
+		kind, buf, err := encodeLifeline(src, ref)
+		if err != nil {
+			return err
+		}
+		meta.Lifelines++
+		if err = aw.writeEntry(kind, ref.Key(), buf); err != nil {
+			return err
+		}
+		return src.IterateHistory(ref, func(historyRef *record.Reference) error {
+			buf, err := index.EncodeHistoryRef(historyRef)
+			if err != nil {
+				return err
+			}
+			meta.History++
+			return aw.writeEntry(kindHistory, ref.Key(), buf)
+		})
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to export lifelines")
+	}
+
+	err = src.IterateRequestResults(func(requestRef, resultRef *record.Reference) error {
+		meta.Results++
+		return aw.writeEntry(kindRequestResult, requestRef.Key(), resultRef.Key())
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to export request results")
+	}
+
+	err = src.IterateBlobs(func(hash, data []byte, refs uint64) error {
+		meta.Blobs++
+		return aw.writeEntry(kindBlob, encodeBlobHead(hash, refs), data)
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to export blobs")
+	}
+
+	encodedMeta, err := encodeMetadata(meta)
+	if err != nil {
+		return nil, err
+	}
+	if err = aw.writeEntry(kindMetadata, encodedMeta); err != nil {
+		return nil, err
+	}
+
+	// Checksum covers everything before the end entry.
+	checksum := aw.sum.Sum(nil)
+	if err = aw.writeEntry(kindEnd, checksum); err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+func encodeLifeline(src Source, ref *record.Reference) (byte, []byte, error) {
+	rec, err := src.GetRecord(ref)
+	if err != nil {
+		return 0, nil, errors.Wrap(err, "failed to fetch lifeline record")
+	}
+	switch rec.(type) {
+	case *record.ClassActivateRecord:
+		idx, err := src.GetClassIndex(ref)
+		if err != nil {
+			return 0, nil, err
+		}
+		buf, err := index.EncodeClassLifeline(idx)
+		return kindClassLifeline, buf, err
+	case *record.ObjectActivateRecord:
+		idx, err := src.GetObjectIndex(ref)
+		if err != nil {
+			return 0, nil, err
+		}
+		buf, err := index.EncodeObjectLifeline(idx)
+		return kindObjectLifeline, buf, err
+	}
+	return 0, nil, errors.Errorf("lifeline belongs to unexpected record type %T", rec)
+}
+
+// encodeBlobHead encodes blob entry prefix: big endian reference count, hash length and hash. Blob data follows it.
+func encodeBlobHead(hash []byte, refs uint64) []byte {
+	head := make([]byte, 9+len(hash))
+	binary.BigEndian.PutUint64(head, refs)
+	head[8] = byte(len(hash))
+	copy(head[9:], hash)
+	return head
+}
+
+func decodeBlob(payload []byte) (hash, data []byte, refs uint64, err error) {
+	if len(payload) < 9 || len(payload) < 9+int(payload[8]) {
+		return nil, nil, 0, errors.Wrap(ErrInvalidArchive, "blob entry is too short")
+	}
+	refs = binary.BigEndian.Uint64(payload)
+	hashEnd := 9 + int(payload[8])
+	return payload[9:hashEnd], payload[hashEnd:], refs, nil
+}
+
+func encodeMetadata(meta *Metadata) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := codec.NewEncoder(&buf, &codec.CborHandle{})
+	if err := enc.Encode(meta); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeMetadata(buf []byte) (*Metadata, error) {
+	dec := codec.NewDecoder(bytes.NewReader(buf), &codec.CborHandle{})
+	var meta Metadata
+	if err := dec.Decode(&meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+type reader struct {
+	r   io.Reader
+	sum hash.Hash
+}
+
+// read reads exactly len(b) bytes. Truncated input is reported as ErrInvalidArchive.
+func (ar *reader) read(b []byte) error {
+	_, err := io.ReadFull(ar.r, b)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.Wrap(ErrInvalidArchive, "unexpected end of archive")
+	}
+	return err
+}
+
+// readEntry reads next entry. Entry is added to checksum unless it is the end entry.
+func (ar *reader) readEntry() (byte, []byte, error) {
+	head := make([]byte, 5)
+	if err := ar.read(head); err != nil {
+		return 0, nil, err
+	}
+	size := binary.BigEndian.Uint32(head[1:])
+	if size > maxPayloadSize {
+		return 0, nil, errors.Wrapf(ErrInvalidArchive, "entry size %d is too big", size)
+	}
+	payload := make([]byte, size)
+	if err := ar.read(payload); err != nil {
+		return 0, nil, err
+	}
+	if head[0] != kindEnd {
+		_, _ = ar.sum.Write(head)
+		_, _ = ar.sum.Write(payload)
+	}
+	return head[0], payload, nil
+}
+
+func splitRef(payload []byte) (*record.Reference, []byte, error) {
+	_, n, err := record.ParseRefKey(payload)
+	if err != nil {
+		return nil, nil, errors.Wrapf(ErrInvalidArchive, "malformed reference: %v", err)
+	}
+	ref, _, err := record.ParseRefKey(append([]byte{}, payload[:n]...))
+	if err != nil {
+		return nil, nil, err
+	}
+	return ref, payload[n:], nil
+}
+
+type importer struct {
+	ar        *reader
+	batch     storage.RawBatch
+	records   uint64
+	lifelines uint64
+	results   uint64
+	blobs     uint64
+	history   uint64
+	meta      *Metadata
+}
+
+func (im *importer) importEntry(kind byte, payload []byte) error {
+	if im.meta != nil {
+		return errors.Wrap(ErrInvalidArchive, "unexpected entry after metadata")
+	}
+	if kind == kindMetadata {
+		meta, err := decodeMetadata(payload)
+		if err != nil {
+			return errors.Wrap(ErrInvalidArchive, err.Error())
+		}
+		im.meta = meta
+		return nil
+	}
+	if kind == kindBlob {
+		hash, data, refs, err := decodeBlob(payload)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(record.BlobHash(data), hash) {
+			return errors.Wrapf(ErrRecordHashMismatch, "blob %x", hash)
+		}
+		im.blobs++
+		return im.batch.SetRawBlob(hash, data, refs)
+	}
+
+	ref, data, err := splitRef(payload)
+	if err != nil {
+		return err
+	}
+	switch kind {
+	case kindRecord:
+		raw, err := record.DecodeToRaw(data)
+		if err != nil {
+			return errors.Wrap(ErrInvalidArchive, err.Error())
+		}
+		if !bytes.Equal(raw.Sum(ref.Record.Algorithm), ref.Record.Hash) {
+			return errors.Wrapf(ErrRecordHashMismatch, "record %x", ref.Key())
+		}
+		im.records++
+		return im.batch.SetRawRecord(ref, raw)
+	case kindClassLifeline:
+		idx, err := index.DecodeClassLifeline(data)
+		if err != nil {
+			return errors.Wrap(ErrInvalidArchive, err.Error())
+		}
+		im.lifelines++
+		return im.batch.SetClassIndex(ref, idx)
+	case kindObjectLifeline:
+		idx, err := index.DecodeObjectLifeline(data)
+		if err != nil {
+			return errors.Wrap(ErrInvalidArchive, err.Error())
+		}
+		im.lifelines++
+		return im.batch.SetObjectIndex(ref, idx)
+	case kindHistory:
+		historyRef, err := index.DecodeHistoryRef(data)
+		if err != nil {
+			return errors.Wrap(ErrInvalidArchive, err.Error())
+		}
+		im.history++
+		return im.batch.AppendHistory(ref, historyRef)
+	case kindRequestResult:
+		resultRef, _, err := splitRef(data)
+		if err != nil {
+			return err
+		}
+		im.results++
+		return im.batch.SetRequestResult(ref, resultRef)
+	}
+	return errors.Wrapf(ErrInvalidArchive, "unknown entry kind %d", kind)
+}
+
+func (im *importer) finish(checksum []byte) error {
+	if !bytes.Equal(checksum, im.ar.sum.Sum(nil)) {
+		return ErrChecksumMismatch
+	}
+	if im.meta == nil {
+		return errors.Wrap(ErrInvalidArchive, "metadata is missing")
+	}
+	if im.meta.Records != im.records || im.meta.Lifelines != im.lifelines || im.meta.Results != im.results ||
+		im.meta.Blobs != im.blobs || im.meta.History != im.history {
+		return errors.Wrap(ErrInvalidArchive, "entry count does not match metadata")
+	}
+	if !bytes.Equal(im.meta.GenesisRef, genesisKey()) {
+		return storage.ErrGenesisMismatch
+	}
+	return nil
+}
+
+// Import reads archive from r and writes its content into dst.
+//
+// Every record hash is checked against its reference. All data is written in a single batch, which is applied only
+// if the whole archive is valid, so dst is not modified if Import fails. Import fails with storage.ErrRequestHasResult
+// if dst already has a result of an archived request.
+func Import(r io.Reader, dst storage.RawStorer) (*Metadata, error) {
+	ar := &reader{r: r, sum: sha3.New256()}
+	header := make([]byte, len(magic)+4)
+	if err := ar.read(header); err != nil {
+		return nil, err
+	}
+	_, _ = ar.sum.Write(header)
+	if !bytes.Equal(header[:len(magic)], magic) {
+		return nil, errors.Wrap(ErrInvalidArchive, "bad magic")
+	}
+	if v := binary.BigEndian.Uint32(header[len(magic):]); v == 0 || v > Version {
+		return nil, errors.Wrapf(ErrUnsupportedVersion, "version %d", v)
+	}
+
+	var meta *Metadata
+	err := dst.UpdateRaw(func(batch storage.RawBatch) error {
+		im := &importer{ar: ar, batch: batch}
+		for {
+			kind, payload, err := ar.readEntry()
+			if err != nil {
+				return err
+			}
+			if kind == kindEnd {
+				if err = im.finish(payload); err != nil {
+					return err
+				}
+				meta = im.meta
+				return nil
+			}
+			if err = im.importEntry(kind, payload); err != nil {
+				return err
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return meta, nil
+}
+
+func genesisKey() []byte {
+	ref := storage.GenesisReference()
+	return ref.Key()
+}
@@ -0,0 +1,229 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package archive
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/pulse"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+	"github.com/insolar/insolar/ledger/storage/leveldb"
+	"github.com/insolar/insolar/ledger/storage/memory"
+)
+
+type fixture struct {
+	classRef   *record.Reference
+	objRef     *record.Reference
+	amendRef   *record.Reference
+	requestRef *record.Reference
+	blobHash   []byte
+}
+
+func mustNoError(t *testing.T, err error) {
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+}
+
+func tmpLedger(t *testing.T) *leveldb.LevelLedger {
+	dir, err := ioutil.TempDir("", "archive")
+	mustNoError(t, err)
+	cfg := leveldb.DefaultConfig()
+	cfg.DataDirectory = dir
+	ledger, err := leveldb.NewLevelLedger(cfg)
+	mustNoError(t, err)
+	return ledger
+}
+
+func populate(t *testing.T, s storage.LedgerStorer) *fixture {
+	var f fixture
+	err := s.Update(func(batch storage.Batch) error {
+		var err error
+		f.classRef, err = batch.SetRecord(&record.ClassActivateRecord{DefaultMemory: record.Memory{1, 2, 3}})
+		if err != nil {
+			return err
+		}
+		f.objRef, err = batch.SetRecord(&record.ObjectActivateRecord{
+			ClassActivateRecord: *f.classRef,
+			Memory:              record.Memory{4, 5, 6},
+		})
+		if err != nil {
+			return err
+		}
+		f.amendRef, err = batch.SetRecord(&record.ObjectAmendRecord{NewMemory: record.Memory{7}})
+		if err != nil {
+			return err
+		}
+		err = batch.SetClassIndex(f.classRef, &index.ClassLifeline{LatestStateRef: *f.classRef})
+		if err != nil {
+			return err
+		}
+		err = batch.SetObjectIndex(f.objRef, &index.ObjectLifeline{
+			ClassRef:       *f.classRef,
+			LatestStateRef: *f.amendRef,
+		})
+		if err != nil {
+			return err
+		}
+		err = batch.AppendHistory(f.objRef, f.amendRef)
+		if err != nil {
+			return err
+		}
+		f.requestRef, err = batch.SetRecord(&record.CallRequest{CallMethodSignature: 1})
+		if err != nil {
+			return err
+		}
+		err = batch.SetRequestResult(f.requestRef, f.amendRef)
+		if err != nil {
+			return err
+		}
+		f.blobHash, err = batch.SetBlob([]byte("code"))
+		return err
+	})
+	mustNoError(t, err)
+	return &f
+}
+
+func exportFixture(t *testing.T) ([]byte, *fixture) {
+	src := tmpLedger(t)
+	defer src.Drop()
+	src.SetPulseProvider(pulse.NewManual(42))
+	f := populate(t, src)
+
+	var buf bytes.Buffer
+	meta, err := Export(&buf, src)
+	mustNoError(t, err)
+	assert.Equal(t, record.PulseNum(42), meta.LastPulse)
+	// Genesis is exported too.
+	assert.Equal(t, uint64(5), meta.Records)
+	assert.Equal(t, uint64(3), meta.Lifelines)
+	assert.Equal(t, uint64(1), meta.Results)
+	assert.Equal(t, uint64(1), meta.Blobs)
+	assert.Equal(t, uint64(1), meta.History)
+	return buf.Bytes(), f
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	archived, f := exportFixture(t)
+
+	dst := memory.NewMemLedger()
+	meta, err := Import(bytes.NewReader(archived), dst)
+	mustNoError(t, err)
+	assert.Equal(t, uint64(5), meta.Records)
+
+	classRec, err := dst.GetRecord(f.classRef)
+	mustNoError(t, err)
+	assert.Equal(t, record.Memory{1, 2, 3}, classRec.(*record.ClassActivateRecord).DefaultMemory)
+	amendRec, err := dst.GetRecord(f.amendRef)
+	mustNoError(t, err)
+	assert.Equal(t, record.Memory{7}, amendRec.(*record.ObjectAmendRecord).NewMemory)
+
+	classIndex, err := dst.GetClassIndex(f.classRef)
+	mustNoError(t, err)
+	assert.Equal(t, f.classRef.Key(), classIndex.LatestStateRef.Key())
+	objIndex, err := dst.GetObjectIndex(f.objRef)
+	mustNoError(t, err)
+	assert.Equal(t, f.amendRef.Key(), objIndex.LatestStateRef.Key())
+	var history []record.Reference
+	err = dst.IterateHistory(f.objRef, func(ref *record.Reference) error {
+		history = append(history, *ref)
+		return nil
+	})
+	mustNoError(t, err)
+	assert.Equal(t, []record.Reference{*f.amendRef}, history)
+	resultRef, err := dst.GetRequestResult(f.requestRef)
+	mustNoError(t, err)
+	assert.Equal(t, f.amendRef.Key(), resultRef.Key())
+	blob, err := dst.GetBlob(f.blobHash)
+	mustNoError(t, err)
+	assert.Equal(t, []byte("code"), blob)
+	refs, err := dst.GetBlobRefs(f.blobHash)
+	mustNoError(t, err)
+	assert.Equal(t, uint64(1), refs)
+
+	// Exporting imported ledger produces the same archive.
+	var buf bytes.Buffer
+	_, err = Export(&buf, dst)
+	mustNoError(t, err)
+	assert.Equal(t, archived, buf.Bytes())
+}
+
+func TestImport_PersistsLastPulse(t *testing.T) {
+	archived, _ := exportFixture(t)
+
+	dst := tmpLedger(t)
+	defer dst.Drop()
+	_, err := Import(bytes.NewReader(archived), dst)
+	mustNoError(t, err)
+	assert.Equal(t, record.PulseNum(42), dst.LastPulse())
+}
+
+func TestImport_RejectsTamperedArchives(t *testing.T) {
+	archived, f := exportFixture(t)
+	headerSize := len(magic) + 4
+
+	cases := []struct {
+		name   string
+		tamper func([]byte) []byte
+		err    error
+	}{
+		{"RecordData", func(b []byte) []byte {
+			// skip entry header, reference key and a few bytes of CBOR framing
+			b[headerSize+5+record.RefIDSize+20] ^= 0xff
+			return b
+		}, ErrRecordHashMismatch},
+		{"Checksum", func(b []byte) []byte {
+			b[len(b)-1] ^= 0xff
+			return b
+		}, ErrChecksumMismatch},
+		{"Metadata", func(b []byte) []byte {
+			// the last byte of metadata precedes end entry
+			b[len(b)-32-5-1] ^= 0x01
+			return b
+		}, ErrChecksumMismatch},
+		{"Truncated", func(b []byte) []byte {
+			return b[:len(b)-10]
+		}, ErrInvalidArchive},
+		{"Magic", func(b []byte) []byte {
+			b[0] = 'X'
+			return b
+		}, ErrInvalidArchive},
+		{"Version", func(b []byte) []byte {
+			b[headerSize-1] = byte(Version + 1)
+			return b
+		}, ErrUnsupportedVersion},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tampered := c.tamper(append([]byte{}, archived...))
+			dst := memory.NewMemLedger()
+			_, err := Import(bytes.NewReader(tampered), dst)
+			assert.Equal(t, c.err, errors.Cause(err))
+
+			// Nothing is written on failure.
+			_, err = dst.GetRecord(f.objRef)
+			assert.Equal(t, storage.ErrNotFound, err)
+		})
+	}
+}
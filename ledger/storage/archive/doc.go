@@ -0,0 +1,25 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package archive implements portable ledger archive format used for backups and moving ledgers between
+// environments.
+//
+// Archive starts with a header (magic string and format version) followed by a sequence of entries. Every entry is
+// framed as kind byte, big endian uint32 payload length and payload. Records are stored as CBOR produced by
+// record.EncodeRaw, so archive does not depend on record structures. Lifeline indexes followed by their histories,
+// request results and code blobs are stored after records. Archive ends with metadata entry and SHA3-256 checksum
+// of all preceding bytes.
+package archive
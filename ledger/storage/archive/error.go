@@ -0,0 +1,33 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package archive
+
+import "errors"
+
+var (
+	// ErrInvalidArchive returns if archive is malformed or truncated.
+	ErrInvalidArchive = errors.New("invalid ledger archive")
+
+	// ErrUnsupportedVersion returns if archive format version is not supported.
+	ErrUnsupportedVersion = errors.New("unsupported ledger archive version")
+
+	// ErrChecksumMismatch returns if archive checksum does not match its content.
+	ErrChecksumMismatch = errors.New("ledger archive checksum mismatch")
+
+	// ErrRecordHashMismatch returns if record hash does not match hash from its reference.
+	ErrRecordHashMismatch = errors.New("record hash does not match its reference")
+)
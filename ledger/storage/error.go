@@ -0,0 +1,41 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import "errors"
+
+var (
+	// ErrNotFound returns if record/index not found in storage.
+	//
+	// Every LedgerStorer implementation should return exactly this error value for missing keys.
+	ErrNotFound = errors.New("record not found")
+
+	// ErrGenesisMismatch returns if existing storage does not contain expected genesis record.
+	ErrGenesisMismatch = errors.New("storage genesis does not match expected one")
+
+	// ErrCorrupted returns if stored data can't be decoded or storage indexes refer to missing data.
+	ErrCorrupted = errors.New("storage data is corrupted")
+
+	// ErrRequestHasResult returns if batch sets a result of request which already has one.
+	ErrRequestHasResult = errors.New("request already has a result")
+
+	// ErrConflict returns if batch precondition is not met because stored data was changed after it was read.
+	ErrConflict = errors.New("stored data was changed concurrently")
+
+	// ErrStopIteration can be returned by iteration callbacks to stop iteration without error.
+	ErrStopIteration = errors.New("stop iteration")
+)
@@ -0,0 +1,23 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package fsck implements ledger storage consistency checker.
+//
+// Check scans every record, lifeline index and request result of the storage. It verifies record hashes against
+// their references and checks that every reference stored in indexes resolves to a record of expected type. Problems
+// which can be fixed without losing data (dangling index references, lifelines without head records, activation
+// records without lifelines) are repaired if requested.
+package fsck
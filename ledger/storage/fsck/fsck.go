@@ -0,0 +1,600 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package fsck
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// Storage is a storage which can be checked.
+type Storage interface {
+	storage.LedgerStorer
+	storage.RawStorer
+}
+
+// ProblemKind is a kind of found inconsistency.
+type ProblemKind int
+
+// Problem kinds.
+const (
+	// HashMismatch is a record which hash does not match its reference or a blob which hash does not match its data.
+	HashMismatch ProblemKind = iota + 1
+	// CorruptedRecord is a record which can't be decoded.
+	CorruptedRecord
+	// CorruptedLifeline is a lifeline index which can't be decoded.
+	CorruptedLifeline
+	// OrphanLifeline is a lifeline index which head record is missing or is not an activation record.
+	OrphanLifeline
+	// DanglingRef is a lifeline reference to a missing record.
+	DanglingRef
+	// WrongRecordType is a lifeline reference to a record of unexpected type.
+	WrongRecordType
+	// MissingLifeline is an activation record without lifeline index.
+	MissingLifeline
+	// UnindexedRecord is an amend, append or deactivation record which is not in its head lifeline history.
+	UnindexedRecord
+	// DanglingResult is a request result which request or result record is missing.
+	DanglingResult
+	// MissingBlob is a code record reference to a missing blob.
+	MissingBlob
+	// BlobRefCount is a blob which reference count does not match the number of code records referring to it.
+	BlobRefCount
+)
+
+var kindNames = map[ProblemKind]string{
+	HashMismatch:      "hash mismatch",
+	CorruptedRecord:   "corrupted record",
+	CorruptedLifeline: "corrupted lifeline",
+	OrphanLifeline:    "orphan lifeline",
+	DanglingRef:       "dangling reference",
+	WrongRecordType:   "wrong record type",
+	MissingLifeline:   "missing lifeline",
+	UnindexedRecord:   "unindexed record",
+	DanglingResult:    "dangling request result",
+	MissingBlob:       "missing blob",
+	BlobRefCount:      "wrong blob reference count",
+}
+
+func (k ProblemKind) String() string {
+	if name, ok := kindNames[k]; ok {
+		return name
+	}
+	return fmt.Sprintf("problem %d", int(k))
+}
+
+// Problem describes a single found inconsistency.
+type Problem struct {
+	Kind ProblemKind
+	// Ref is a reference of the record or the lifeline with problem.
+	Ref record.Reference
+	// Target is a reference stored in the lifeline, if the problem is caused by it.
+	Target *record.Reference
+	// Blob is a content hash of the blob with problem or of the blob referred by the record.
+	Blob []byte
+	// Repaired is true if problem was fixed.
+	Repaired bool
+}
+
+func (p Problem) String() string {
+	if p.Ref.Record.Hash == nil && p.Blob != nil {
+		s := fmt.Sprintf("%s: blob %x", p.Kind, p.Blob)
+		if p.Repaired {
+			s += " (repaired)"
+		}
+		return s
+	}
+	s := fmt.Sprintf("%s: %x", p.Kind, p.Ref.Key())
+	if p.Target != nil {
+		s += fmt.Sprintf(" -> %x", p.Target.Key())
+	}
+	if p.Blob != nil {
+		s += fmt.Sprintf(" -> blob %x", p.Blob)
+	}
+	if p.Repaired {
+		s += " (repaired)"
+	}
+	return s
+}
+
+// Report contains check results.
+type Report struct {
+	Records   int
+	Lifelines int
+	Results   int
+	Blobs     int
+	Problems  []Problem
+}
+
+// OK returns true if no unrepaired problems were found.
+func (r *Report) OK() bool {
+	for _, p := range r.Problems {
+		if !p.Repaired {
+			return false
+		}
+	}
+	return true
+}
+
+// Options configures Check.
+type Options struct {
+	// Repair enables fixing found problems where it is possible.
+	Repair bool
+}
+
+var (
+	classActivateType  = record.TypeIDOf(&record.ClassActivateRecord{})
+	objectActivateType = record.TypeIDOf(&record.ObjectActivateRecord{})
+	classAmendType     = record.TypeIDOf(&record.ClassAmendRecord{})
+	objectAmendType    = record.TypeIDOf(&record.ObjectAmendRecord{})
+	objectAppendType   = record.TypeIDOf(&record.ObjectAppendRecord{})
+	deactivationType   = record.TypeIDOf(&record.DeactivationRecord{})
+)
+
+type checker struct {
+	s      Storage
+	report Report
+	// keys contains reference keys of valid records in order of storage iteration.
+	keys []string
+	// types contains types of valid records by reference key.
+	types map[string]record.TypeID
+	// amends contains head references of amend, append and deactivation records by their reference key.
+	amends map[string]record.Reference
+	// objectClasses contains class references of object activation records.
+	objectClasses map[string]record.Reference
+	// indexed contains keys of records listed in lifeline histories.
+	indexed map[string]bool
+	// lifelines contains keys of stored lifelines of activation records, including corrupted ones.
+	lifelines map[string]bool
+	// histories contains keys of lifelines with stored history.
+	histories map[string]bool
+	// codeBlobs contains blob hashes referred by code records by their reference key.
+	codeBlobs map[string][][]byte
+	// blobRefs contains number of code records referring to blob by its hash.
+	blobRefs map[string]uint64
+	fixes    []fix
+}
+
+// fix is a repair of report problems.
+type fix struct {
+	// problems contains indexes of report problems repaired by apply.
+	problems []int
+	apply    func(storage.RawBatch) error
+}
+
+// Check scans provided storage for inconsistencies. If repair is requested, all fixes are applied in a single batch
+// after the scan.
+func Check(s Storage, opts Options) (*Report, error) {
+	c := &checker{
+		s:             s,
+		types:         map[string]record.TypeID{},
+		amends:        map[string]record.Reference{},
+		objectClasses: map[string]record.Reference{},
+		indexed:       map[string]bool{},
+		lifelines:     map[string]bool{},
+		histories:     map[string]bool{},
+		codeBlobs:     map[string][][]byte{},
+		blobRefs:      map[string]uint64{},
+	}
+	if err := s.IterateRawRecords(c.checkRecord); err != nil {
+		return nil, errors.Wrap(err, "failed to scan records")
+	}
+	var lifelineRefs []*record.Reference
+	err := s.IterateLifelineRefs(func(ref *record.Reference) error {
+		lifelineRefs = append(lifelineRefs, ref)
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to scan lifelines")
+	}
+	for _, ref := range lifelineRefs {
+		if err = c.checkLifeline(ref); err != nil {
+			return nil, err
+		}
+	}
+	if err = c.checkUnindexed(); err != nil {
+		return nil, err
+	}
+	if err = s.IterateRequestResults(c.checkResult); err != nil {
+		return nil, errors.Wrap(err, "failed to scan request results")
+	}
+	if err = c.checkBlobs(); err != nil {
+		return nil, errors.Wrap(err, "failed to scan blobs")
+	}
+
+	if opts.Repair && len(c.fixes) > 0 {
+		err = s.UpdateRaw(func(batch storage.RawBatch) error {
+			for _, fix := range c.fixes {
+				if err := fix.apply(batch); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to repair storage")
+		}
+		for _, fix := range c.fixes {
+			for _, i := range fix.problems {
+				c.report.Problems[i].Repaired = true
+			}
+		}
+	}
+	return &c.report, nil
+}
+
+func (c *checker) problem(kind ProblemKind, ref record.Reference, target *record.Reference) {
+	c.report.Problems = append(c.report.Problems, Problem{Kind: kind, Ref: ref, Target: target})
+}
+
+// addFix adds repair of problems found since the report had provided number of problems.
+func (c *checker) addFix(from int, apply func(storage.RawBatch) error) {
+	f := fix{apply: apply}
+	for i := from; i < len(c.report.Problems); i++ {
+		f.problems = append(f.problems, i)
+	}
+	c.fixes = append(c.fixes, f)
+}
+
+// checkRecord checks that record matches its reference and can be decoded, and collects data of valid records for
+// lifeline, result and blob checks.
+func (c *checker) checkRecord(ref *record.Reference, raw *record.Raw) error {
+	c.report.Records++
+	if !bytes.Equal(raw.Sum(ref.Record.Algorithm), ref.Record.Hash) {
+		c.problem(HashMismatch, *ref, nil)
+		return nil
+	}
+	rec, err := raw.Decode()
+	if err != nil {
+		c.problem(CorruptedRecord, *ref, nil)
+		return nil
+	}
+	key := string(ref.Key())
+	c.keys = append(c.keys, key)
+	c.types[key] = raw.Type
+	switch r := rec.(type) {
+	case *record.ObjectActivateRecord:
+		c.objectClasses[key] = r.ClassActivateRecord
+	case *record.ClassAmendRecord:
+		c.amends[key] = r.HeadRecord
+	case *record.ObjectAmendRecord:
+		c.amends[key] = r.HeadRecord
+	case *record.ObjectAppendRecord:
+		c.amends[key] = r.HeadRecord
+	case *record.DeactivationRecord:
+		c.amends[key] = r.HeadRecord
+	case *record.CodeRecord:
+		c.addCodeBlobs(key, r.CodeBlobs)
+	}
+	return nil
+}
+
+func (c *checker) addCodeBlobs(key string, blobs map[record.ArchType][]byte) {
+	archTypes := make([]int, 0, len(blobs))
+	for archType := range blobs {
+		archTypes = append(archTypes, int(archType))
+	}
+	sort.Ints(archTypes)
+	for _, archType := range archTypes {
+		hash := blobs[record.ArchType(archType)]
+		c.codeBlobs[key] = append(c.codeBlobs[key], hash)
+		c.blobRefs[string(hash)]++
+	}
+}
+
+// checkRef checks that ref points to a record of one of provided types.
+func (c *checker) checkRef(lifelineRef, ref record.Reference, types ...record.TypeID) bool {
+	typeID, ok := c.types[string(ref.Key())]
+	if !ok {
+		c.problem(DanglingRef, lifelineRef, &ref)
+		return false
+	}
+	for _, t := range types {
+		if t == typeID {
+			return true
+		}
+	}
+	c.problem(WrongRecordType, lifelineRef, &ref)
+	return false
+}
+
+// checkRefs checks references and returns the valid ones.
+func (c *checker) checkRefs(lifelineRef record.Reference, refs []record.Reference, types ...record.TypeID) (
+	[]record.Reference, bool,
+) {
+	valid := make([]record.Reference, 0, len(refs))
+	for _, ref := range refs {
+		if c.checkRef(lifelineRef, ref, types...) {
+			valid = append(valid, ref)
+		}
+	}
+	return valid, len(valid) == len(refs)
+}
+
+// latestState returns the valid latest state of lifeline. If stored state is invalid, the last state record of
+// history is used.
+func (c *checker) latestState(
+	head, latest record.Reference, history []record.Reference, types ...record.TypeID,
+) (record.Reference, bool) {
+	if bytes.Equal(latest.Key(), head.Key()) {
+		return latest, true
+	}
+	if c.checkRef(head, latest, types...) {
+		return latest, true
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		typeID := c.types[string(history[i].Key())]
+		for _, t := range types {
+			if t == typeID {
+				return history[i], false
+			}
+		}
+	}
+	return head, false
+}
+
+func (c *checker) checkLifeline(ref *record.Reference) error {
+	c.report.Lifelines++
+	head := *ref
+	switch c.types[string(ref.Key())] {
+	case classActivateType:
+		return c.checkClassLifeline(head)
+	case objectActivateType:
+		return c.checkObjectLifeline(head)
+	}
+	c.problem(OrphanLifeline, head, nil)
+	c.addFix(len(c.report.Problems)-1, func(batch storage.RawBatch) error {
+		if err := batch.SetHistory(&head, nil); err != nil {
+			return err
+		}
+		return batch.DeleteLifeline(&head)
+	})
+	return nil
+}
+
+// storedHistory returns lifeline history of the head. Corrupted history is reported as corrupted lifeline.
+func (c *checker) storedHistory(head record.Reference) ([]record.Reference, bool, error) {
+	var history []record.Reference
+	err := c.s.IterateHistory(&head, func(ref *record.Reference) error {
+		history = append(history, *ref)
+		return nil
+	})
+	if errors.Cause(err) == storage.ErrCorrupted {
+		c.problem(CorruptedLifeline, head, nil)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(history) > 0 {
+		c.histories[string(head.Key())] = true
+	}
+	return history, true, nil
+}
+
+// fixLifeline adds repair of lifeline index and history. History is rewritten only if it had invalid references.
+func (c *checker) fixLifeline(first int, head record.Reference, history []record.Reference, historyOK bool,
+	setIndex func(storage.RawBatch) error,
+) {
+	c.addFix(first, func(batch storage.RawBatch) error {
+		if !historyOK {
+			if err := batch.SetHistory(&head, history); err != nil {
+				return err
+			}
+		}
+		return setIndex(batch)
+	})
+}
+
+func (c *checker) markIndexed(refs []record.Reference) {
+	for _, ref := range refs {
+		c.indexed[string(ref.Key())] = true
+	}
+}
+
+func (c *checker) checkClassLifeline(head record.Reference) error {
+	// Corrupted lifeline is reported once, not as missing one.
+	c.lifelines[string(head.Key())] = true
+	idx, err := c.s.GetClassIndex(&head)
+	if errors.Cause(err) == storage.ErrCorrupted {
+		c.problem(CorruptedLifeline, head, nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	first := len(c.report.Problems)
+	stored, ok, err := c.storedHistory(head)
+	if !ok {
+		return err
+	}
+	amends, amendsOK := c.checkRefs(head, idx.AmendRefs, classAmendType)
+	history, historyOK := c.checkRefs(head, stored, classAmendType, deactivationType)
+	latest, latestOK := c.latestState(head, idx.LatestStateRef, history, classAmendType, deactivationType)
+	c.markIndexed(history)
+	if len(stored) == 0 {
+		c.markIndexed(amends)
+		c.markIndexed([]record.Reference{latest})
+	}
+	if amendsOK && historyOK && latestOK {
+		return nil
+	}
+	fixed := &index.ClassLifeline{LatestStateRef: latest, AmendRefs: amends}
+	c.fixLifeline(first, head, history, historyOK, func(batch storage.RawBatch) error {
+		return batch.SetClassIndex(&head, fixed)
+	})
+	return nil
+}
+
+func (c *checker) checkObjectLifeline(head record.Reference) error {
+	// Corrupted lifeline is reported once, not as missing one.
+	c.lifelines[string(head.Key())] = true
+	idx, err := c.s.GetObjectIndex(&head)
+	if errors.Cause(err) == storage.ErrCorrupted {
+		c.problem(CorruptedLifeline, head, nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	first := len(c.report.Problems)
+	stored, ok, err := c.storedHistory(head)
+	if !ok {
+		return err
+	}
+	classRef := idx.ClassRef
+	classOK := c.checkRef(head, classRef, classActivateType)
+	if !classOK {
+		// Object activation record also refers to the class.
+		classRef = c.objectClasses[string(head.Key())]
+	}
+	appends, appendsOK := c.checkRefs(head, idx.AppendRefs, objectAppendType)
+	history, historyOK := c.checkRefs(head, stored, objectAmendType, objectAppendType, deactivationType)
+	latest, latestOK := c.latestState(head, idx.LatestStateRef, history, objectAmendType, deactivationType)
+	c.markIndexed(history)
+	if len(stored) == 0 {
+		c.markIndexed(appends)
+		c.markIndexed([]record.Reference{latest})
+	}
+	if classOK && appendsOK && historyOK && latestOK {
+		return nil
+	}
+	fixed := &index.ObjectLifeline{
+		ClassRef:       classRef,
+		LatestStateRef: latest,
+		AppendRefs:     appends,
+		ClassStateRef:  idx.ClassStateRef,
+	}
+	c.fixLifeline(first, head, history, historyOK, func(batch storage.RawBatch) error {
+		return batch.SetObjectIndex(&head, fixed)
+	})
+	return nil
+}
+
+// checkUnindexed finds activation records without lifelines and amend records missing from lifeline histories.
+func (c *checker) checkUnindexed() error {
+	for _, key := range c.keys {
+		if c.lifelines[key] {
+			continue
+		}
+		ref, err := refFromKey(key)
+		if err != nil {
+			return err
+		}
+		switch c.types[key] {
+		case classActivateType:
+			c.problem(MissingLifeline, ref, nil)
+			c.addFix(len(c.report.Problems)-1, func(batch storage.RawBatch) error {
+				return batch.SetClassIndex(&ref, &index.ClassLifeline{LatestStateRef: ref})
+			})
+		case objectActivateType:
+			classRef := c.objectClasses[key]
+			c.problem(MissingLifeline, ref, nil)
+			c.addFix(len(c.report.Problems)-1, func(batch storage.RawBatch) error {
+				return batch.SetObjectIndex(&ref, &index.ObjectLifeline{ClassRef: classRef, LatestStateRef: ref})
+			})
+		}
+	}
+	for _, key := range c.keys {
+		head, ok := c.amends[key]
+		if !ok || c.indexed[key] {
+			continue
+		}
+		// Legacy lifelines have no history, so only current state is known.
+		if !c.lifelines[string(head.Key())] || c.histories[string(head.Key())] {
+			ref, err := refFromKey(key)
+			if err != nil {
+				return err
+			}
+			c.problem(UnindexedRecord, ref, nil)
+		}
+	}
+	return nil
+}
+
+func (c *checker) checkResult(requestRef, resultRef *record.Reference) error {
+	c.report.Results++
+	if _, ok := c.types[string(requestRef.Key())]; !ok {
+		c.problem(DanglingResult, *requestRef, nil)
+		return nil
+	}
+	if _, ok := c.types[string(resultRef.Key())]; !ok {
+		c.problem(DanglingResult, *requestRef, resultRef)
+	}
+	return nil
+}
+
+// checkBlobs checks blob hashes, finds blobs missing for code records and blobs with wrong reference count.
+// Reference count of a blob is repaired to the number of code records referring to it, so unreferenced blobs are
+// removed.
+func (c *checker) checkBlobs() error {
+	stored := map[string]bool{}
+	err := c.s.IterateBlobs(func(hash, data []byte, refs uint64) error {
+		c.report.Blobs++
+		stored[string(hash)] = true
+		if !bytes.Equal(record.BlobHash(data), hash) {
+			c.report.Problems = append(c.report.Problems, Problem{Kind: HashMismatch, Blob: hash})
+			return nil
+		}
+		want := c.blobRefs[string(hash)]
+		if refs != want {
+			c.report.Problems = append(c.report.Problems, Problem{Kind: BlobRefCount, Blob: hash})
+			c.addFix(len(c.report.Problems)-1, func(batch storage.RawBatch) error {
+				return batch.SetRawBlob(hash, data, want)
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, key := range c.keys {
+		for _, hash := range c.codeBlobs[key] {
+			if !stored[string(hash)] {
+				ref, err := refFromKey(key)
+				if err != nil {
+					return err
+				}
+				c.report.Problems = append(c.report.Problems, Problem{
+					Kind: MissingBlob,
+					Ref:  ref,
+					Blob: hash,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+// refFromKey parses reference from the key it was collected under.
+func refFromKey(k string) (record.Reference, error) {
+	ref, n, err := record.ParseRefKey([]byte(k))
+	if err != nil {
+		return record.Reference{}, errors.Wrapf(storage.ErrCorrupted, "malformed reference key: %v", err)
+	}
+	if n != len(k) {
+		return record.Reference{}, errors.Wrap(storage.ErrCorrupted, "malformed reference key: trailing bytes")
+	}
+	return *ref, nil
+}
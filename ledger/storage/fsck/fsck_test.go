@@ -0,0 +1,321 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package fsck
+
+import (
+	"crypto/rand"
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	goleveldb "github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+	"github.com/insolar/insolar/ledger/storage/leveldb"
+	"github.com/insolar/insolar/ledger/storage/memory"
+)
+
+type factory func(t *testing.T) (Storage, func())
+
+var factories = map[string]factory{
+	"Memory": func(t *testing.T) (Storage, func()) {
+		return memory.NewMemLedger(), func() {}
+	},
+	"LevelDB": func(t *testing.T) (Storage, func()) {
+		dir, err := ioutil.TempDir("", "fsck")
+		mustNoError(t, err)
+		cfg := leveldb.DefaultConfig()
+		cfg.DataDirectory = dir
+		ledger, err := leveldb.NewLevelLedger(cfg)
+		mustNoError(t, err)
+		return ledger, func() {
+			assert.NoError(t, ledger.Drop())
+		}
+	},
+}
+
+func mustNoError(t *testing.T, err error) {
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+}
+
+func randRef() record.Reference {
+	hash := make([]byte, record.HashSize)
+	_, err := rand.Read(hash)
+	if err != nil {
+		panic(err)
+	}
+	return record.Reference{Record: record.ID{Hash: hash}}
+}
+
+type fixture struct {
+	classRef  *record.Reference
+	objRef    *record.Reference
+	amendRef  *record.Reference
+	appendRef *record.Reference
+	blobHash  []byte
+}
+
+// populate creates consistent ledger with one class, one amended object with a delegate and code stored in blob.
+func populate(t *testing.T, s Storage) *fixture {
+	var f fixture
+	err := s.Update(func(batch storage.Batch) error {
+		var err error
+		if f.classRef, err = batch.SetRecord(&record.ClassActivateRecord{DefaultMemory: record.Memory{1}}); err != nil {
+			return err
+		}
+		if err = batch.SetClassIndex(f.classRef, &index.ClassLifeline{LatestStateRef: *f.classRef}); err != nil {
+			return err
+		}
+		f.objRef, err = batch.SetRecord(&record.ObjectActivateRecord{ClassActivateRecord: *f.classRef})
+		if err != nil {
+			return err
+		}
+		f.amendRef, err = batch.SetRecord(&record.ObjectAmendRecord{
+			AmendRecord: record.AmendRecord{HeadRecord: *f.objRef},
+			NewMemory:   record.Memory{2},
+		})
+		if err != nil {
+			return err
+		}
+		f.appendRef, err = batch.SetRecord(&record.ObjectAppendRecord{
+			AmendRecord:  record.AmendRecord{HeadRecord: *f.objRef},
+			AppendMemory: record.Memory{3},
+		})
+		if err != nil {
+			return err
+		}
+		err = batch.SetObjectIndex(f.objRef, &index.ObjectLifeline{
+			ClassRef:       *f.classRef,
+			LatestStateRef: *f.amendRef,
+			AppendRefs:     []record.Reference{*f.appendRef},
+		})
+		if err != nil {
+			return err
+		}
+		if err = batch.AppendHistory(f.objRef, f.amendRef); err != nil {
+			return err
+		}
+		if err = batch.AppendHistory(f.objRef, f.appendRef); err != nil {
+			return err
+		}
+		if f.blobHash, err = batch.SetBlob([]byte("code")); err != nil {
+			return err
+		}
+		_, err = batch.SetRecord(&record.CodeRecord{CodeBlobs: map[record.ArchType][]byte{1: f.blobHash}})
+		if err != nil {
+			return err
+		}
+		requestRef, err := batch.SetRecord(&record.CallRequest{})
+		if err != nil {
+			return err
+		}
+		return batch.SetRequestResult(requestRef, f.amendRef)
+	})
+	mustNoError(t, err)
+	return &f
+}
+
+// breakStorage adds one problem of every kind except corrupted data.
+func breakStorage(t *testing.T, s Storage, f *fixture) {
+	err := s.UpdateRaw(func(batch storage.RawBatch) error {
+		orphanRef := randRef()
+		if err := batch.SetClassIndex(&orphanRef, &index.ClassLifeline{LatestStateRef: orphanRef}); err != nil {
+			return err
+		}
+		err := batch.SetObjectIndex(f.objRef, &index.ObjectLifeline{
+			ClassRef:       *f.classRef,
+			LatestStateRef: *f.classRef,
+			AppendRefs:     []record.Reference{*f.appendRef, randRef()},
+		})
+		if err != nil {
+			return err
+		}
+		if _, err = batch.SetRecord(&record.ClassActivateRecord{DefaultMemory: record.Memory{4}}); err != nil {
+			return err
+		}
+		_, err = batch.SetRecord(&record.ObjectAmendRecord{
+			AmendRecord: record.AmendRecord{HeadRecord: *f.objRef},
+			NewMemory:   record.Memory{5},
+		})
+		if err != nil {
+			return err
+		}
+		raw, err := record.EncodeToRaw(&record.CodeRecord{SourceCode: "tampered"})
+		if err != nil {
+			return err
+		}
+		tamperedRef := randRef()
+		if err = batch.SetRawRecord(&tamperedRef, raw); err != nil {
+			return err
+		}
+		if err = batch.SetRawBlob(f.blobHash, []byte("code"), 2); err != nil {
+			return err
+		}
+		_, err = batch.SetRecord(&record.CodeRecord{CodeBlobs: map[record.ArchType][]byte{1: []byte("missing")}})
+		if err != nil {
+			return err
+		}
+		missingRef := randRef()
+		return batch.SetRequestResult(&missingRef, f.amendRef)
+	})
+	mustNoError(t, err)
+}
+
+func problemKinds(report *Report) []ProblemKind {
+	var kinds []ProblemKind
+	for _, p := range report.Problems {
+		kinds = append(kinds, p.Kind)
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+func TestCheck_ConsistentStorage(t *testing.T) {
+	for name, newStorage := range factories {
+		t.Run(name, func(t *testing.T) {
+			s, closer := newStorage(t)
+			defer closer()
+			populate(t, s)
+
+			report, err := Check(s, Options{})
+			mustNoError(t, err)
+			assert.True(t, report.OK())
+			assert.Empty(t, report.Problems)
+			// genesis, class, object, amend, append, code and request
+			assert.Equal(t, 7, report.Records)
+			assert.Equal(t, 3, report.Lifelines)
+			assert.Equal(t, 1, report.Results)
+			assert.Equal(t, 1, report.Blobs)
+		})
+	}
+}
+
+func TestCheck_ReportsAndRepairsProblems(t *testing.T) {
+	for name, newStorage := range factories {
+		t.Run(name, func(t *testing.T) {
+			s, closer := newStorage(t)
+			defer closer()
+			f := populate(t, s)
+			breakStorage(t, s, f)
+
+			report, err := Check(s, Options{})
+			mustNoError(t, err)
+			assert.False(t, report.OK())
+			assert.Equal(t, []ProblemKind{
+				HashMismatch, OrphanLifeline, DanglingRef, WrongRecordType, MissingLifeline, UnindexedRecord,
+				DanglingResult, MissingBlob, BlobRefCount,
+			}, problemKinds(report))
+
+			// Check without repair does not modify storage.
+			report, err = Check(s, Options{})
+			mustNoError(t, err)
+			assert.Len(t, report.Problems, 9)
+
+			report, err = Check(s, Options{Repair: true})
+			mustNoError(t, err)
+			assert.False(t, report.OK())
+			var repaired, left Report
+			for _, p := range report.Problems {
+				if p.Repaired {
+					repaired.Problems = append(repaired.Problems, p)
+				} else {
+					left.Problems = append(left.Problems, p)
+				}
+			}
+			assert.Equal(t, []ProblemKind{
+				OrphanLifeline, DanglingRef, WrongRecordType, MissingLifeline, BlobRefCount,
+			}, problemKinds(&repaired))
+			assert.Equal(t, []ProblemKind{
+				HashMismatch, UnindexedRecord, DanglingResult, MissingBlob,
+			}, problemKinds(&left))
+
+			report, err = Check(s, Options{})
+			mustNoError(t, err)
+			assert.Equal(t, []ProblemKind{
+				HashMismatch, UnindexedRecord, DanglingResult, MissingBlob,
+			}, problemKinds(report))
+			objIndex, err := s.GetObjectIndex(f.objRef)
+			mustNoError(t, err)
+			assert.Equal(t, f.amendRef.Key(), objIndex.LatestStateRef.Key())
+			assert.Len(t, objIndex.AppendRefs, 1)
+			refs, err := s.GetBlobRefs(f.blobHash)
+			mustNoError(t, err)
+			assert.Equal(t, uint64(1), refs)
+		})
+	}
+}
+
+func TestCheck_RepairsLifelineHistory(t *testing.T) {
+	for name, newStorage := range factories {
+		t.Run(name, func(t *testing.T) {
+			s, closer := newStorage(t)
+			defer closer()
+			f := populate(t, s)
+			danglingRef := randRef()
+			err := s.Update(func(batch storage.Batch) error {
+				return batch.AppendHistory(f.objRef, &danglingRef)
+			})
+			mustNoError(t, err)
+
+			report, err := Check(s, Options{Repair: true})
+			mustNoError(t, err)
+			assert.Equal(t, []ProblemKind{DanglingRef}, problemKinds(report))
+			assert.Equal(t, danglingRef.Key(), report.Problems[0].Target.Key())
+			assert.True(t, report.OK())
+
+			var history [][]byte
+			err = s.IterateHistory(f.objRef, func(ref *record.Reference) error {
+				history = append(history, ref.Key())
+				return nil
+			})
+			mustNoError(t, err)
+			assert.Equal(t, [][]byte{f.amendRef.Key(), f.appendRef.Key()}, history)
+		})
+	}
+}
+
+func TestCheck_ReportsCorruptedLifelineOnce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsck")
+	mustNoError(t, err)
+	cfg := leveldb.DefaultConfig()
+	cfg.DataDirectory = dir
+	ledger, err := leveldb.NewLevelLedger(cfg)
+	mustNoError(t, err)
+	f := populate(t, ledger)
+	mustNoError(t, ledger.Close())
+
+	// Lifeline index is stored under scope 1 prefix.
+	db, err := goleveldb.OpenFile(dir, nil)
+	mustNoError(t, err)
+	mustNoError(t, db.Put(append([]byte{1}, f.objRef.Key()...), []byte{0xff}, nil))
+	mustNoError(t, db.Close())
+	ledger, err = leveldb.NewLevelLedger(cfg)
+	mustNoError(t, err)
+	defer func() {
+		assert.NoError(t, ledger.Drop())
+	}()
+
+	report, err := Check(ledger, Options{Repair: true})
+	mustNoError(t, err)
+	assert.Equal(t, []ProblemKind{CorruptedLifeline}, problemKinds(report))
+	assert.False(t, report.OK())
+}
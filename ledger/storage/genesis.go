@@ -0,0 +1,50 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+)
+
+// GenesisPulse is a pulse number of genesis record.
+const GenesisPulse record.PulseNum = 0
+
+// GenesisRecord returns canonical zero record. It is an empty class activation record, which is the root of the
+// record graph.
+//
+// Every storage should contain genesis record from the moment it is created.
+func GenesisRecord() *record.ClassActivateRecord {
+	return &record.ClassActivateRecord{}
+}
+
+// GenesisReference returns reference to genesis record. Genesis record is also the root domain, so its ID is used as
+// both domain and record parts of the reference.
+func GenesisReference() record.Reference {
+	id := GenesisPulse.ID(GenesisRecord())
+	return record.Reference{
+		Domain: id,
+		Record: id,
+	}
+}
+
+// GenesisIndex returns lifeline index of genesis record.
+func GenesisIndex() *index.ClassLifeline {
+	return &index.ClassLifeline{
+		LatestStateRef: GenesisReference(),
+	}
+}
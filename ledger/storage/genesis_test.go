@@ -0,0 +1,32 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenesisReference_IsDeterministic(t *testing.T) {
+	ref := GenesisReference()
+	assert.Equal(t, ref, GenesisReference())
+	assert.Equal(t, GenesisPulse, ref.Record.Pulse)
+	assert.Equal(t, ref.Domain, ref.Record)
+	assert.Equal(t, "351f8009b878b397dabdcf7f398be3b44d89c61f59db2375a7c321a4", hex.EncodeToString(ref.Record.Hash))
+}
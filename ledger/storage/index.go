@@ -0,0 +1,48 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/index"
+)
+
+// CompareObjectIndex checks that stored object index is equal to the expected one. Expected index is encoded by
+// index.EncodeObjectLifeline, stored one is decoded and encoded again, so indexes stored in older formats are compared
+// by value. Nil stored index means that index is missing.
+//
+// It returns ErrConflict if indexes differ. Storages use it to check Batch.CheckObjectIndex preconditions.
+func CompareObjectIndex(stored, expected []byte) error {
+	if stored == nil {
+		return ErrConflict
+	}
+	idx, err := index.DecodeObjectLifeline(stored)
+	if err != nil {
+		return errors.Wrapf(ErrCorrupted, "failed to decode object index: %v", err)
+	}
+	normalized, err := index.EncodeObjectLifeline(idx)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(normalized, expected) {
+		return ErrConflict
+	}
+	return nil
+}
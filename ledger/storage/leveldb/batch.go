@@ -0,0 +1,227 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package leveldb
+
+import (
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// levelBatch implements storage.RawBatch on top of LevelDB batch.
+type levelBatch struct {
+	ll    *LevelLedger
+	batch leveldb.Batch
+	// pulse is taken once per batch, so all records of atomic update have the same pulse.
+	pulse record.PulseNum
+	// maxPulse is the newest pulse of records in batch. It is valid only if hasRecords is true.
+	maxPulse   record.PulseNum
+	hasRecords bool
+	// blobs are pending blob reference count changes by content hash. They are resolved on write.
+	blobs map[string]*blobChange
+	// history are records appended to lifeline histories by head key, rawHistory are replaced histories. They are
+	// resolved on write.
+	history    map[string][]*record.Reference
+	rawHistory map[string][]record.Reference
+	// results are keys of request results set by batch. They are checked against stored ones on write.
+	results map[string]bool
+	// checks are expected encoded object indexes by lifeline key. They are checked against stored ones on write.
+	checks       map[string][]byte
+	hasLifelines bool
+}
+
+func (b *levelBatch) putRecord(ref *record.Reference, k, v []byte, typeID record.TypeID) {
+	b.batch.Put(k, v)
+	for _, ik := range recordIndexKeys(ref, typeID) {
+		b.batch.Put(ik, nil)
+	}
+	if !b.hasRecords || ref.Record.Pulse > b.maxPulse {
+		b.maxPulse = ref.Record.Pulse
+	}
+	b.hasRecords = true
+}
+
+// SetRecord adds record to batch.
+func (b *levelBatch) SetRecord(rec record.Record) (*record.Reference, error) {
+	ref, k, v, err := b.ll.recordKV(rec, b.pulse)
+	if err != nil {
+		return nil, err
+	}
+	b.putRecord(ref, k, v, record.TypeIDOf(rec))
+	return ref, nil
+}
+
+// SetRawRecord adds serialized record to batch.
+func (b *levelBatch) SetRawRecord(ref *record.Reference, raw *record.Raw) error {
+	if !ref.Record.Pulse.IsValid() {
+		return errors.Wrapf(record.ErrReservedPulse, "can't store record at pulse %d", ref.Record.Pulse)
+	}
+	if err := b.ll.CheckSignature(ref, raw); err != nil {
+		return err
+	}
+	v, err := record.EncodeRaw(raw)
+	if err != nil {
+		return err
+	}
+	b.putRecord(ref, prefixkey(scopeIDRecord, ref.Key()), v, raw.Type)
+	return nil
+}
+
+// SetClassIndex adds lifeline index to batch.
+func (b *levelBatch) SetClassIndex(ref *record.Reference, idx *index.ClassLifeline) error {
+	encoded, err := index.EncodeClassLifeline(idx)
+	if err != nil {
+		return err
+	}
+	b.batch.Put(prefixkey(scopeIDLifeline, ref.Key()), encoded)
+	b.hasLifelines = true
+	return nil
+}
+
+// SetObjectIndex adds lifeline index to batch.
+func (b *levelBatch) SetObjectIndex(ref *record.Reference, idx *index.ObjectLifeline) error {
+	encoded, err := index.EncodeObjectLifeline(idx)
+	if err != nil {
+		return err
+	}
+	b.batch.Put(prefixkey(scopeIDLifeline, ref.Key()), encoded)
+	b.hasLifelines = true
+	return nil
+}
+
+// DeleteLifeline adds lifeline index removal to batch.
+func (b *levelBatch) DeleteLifeline(ref *record.Reference) error {
+	b.batch.Delete(prefixkey(scopeIDLifeline, ref.Key()))
+	b.hasLifelines = true
+	return nil
+}
+
+// SetRequestResult adds request result reference to batch.
+func (b *levelBatch) SetRequestResult(requestRef, resultRef *record.Reference) error {
+	k := prefixkey(scopeIDResult, requestRef.Key())
+	if b.results[string(k)] {
+		return storage.ErrRequestHasResult
+	}
+	if b.results == nil {
+		b.results = map[string]bool{}
+	}
+	b.results[string(k)] = true
+	b.batch.Put(k, resultRef.Key())
+	return nil
+}
+
+// CheckObjectIndex adds object index precondition to batch.
+func (b *levelBatch) CheckObjectIndex(ref *record.Reference, idx *index.ObjectLifeline) error {
+	encoded, err := index.EncodeObjectLifeline(idx)
+	if err != nil {
+		return err
+	}
+	if b.checks == nil {
+		b.checks = map[string][]byte{}
+	}
+	b.checks[string(prefixkey(scopeIDLifeline, ref.Key()))] = encoded
+	return nil
+}
+
+// checkIndexes checks object index preconditions. It must be called under writeLock.
+func (b *levelBatch) checkIndexes() error {
+	for k, expected := range b.checks {
+		stored, err := b.ll.ldb.Get([]byte(k), nil)
+		if err != nil && err != leveldb.ErrNotFound {
+			return err
+		}
+		if err = storage.CompareObjectIndex(stored, expected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkResults checks that batch does not replace stored request results. It must be called under writeLock.
+func (b *levelBatch) checkResults() error {
+	for k := range b.results {
+		has, err := b.ll.ldb.Has([]byte(k), nil)
+		if err != nil {
+			return err
+		}
+		if has {
+			return storage.ErrRequestHasResult
+		}
+	}
+	return nil
+}
+
+// Update collects writes made by fn into LevelDB batch and writes them in one operation.
+//
+// If batch contains records with pulse newer than the last persisted one, the new pulse is persisted in the same
+// write. Blob reference counts and history sequence numbers are resolved, request results and index preconditions
+// are checked against stored data right before the write.
+func (ll *LevelLedger) Update(fn func(storage.Batch) error) error {
+	return ll.update(func(b *levelBatch) error {
+		return fn(b)
+	})
+}
+
+// UpdateRaw works as Update, but provided batch also accepts serialized records.
+func (ll *LevelLedger) UpdateRaw(fn func(storage.RawBatch) error) error {
+	return ll.update(func(b *levelBatch) error {
+		return fn(b)
+	})
+}
+
+func (ll *LevelLedger) update(fn func(*levelBatch) error) error {
+	b := &levelBatch{ll: ll, pulse: ll.currentPulse()}
+	if err := fn(b); err != nil {
+		return err
+	}
+	if !b.hasRecords && !b.hasLifelines && len(b.blobs) == 0 && len(b.results) == 0 && len(b.checks) == 0 &&
+		len(b.history) == 0 && len(b.rawHistory) == 0 {
+		return ll.ldb.Write(&b.batch, ll.writeOpts)
+	}
+
+	// Serialize writes with records, so persisted pulse never goes back, writes with blobs, so reference counts
+	// are not lost, writes with request results, so a request never gets two results, writes with lifelines, so
+	// index preconditions hold, and writes with histories, so history records are not lost.
+	ll.writeLock.Lock()
+	defer ll.writeLock.Unlock()
+	if err := b.checkIndexes(); err != nil {
+		return err
+	}
+	if err := b.checkResults(); err != nil {
+		return err
+	}
+	if err := b.applyBlobs(); err != nil {
+		return err
+	}
+	if err := b.applyHistory(); err != nil {
+		return err
+	}
+	persistPulse := b.hasRecords && b.maxPulse > ll.LastPulse()
+	if persistPulse {
+		b.batch.Put(metakey(metaKeyPulse), encodePulse(b.maxPulse))
+	}
+	if err := ll.ldb.Write(&b.batch, ll.writeOpts); err != nil {
+		return err
+	}
+	if persistPulse {
+		ll.updateLastPulse(b.maxPulse)
+	}
+	return nil
+}
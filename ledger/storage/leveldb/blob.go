@@ -0,0 +1,148 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package leveldb
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+func blobKey(scope byte, hash []byte) []byte {
+	return append([]byte{scope}, hash...)
+}
+
+func encodeBlobRefs(refs uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, refs)
+	return buf
+}
+
+func decodeBlobRefs(buf []byte) (uint64, error) {
+	if len(buf) != 8 {
+		return 0, errors.Wrapf(storage.ErrCorrupted, "wrong blob reference count length %v", len(buf))
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+// GetBlob returns blob stored under provided content hash.
+func (ll *LevelLedger) GetBlob(hash []byte) ([]byte, error) {
+	buf, err := ll.ldb.Get(blobKey(scopeIDBlob, hash), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// GetBlobRefs returns number of references to the blob.
+func (ll *LevelLedger) GetBlobRefs(hash []byte) (uint64, error) {
+	buf, err := ll.ldb.Get(blobKey(scopeIDBlobRefs, hash), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return 0, ErrNotFound
+		}
+		return 0, err
+	}
+	return decodeBlobRefs(buf)
+}
+
+// IterateBlobs calls provided function for every stored blob with its reference count.
+func (ll *LevelLedger) IterateBlobs(fn func(hash, data []byte, refs uint64) error) error {
+	return ll.iterateScope(scopeIDBlob, func(k, v []byte) error {
+		hash := append([]byte(nil), k...)
+		refs, err := ll.GetBlobRefs(hash)
+		if err == ErrNotFound {
+			return errors.Wrapf(storage.ErrCorrupted, "blob %x has no reference count", hash)
+		}
+		if err != nil {
+			return err
+		}
+		return fn(hash, append([]byte(nil), v...), refs)
+	})
+}
+
+// blobChange is a pending change of blob reference count.
+type blobChange struct {
+	data []byte
+	refs uint64 // references added by batch
+}
+
+func (b *levelBatch) blobChange(hash []byte) *blobChange {
+	if b.blobs == nil {
+		b.blobs = map[string]*blobChange{}
+	}
+	c, ok := b.blobs[string(hash)]
+	if !ok {
+		c = &blobChange{}
+		b.blobs[string(hash)] = c
+	}
+	return c
+}
+
+// SetBlob adds blob reference to batch.
+func (b *levelBatch) SetBlob(data []byte) ([]byte, error) {
+	hash := record.BlobHash(data)
+	c := b.blobChange(hash)
+	c.data = data
+	c.refs++
+	return hash, nil
+}
+
+// SetRawBlob adds blob with exact reference count to batch.
+func (b *levelBatch) SetRawBlob(hash, data []byte, refs uint64) error {
+	if refs == 0 {
+		b.deleteBlob(hash)
+	} else {
+		b.putBlob(hash, data, refs)
+	}
+	return nil
+}
+
+func (b *levelBatch) putBlob(hash, data []byte, refs uint64) {
+	b.batch.Put(blobKey(scopeIDBlob, hash), data)
+	b.batch.Put(blobKey(scopeIDBlobRefs, hash), encodeBlobRefs(refs))
+}
+
+func (b *levelBatch) deleteBlob(hash []byte) {
+	b.batch.Delete(blobKey(scopeIDBlob, hash))
+	b.batch.Delete(blobKey(scopeIDBlobRefs, hash))
+}
+
+// applyBlobs resolves pending reference count changes against stored counts. It must be called under writeLock, so
+// concurrent batches don't lose each other's changes.
+func (b *levelBatch) applyBlobs() error {
+	for k, c := range b.blobs {
+		hash := []byte(k)
+		refs, err := b.ll.GetBlobRefs(hash)
+		if err != nil && err != ErrNotFound {
+			return err
+		}
+		if refs == 0 {
+			b.putBlob(hash, c.data, c.refs)
+		} else {
+			b.batch.Put(blobKey(scopeIDBlobRefs, hash), encodeBlobRefs(refs+c.refs))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package leveldb
+
+import (
+	"github.com/syndtr/goleveldb/leveldb/comparer"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+)
+
+// SyncPolicy defines when written data is flushed to disk.
+type SyncPolicy int
+
+const (
+	// SyncDefault writes data to OS buffers and relies on LevelDB journal for recovery.
+	SyncDefault SyncPolicy = iota
+	// SyncAlways flushes every write to disk before returning.
+	SyncAlways
+	// SyncNever disables fsync completely. Data can be lost on machine crash.
+	SyncNever
+)
+
+const (
+	defaultDataDirectory = "_db"
+	// defaultBlockCacheSize increased to 32MiB from LevelDB's default 8 MiB.
+	defaultBlockCacheSize = 32 * 1024 * 1024
+	// defaultWriteBufferSize increased to 16MiB from LevelDB's default 4 MiB.
+	defaultWriteBufferSize = 16 * 1024 * 1024
+)
+
+// Config contains LevelLedger settings.
+//
+// Zero values of size fields are replaced by defaults, so Config with only DataDirectory set is valid.
+type Config struct {
+	// DataDirectory is a path to directory with LevelDB files. Relative path is resolved from working directory.
+	DataDirectory string
+	// BlockCacheSize defines the capacity of the 'sorted table' block caching in bytes.
+	BlockCacheSize int
+	// WriteBufferSize defines maximum size of in-memory table (backed by journal) before it will be flushed to disk.
+	WriteBufferSize int
+	// NoCompression disables snappy compression of 'sorted table' blocks.
+	NoCompression bool
+	// Sync defines when written data is flushed to disk.
+	Sync SyncPolicy
+	// ReadOnly opens existing storage in read-only mode. All write operations will fail.
+	ReadOnly bool
+}
+
+// DefaultConfig returns config with default settings used by InitDB.
+func DefaultConfig() Config {
+	return Config{
+		DataDirectory:   defaultDataDirectory,
+		BlockCacheSize:  defaultBlockCacheSize,
+		WriteBufferSize: defaultWriteBufferSize,
+	}
+}
+
+func (cfg Config) options() *opt.Options {
+	blockCacheSize := cfg.BlockCacheSize
+	if blockCacheSize <= 0 {
+		blockCacheSize = defaultBlockCacheSize
+	}
+	writeBufferSize := cfg.WriteBufferSize
+	if writeBufferSize <= 0 {
+		writeBufferSize = defaultWriteBufferSize
+	}
+	compression := opt.DefaultCompression
+	if cfg.NoCompression {
+		compression = opt.NoCompression
+	}
+
+	// Options struct doc: https://godoc.org/github.com/syndtr/goleveldb/leveldb/opt#Options.
+	return &opt.Options{
+		AltFilters:  nil,
+		BlockCacher: opt.LRUCacher,
+		// BlockCacheCapacity defines the capacity of the 'sorted table' block caching.
+		BlockCacheCapacity:                    blockCacheSize,
+		BlockRestartInterval:                  16,
+		BlockSize:                             4 * 1024,
+		CompactionExpandLimitFactor:           25,
+		CompactionGPOverlapsFactor:            10,
+		CompactionL0Trigger:                   4,
+		CompactionSourceLimitFactor:           1,
+		CompactionTableSize:                   2 * 1024 * 1024,
+		CompactionTableSizeMultiplier:         1.0,
+		CompactionTableSizeMultiplierPerLevel: nil,
+		// CompactionTotalSize increased to 32MiB from default 10 MiB.
+		// CompactionTotalSize limits total size of 'sorted table' for each level.
+		// The limits for each level will be calculated as:
+		//   CompactionTotalSize * (CompactionTotalSizeMultiplier ^ Level)
+		CompactionTotalSize:                   32 * 1024 * 1024,
+		CompactionTotalSizeMultiplier:         10.0,
+		CompactionTotalSizeMultiplierPerLevel: nil,
+		Comparer:                     comparer.DefaultComparer,
+		Compression:                  compression,
+		DisableBufferPool:            false,
+		DisableBlockCache:            false,
+		DisableCompactionBackoff:     false,
+		DisableLargeBatchTransaction: false,
+		ErrorIfExist:                 false,
+		ErrorIfMissing:               cfg.ReadOnly,
+		Filter:                       nil,
+		IteratorSamplingRate:         1 * 1024 * 1024,
+		NoSync:                       cfg.Sync == SyncNever,
+		NoWriteMerge:                 false,
+		OpenFilesCacher:              opt.LRUCacher,
+		OpenFilesCacheCapacity:       500,
+		ReadOnly:                     cfg.ReadOnly,
+		Strict:                       opt.DefaultStrict,
+		WriteBuffer:                  writeBufferSize,
+		WriteL0PauseTrigger:          12,
+		WriteL0SlowdownTrigger:       8,
+	}
+}
+
+func (cfg Config) writeOptions() *opt.WriteOptions {
+	return &opt.WriteOptions{
+		Sync: cfg.Sync == SyncAlways,
+	}
+}
@@ -16,9 +16,9 @@
 
 package leveldb
 
-import "errors"
+import "github.com/insolar/insolar/ledger/storage"
 
 var (
 	// ErrNotFound returns if record/index not found in leveldb storage.
-	ErrNotFound = errors.New("record not found")
+	ErrNotFound = storage.ErrNotFound
 )
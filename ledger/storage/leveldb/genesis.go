@@ -0,0 +1,98 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package leveldb
+
+import (
+	"bytes"
+
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/opt"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+const metaKeyGenesis = "genesis"
+
+// bootstrapGenesis writes genesis record into empty storage or checks that existing storage has expected genesis.
+func bootstrapGenesis(db *leveldb.DB, readOnly bool, wo *opt.WriteOptions) error {
+	genesisRef := storage.GenesisReference()
+	raw, err := record.EncodeToRaw(storage.GenesisRecord())
+	if err != nil {
+		return err
+	}
+	recordKey := prefixkey(scopeIDRecord, genesisRef.Key())
+
+	storedRef, err := db.Get(metakey(metaKeyGenesis), nil)
+	if err == leveldb.ErrNotFound {
+		if readOnly {
+			return errors.Wrap(storage.ErrGenesisMismatch, "genesis record not found")
+		}
+		empty, err := isEmpty(db)
+		if err != nil {
+			return err
+		}
+		if !empty {
+			return errors.Wrap(storage.ErrGenesisMismatch, "non-empty storage without genesis record")
+		}
+
+		encodedIndex, err := index.EncodeClassLifeline(storage.GenesisIndex())
+		if err != nil {
+			return err
+		}
+		batch := new(leveldb.Batch)
+		batch.Put(recordKey, record.MustEncodeRaw(raw))
+		for _, ik := range recordIndexKeys(&genesisRef, raw.Type) {
+			batch.Put(ik, nil)
+		}
+		batch.Put(prefixkey(scopeIDLifeline, genesisRef.Key()), encodedIndex)
+		batch.Put(metakey(metaKeyGenesis), genesisRef.Key())
+		return db.Write(batch, wo)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(storedRef, genesisRef.Key()) {
+		return errors.Wrap(storage.ErrGenesisMismatch, "wrong genesis reference")
+	}
+	storedRecord, err := db.Get(recordKey, nil)
+	if err == leveldb.ErrNotFound {
+		return errors.Wrap(storage.ErrGenesisMismatch, "genesis record not found")
+	}
+	if err != nil {
+		return err
+	}
+	// Record is compared by hash, so changes of raw record encoding don't affect existing storages.
+	storedRaw, err := record.DecodeToRaw(storedRecord)
+	if err != nil {
+		return errors.Wrapf(storage.ErrGenesisMismatch, "malformed genesis record: %v", err)
+	}
+	if !bytes.Equal(storedRaw.Sum(genesisRef.Record.Algorithm), genesisRef.Record.Hash) {
+		return errors.Wrap(storage.ErrGenesisMismatch, "wrong genesis record")
+	}
+	return nil
+}
+
+func isEmpty(db *leveldb.DB) (bool, error) {
+	it := db.NewIterator(nil, nil)
+	defer it.Release()
+	empty := !it.First()
+	return empty, it.Error()
+}
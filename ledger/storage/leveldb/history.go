@@ -0,0 +1,148 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package leveldb
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// Lifeline history is stored one record per key, so appending to history does not rewrite it:
+//
+//	scopeIDHistory | head reference key | big endian sequence number -> encoded record reference
+//
+// Reference keys are self-delimiting, so the head prefix never matches keys of other heads.
+const scopeIDHistory byte = 11
+
+func historyPrefix(headKey []byte) []byte {
+	return prefixkey(scopeIDHistory, headKey)
+}
+
+func historyKey(headKey []byte, seq uint64) []byte {
+	k := historyPrefix(headKey)
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, seq)
+	return append(k, buf...)
+}
+
+// IterateHistory calls provided function for every record in lifeline history of the head in order of appending.
+func (ll *LevelLedger) IterateHistory(headRef *record.Reference, fn func(*record.Reference) error) error {
+	it := ll.ldb.NewIterator(util.BytesPrefix(historyPrefix(headRef.Key())), nil)
+	defer it.Release()
+	for it.Next() {
+		ref, err := index.DecodeHistoryRef(it.Value())
+		if err != nil {
+			return errors.Wrapf(storage.ErrCorrupted, "failed to decode history reference: %v", err)
+		}
+		err = fn(ref)
+		if err == storage.ErrStopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// historyLen returns the number of records in stored lifeline history of the head.
+func (ll *LevelLedger) historyLen(headKey []byte) (uint64, error) {
+	prefix := historyPrefix(headKey)
+	it := ll.ldb.NewIterator(util.BytesPrefix(prefix), nil)
+	defer it.Release()
+	if !it.Last() {
+		return 0, it.Error()
+	}
+	if len(it.Key()) != len(prefix)+8 {
+		return 0, errors.Wrap(storage.ErrCorrupted, "malformed history key")
+	}
+	return binary.BigEndian.Uint64(it.Key()[len(prefix):]) + 1, nil
+}
+
+// AppendHistory adds record to pending lifeline history of the head. Sequence numbers are assigned on write.
+func (b *levelBatch) AppendHistory(headRef, ref *record.Reference) error {
+	if b.history == nil {
+		b.history = map[string][]*record.Reference{}
+	}
+	k := string(headRef.Key())
+	b.history[k] = append(b.history[k], ref)
+	return nil
+}
+
+// SetHistory adds replacement of lifeline history of the head to batch.
+func (b *levelBatch) SetHistory(headRef *record.Reference, refs []record.Reference) error {
+	if b.rawHistory == nil {
+		b.rawHistory = map[string][]record.Reference{}
+	}
+	k := string(headRef.Key())
+	b.rawHistory[k] = append([]record.Reference{}, refs...)
+	delete(b.history, k)
+	return nil
+}
+
+// applyHistory writes replaced histories and appends pending records after stored ones. It must be called under
+// writeLock, so concurrent batches don't get the same sequence numbers.
+func (b *levelBatch) applyHistory() error {
+	for k, refs := range b.rawHistory {
+		headKey := []byte(k)
+		it := b.ll.ldb.NewIterator(util.BytesPrefix(historyPrefix(headKey)), nil)
+		for it.Next() {
+			b.batch.Delete(append([]byte{}, it.Key()...))
+		}
+		it.Release()
+		if err := it.Error(); err != nil {
+			return err
+		}
+		for i := range refs {
+			if err := b.putHistory(headKey, uint64(i), &refs[i]); err != nil {
+				return err
+			}
+		}
+	}
+	for k, refs := range b.history {
+		headKey := []byte(k)
+		next := uint64(len(b.rawHistory[k]))
+		if _, ok := b.rawHistory[k]; !ok {
+			var err error
+			if next, err = b.ll.historyLen(headKey); err != nil {
+				return err
+			}
+		}
+		for _, ref := range refs {
+			if err := b.putHistory(headKey, next, ref); err != nil {
+				return err
+			}
+			next++
+		}
+	}
+	return nil
+}
+
+func (b *levelBatch) putHistory(headKey []byte, seq uint64, ref *record.Reference) error {
+	buf, err := index.EncodeHistoryRef(ref)
+	if err != nil {
+		return err
+	}
+	b.batch.Put(historyKey(headKey, seq), buf)
+	return nil
+}
@@ -0,0 +1,209 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package leveldb
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// Secondary record indexes. Index keys contain record pulse followed by reference key and have empty values:
+//
+//	scopeIDPulseIndex  | record pulse | reference key
+//	scopeIDTypeIndex   | type id      | record pulse | reference key
+//	scopeIDDomainIndex | domain id    | record pulse | reference key
+const (
+	scopeIDPulseIndex  byte = 4
+	scopeIDTypeIndex   byte = 5
+	scopeIDDomainIndex byte = 6
+)
+
+func typeBytes(typeID record.TypeID) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, uint32(typeID))
+	return b
+}
+
+func indexPrefix(q *storage.RecordQuery) []byte {
+	switch {
+	case q.Domain != nil:
+		return append([]byte{scopeIDDomainIndex}, record.ID2Bytes(*q.Domain)...)
+	case q.Type != 0:
+		return append([]byte{scopeIDTypeIndex}, typeBytes(q.Type)...)
+	default:
+		return []byte{scopeIDPulseIndex}
+	}
+}
+
+func indexKey(prefix []byte, ref *record.Reference) []byte {
+	k := make([]byte, 0, len(prefix)+record.PulseNumSize+record.MaxRefIDSize)
+	k = append(k, prefix...)
+	k = append(k, encodePulse(ref.Record.Pulse)...)
+	return append(k, ref.Key()...)
+}
+
+// recordIndexKeys returns all secondary index keys of the record.
+func recordIndexKeys(ref *record.Reference, typeID record.TypeID) [][]byte {
+	return [][]byte{
+		indexKey([]byte{scopeIDPulseIndex}, ref),
+		indexKey(append([]byte{scopeIDTypeIndex}, typeBytes(typeID)...), ref),
+		indexKey(append([]byte{scopeIDDomainIndex}, record.ID2Bytes(ref.Domain)...), ref),
+	}
+}
+
+func refFromKey(k []byte) (*record.Reference, error) {
+	ref, n, err := record.ParseRefKey(append([]byte{}, k...))
+	if err != nil {
+		return nil, errors.Wrapf(storage.ErrCorrupted, "malformed reference key: %v", err)
+	}
+	if n != len(k) {
+		return nil, errors.Wrap(storage.ErrCorrupted, "malformed reference key: trailing bytes")
+	}
+	return ref, nil
+}
+
+// IterateRecords iterates over records matching the query.
+//
+// The most selective secondary index is used for the query: domain index, type index or pulse index in this order.
+// Iteration runs on a storage snapshot, so records written during iteration are not visible.
+func (ll *LevelLedger) IterateRecords(
+	q storage.RecordQuery, fn func(*record.Reference, record.Record) error,
+) error {
+	snapshot, err := ll.ldb.GetSnapshot()
+	if err != nil {
+		return err
+	}
+	defer snapshot.Release()
+
+	prefix := indexPrefix(&q)
+	rng := util.BytesPrefix(prefix)
+	rng.Start = append(append([]byte{}, prefix...), encodePulse(q.FromPulse)...)
+	if q.After != nil {
+		// The smallest key greater than cursor key.
+		cursor := append(indexKey(prefix, q.After), 0)
+		if bytes.Compare(cursor, rng.Start) > 0 {
+			rng.Start = cursor
+		}
+	}
+
+	it := snapshot.NewIterator(rng, nil)
+	defer it.Release()
+	count := 0
+	for it.Next() {
+		refKey := it.Key()[len(prefix)+record.PulseNumSize:]
+		ref, err := refFromKey(refKey)
+		if err != nil {
+			return err
+		}
+		if q.ToPulse != 0 && ref.Record.Pulse >= q.ToPulse {
+			break
+		}
+		buf, err := snapshot.Get(prefixkey(scopeIDRecord, refKey), nil)
+		if err == leveldb.ErrNotFound {
+			return errors.Wrap(storage.ErrCorrupted, "record index refers to missing record")
+		}
+		if err != nil {
+			return err
+		}
+		raw, err := record.DecodeToRaw(buf)
+		if err != nil {
+			return errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
+		}
+		if !q.Matches(ref, raw.Type) {
+			continue
+		}
+		rec, err := raw.Decode()
+		if err != nil {
+			return errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
+		}
+		err = fn(ref, rec)
+		if err == storage.ErrStopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		count++
+		if q.Limit > 0 && count >= q.Limit {
+			break
+		}
+	}
+	return it.Error()
+}
+
+func (ll *LevelLedger) iterateScope(scope byte, fn func(k, v []byte) error) error {
+	it := ll.ldb.NewIterator(util.BytesPrefix([]byte{scope}), nil)
+	defer it.Release()
+	for it.Next() {
+		err := fn(it.Key()[1:], it.Value())
+		if err == storage.ErrStopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return it.Error()
+}
+
+// IterateRawRecords calls provided function for every stored record in its serialized form.
+func (ll *LevelLedger) IterateRawRecords(fn func(*record.Reference, *record.Raw) error) error {
+	return ll.iterateScope(scopeIDRecord, func(k, v []byte) error {
+		raw, err := record.DecodeToRaw(v)
+		if err != nil {
+			return errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
+		}
+		ref, err := refFromKey(k)
+		if err != nil {
+			return err
+		}
+		return fn(ref, raw)
+	})
+}
+
+// IterateLifelineRefs calls provided function for reference of every stored lifeline index.
+func (ll *LevelLedger) IterateLifelineRefs(fn func(*record.Reference) error) error {
+	return ll.iterateScope(scopeIDLifeline, func(k, v []byte) error {
+		ref, err := refFromKey(k)
+		if err != nil {
+			return err
+		}
+		return fn(ref)
+	})
+}
+
+// IterateRequestResults calls provided function for every request which has a result.
+func (ll *LevelLedger) IterateRequestResults(fn func(requestRef, resultRef *record.Reference) error) error {
+	return ll.iterateScope(scopeIDResult, func(k, v []byte) error {
+		requestRef, err := refFromKey(k)
+		if err != nil {
+			return err
+		}
+		resultRef, err := refFromKey(v)
+		if err != nil {
+			return err
+		}
+		return fn(requestRef, resultRef)
+	})
+}
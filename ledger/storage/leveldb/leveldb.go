@@ -19,125 +19,147 @@ package leveldb
 import (
 	"os"
 	"path/filepath"
-	"time"
+	"sync"
 
+	"github.com/pkg/errors"
 	"github.com/syndtr/goleveldb/leveldb"
-	"github.com/syndtr/goleveldb/leveldb/comparer"
 	"github.com/syndtr/goleveldb/leveldb/opt"
 
+	"github.com/insolar/insolar/ledger/hash"
 	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/pulse"
 	"github.com/insolar/insolar/ledger/record"
-)
-
-const (
-	dbDirPath        = "_db"
-	zeroRecordBinary = "" // TODO: Empty ClassActivateRecord serialized
-	zeroRecordHash   = "" // TODO: Hash from zeroRecordBinary
+	"github.com/insolar/insolar/ledger/storage"
 )
 
 // LevelLedger represents ledger's LevelDB storage.
 type LevelLedger struct {
-	ldb     *leveldb.DB
-	pulseFn func() record.PulseNum
-	zeroRef record.Reference
+	ldb       *leveldb.DB
+	dataDir   string
+	writeOpts *opt.WriteOptions
+
+	pulseLock     sync.RWMutex
+	pulseProvider pulse.Provider
+	lastPulse     record.PulseNum
+	writeLock     sync.Mutex
+
+	hashLock      sync.RWMutex
+	hashAlgorithm hash.Algorithm
+
+	storage.SignaturePolicy
 }
 
 const (
-	scopeIDLifeline byte = 1
-	scopeIDRecord   byte = 2
+	scopeIDLifeline  byte = 1
+	scopeIDRecord    byte = 2
+	scopeIDMeta      byte = 3
+	scopeIDResult    byte = 7
+	scopeIDBlob      byte = 8
+	scopeIDBlobRefs  byte = 9
+	scopeIDPulseRoot byte = 10
 )
 
 // InitDB returns LevelLedger with LevelDB initialized with default settings.
 func InitDB() (*LevelLedger, error) {
-	// Options struct doc: https://godoc.org/github.com/syndtr/goleveldb/leveldb/opt#Options.
-	opts := &opt.Options{
-		AltFilters:  nil,
-		BlockCacher: opt.LRUCacher,
-		// BlockCacheCapacity increased to 32MiB from default 8 MiB.
-		// BlockCacheCapacity defines the capacity of the 'sorted table' block caching.
-		BlockCacheCapacity:                    32 * 1024 * 1024,
-		BlockRestartInterval:                  16,
-		BlockSize:                             4 * 1024,
-		CompactionExpandLimitFactor:           25,
-		CompactionGPOverlapsFactor:            10,
-		CompactionL0Trigger:                   4,
-		CompactionSourceLimitFactor:           1,
-		CompactionTableSize:                   2 * 1024 * 1024,
-		CompactionTableSizeMultiplier:         1.0,
-		CompactionTableSizeMultiplierPerLevel: nil,
-		// CompactionTotalSize increased to 32MiB from default 10 MiB.
-		// CompactionTotalSize limits total size of 'sorted table' for each level.
-		// The limits for each level will be calculated as:
-		//   CompactionTotalSize * (CompactionTotalSizeMultiplier ^ Level)
-		CompactionTotalSize:                   32 * 1024 * 1024,
-		CompactionTotalSizeMultiplier:         10.0,
-		CompactionTotalSizeMultiplierPerLevel: nil,
-		Comparer:                     comparer.DefaultComparer,
-		Compression:                  opt.DefaultCompression,
-		DisableBufferPool:            false,
-		DisableBlockCache:            false,
-		DisableCompactionBackoff:     false,
-		DisableLargeBatchTransaction: false,
-		ErrorIfExist:                 false,
-		ErrorIfMissing:               false,
-		Filter:                       nil,
-		IteratorSamplingRate:         1 * 1024 * 1024,
-		NoSync:                       false,
-		NoWriteMerge:                 false,
-		OpenFilesCacher:              opt.LRUCacher,
-		OpenFilesCacheCapacity:       500,
-		ReadOnly:                     false,
-		Strict:                       opt.DefaultStrict,
-		WriteBuffer:                  16 * 1024 * 1024, // Default is 4 MiB
-		WriteL0PauseTrigger:          12,
-		WriteL0SlowdownTrigger:       8,
-	}
-
-	absPath, err := filepath.Abs(dbDirPath)
+	return NewLevelLedger(DefaultConfig())
+}
+
+// NewLevelLedger returns LevelLedger with LevelDB initialized with provided settings.
+//
+// Each LevelLedger should use its own data directory. LevelDB locks the directory, so opening the same directory
+// twice will fail.
+//
+// New storage is bootstrapped with genesis record (see storage.GenesisRecord). Existing storage is checked to contain
+// the expected genesis, otherwise storage.ErrGenesisMismatch is returned.
+//
+// Records are stamped by pulse.Manual provider starting from the last persisted pulse until another provider is set
+// by SetPulseProvider.
+func NewLevelLedger(cfg Config) (*LevelLedger, error) {
+	dataDir := cfg.DataDirectory
+	if dataDir == "" {
+		dataDir = defaultDataDirectory
+	}
+	absPath, err := filepath.Abs(dataDir)
+	if err != nil {
+		return nil, err
+	}
+	db, err := leveldb.OpenFile(absPath, cfg.options())
 	if err != nil {
 		return nil, err
 	}
-	db, err := leveldb.OpenFile(absPath, opts)
+
+	lastPulse, err := loadLastPulse(db)
 	if err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 
-	var zeroID record.ID
 	ledger := LevelLedger{
-		ldb: db,
-		// FIXME: temporary pulse implementation
-		pulseFn: func() record.PulseNum {
-			return record.PulseNum(time.Now().Unix() / 10)
-		},
-		zeroRef: record.Reference{
-			Domain: record.ID{}, // TODO: fill domain
-			Record: zeroID,
-		},
-	}
-	_, err = db.Get([]byte(zeroRecordHash), nil)
-	if err == leveldb.ErrNotFound {
-		err = db.Put([]byte(zeroRecordHash), []byte(zeroRecordBinary), nil)
-		if err != nil {
-			return nil, err
-		}
-		return &ledger, nil
-	} else if err != nil {
+		ldb:           db,
+		dataDir:       absPath,
+		writeOpts:     cfg.writeOptions(),
+		pulseProvider: pulse.NewManual(lastPulse),
+		lastPulse:     lastPulse,
+	}
+	err = bootstrapGenesis(db, cfg.ReadOnly, ledger.writeOpts)
+	if err != nil {
+		_ = db.Close()
 		return nil, err
 	}
 	return &ledger, nil
 }
 
 func prefixkey(prefix byte, key []byte) []byte {
-	k := make([]byte, record.RefIDSize+1)
-	k[0] = prefix
-	_ = copy(k[1:], key)
-	return k
+	k := make([]byte, 0, len(key)+1)
+	k = append(k, prefix)
+	return append(k, key...)
+}
+
+// SetHashAlgorithm sets hash algorithm used to identify new records. Records stored with other algorithms remain
+// readable. Default algorithm is SHA3-224.
+func (ll *LevelLedger) SetHashAlgorithm(alg hash.Algorithm) error {
+	if !alg.IsValid() {
+		return errors.Errorf("unsupported hash algorithm %d", byte(alg))
+	}
+	ll.hashLock.Lock()
+	defer ll.hashLock.Unlock()
+	ll.hashAlgorithm = alg
+	return nil
+}
+
+func (ll *LevelLedger) currentHashAlgorithm() hash.Algorithm {
+	ll.hashLock.RLock()
+	defer ll.hashLock.RUnlock()
+	return ll.hashAlgorithm
 }
 
 // GetRecord returns record from leveldb by *record.Reference.
 //
 // It returns ErrNotFound if the DB does not contains the key.
 func (ll *LevelLedger) GetRecord(ref *record.Reference) (record.Record, error) {
+	raw, err := ll.GetRawRecord(ref)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := raw.Decode()
+	if err != nil {
+		return nil, errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
+	}
+	return rec, nil
+}
+
+// GetRecordSignature returns signature of stored record. It returns nil signature for unsigned records and
+// ErrNotFound if there is no such record.
+func (ll *LevelLedger) GetRecordSignature(ref *record.Reference) (*record.Signature, error) {
+	raw, err := ll.GetRawRecord(ref)
+	if err != nil {
+		return nil, err
+	}
+	return raw.Signature, nil
+}
+
+// GetRawRecord returns stored record in serialized form.
+func (ll *LevelLedger) GetRawRecord(ref *record.Reference) (*record.Raw, error) {
 	k := prefixkey(scopeIDRecord, ref.Key())
 	buf, err := ll.ldb.Get(k, nil)
 	if err != nil {
@@ -148,23 +170,39 @@ func (ll *LevelLedger) GetRecord(ref *record.Reference) (record.Record, error) {
 	}
 	raw, err := record.DecodeToRaw(buf)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
 	}
-	return raw.ToRecord(), nil
+	return raw, nil
 }
 
-// SetRecord stores record in leveldb
-func (ll *LevelLedger) SetRecord(rec record.Record) (*record.Reference, error) {
+func (ll *LevelLedger) recordKV(rec record.Record, pn record.PulseNum) (*record.Reference, []byte, []byte, error) {
+	if !pn.IsValid() {
+		return nil, nil, nil, errors.Wrapf(record.ErrReservedPulse, "can't store record at pulse %d", pn)
+	}
 	raw, err := record.EncodeToRaw(rec)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
+	alg := ll.currentHashAlgorithm()
 	ref := &record.Reference{
 		Domain: rec.Domain(),
-		Record: record.ID{Pulse: ll.pulseFn(), Hash: raw.Hash()},
+		Record: record.ID{Pulse: pn, Hash: raw.Sum(alg), Algorithm: alg},
+	}
+	if err := ll.SignRecord(ref, raw); err != nil {
+		return nil, nil, nil, err
 	}
 	k := prefixkey(scopeIDRecord, ref.Key())
-	err = ll.ldb.Put(k, record.MustEncodeRaw(raw), nil)
+	return ref, k, record.MustEncodeRaw(raw), nil
+}
+
+// SetRecord stores record in leveldb. Record is stamped with current pulse of ledger's pulse provider.
+func (ll *LevelLedger) SetRecord(rec record.Record) (*record.Reference, error) {
+	var ref *record.Reference
+	err := ll.Update(func(batch storage.Batch) error {
+		var err error
+		ref, err = batch.SetRecord(rec)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -183,19 +221,16 @@ func (ll *LevelLedger) GetClassIndex(ref *record.Reference) (*index.ClassLifelin
 	}
 	idx, err := index.DecodeClassLifeline(buf)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(storage.ErrCorrupted, "failed to decode class index: %v", err)
 	}
 	return idx, nil
 }
 
 // SetClassIndex stores lifeline index into leveldb
 func (ll *LevelLedger) SetClassIndex(ref *record.Reference, idx *index.ClassLifeline) error {
-	k := prefixkey(scopeIDLifeline, ref.Key())
-	encoded, err := index.EncodeClassLifeline(idx)
-	if err != nil {
-		return err
-	}
-	return ll.ldb.Put(k, encoded, nil)
+	return ll.Update(func(batch storage.Batch) error {
+		return batch.SetClassIndex(ref, idx)
+	})
 }
 
 // GetObjectIndex fetches lifeline index from leveldb
@@ -210,19 +245,29 @@ func (ll *LevelLedger) GetObjectIndex(ref *record.Reference) (*index.ObjectLifel
 	}
 	idx, err := index.DecodeObjectLifeline(buf)
 	if err != nil {
-		return nil, err
+		return nil, errors.Wrapf(storage.ErrCorrupted, "failed to decode object index: %v", err)
 	}
 	return idx, nil
 }
 
 // SetObjectIndex stores lifeline index into leveldb
 func (ll *LevelLedger) SetObjectIndex(ref *record.Reference, idx *index.ObjectLifeline) error {
-	k := prefixkey(scopeIDLifeline, ref.Key())
-	encoded, err := index.EncodeObjectLifeline(idx)
+	return ll.Update(func(batch storage.Batch) error {
+		return batch.SetObjectIndex(ref, idx)
+	})
+}
+
+// GetRequestResult fetches reference of the result record produced by provided request.
+func (ll *LevelLedger) GetRequestResult(requestRef *record.Reference) (*record.Reference, error) {
+	k := prefixkey(scopeIDResult, requestRef.Key())
+	buf, err := ll.ldb.Get(k, nil)
 	if err != nil {
-		return err
+		if err == leveldb.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
 	}
-	return ll.ldb.Put(k, encoded, nil)
+	return refFromKey(buf)
 }
 
 // Close terminates db connection
@@ -230,9 +275,17 @@ func (ll *LevelLedger) Close() error {
 	return ll.ldb.Close()
 }
 
-// DropDB erases all data from storage.
+// Drop closes storage and erases all its data.
+func (ll *LevelLedger) Drop() error {
+	if err := ll.ldb.Close(); err != nil && err != leveldb.ErrClosed {
+		return err
+	}
+	return os.RemoveAll(ll.dataDir)
+}
+
+// DropDB erases all data from storage in default data directory.
 func DropDB() error {
-	absPath, err := filepath.Abs(dbDirPath)
+	absPath, err := filepath.Abs(defaultDataDirectory)
 	if err != nil {
 		return err
 	}
@@ -20,14 +20,21 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
 
 	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/pulse"
 	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+	"github.com/insolar/insolar/ledger/storage/storagetest"
 )
 
 func TestMain(m *testing.M) {
@@ -38,6 +45,124 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+func tmpConfig(t *testing.T) Config {
+	dir, err := ioutil.TempDir("", "levelledger")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	cfg := DefaultConfig()
+	cfg.DataDirectory = dir
+	return cfg
+}
+
+func tmpLedger(t *testing.T) *LevelLedger {
+	ledger, err := NewLevelLedger(tmpConfig(t))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return ledger
+}
+
+func TestLevelLedger_Conformance(t *testing.T) {
+	storagetest.TestLedgerStorer(t, func(t *testing.T) (storage.LedgerStorer, func()) {
+		ledger := tmpLedger(t)
+		return ledger, func() {
+			assert.NoError(t, ledger.Drop())
+		}
+	})
+}
+
+func TestNewLevelLedger_UsesSeparateDirectories(t *testing.T) {
+	ledger1 := tmpLedger(t)
+	defer ledger1.Drop()
+	ledger2 := tmpLedger(t)
+	defer ledger2.Drop()
+
+	ref, err := ledger1.SetRecord(&record.LockUnlockRequest{})
+	assert.NoError(t, err)
+	_, err = ledger1.GetRecord(ref)
+	assert.NoError(t, err)
+	_, err = ledger2.GetRecord(ref)
+	assert.Equal(t, ErrNotFound, err)
+}
+
+func TestNewLevelLedger_ReadOnly(t *testing.T) {
+	cfg := tmpConfig(t)
+	cfg.Sync = SyncAlways
+	ledger, err := NewLevelLedger(cfg)
+	assert.NoError(t, err)
+	rec := &record.LockUnlockRequest{}
+	ref, err := ledger.SetRecord(rec)
+	assert.NoError(t, err)
+	assert.NoError(t, ledger.Close())
+
+	cfg.ReadOnly = true
+	roLedger, err := NewLevelLedger(cfg)
+	assert.NoError(t, err)
+	defer roLedger.Drop()
+
+	gotRec, err := roLedger.GetRecord(ref)
+	assert.NoError(t, err)
+	assert.Equal(t, rec, gotRec)
+	_, err = roLedger.SetRecord(&record.CallRequest{})
+	assert.Error(t, err)
+	err = roLedger.SetObjectIndex(ref, &index.ObjectLifeline{})
+	assert.Error(t, err)
+}
+
+func TestNewLevelLedger_ReadOnlyRequiresExistingStorage(t *testing.T) {
+	cfg := tmpConfig(t)
+	cfg.DataDirectory = filepath.Join(cfg.DataDirectory, "missing")
+	cfg.ReadOnly = true
+	_, err := NewLevelLedger(cfg)
+	assert.Error(t, err)
+}
+
+func TestLevelLedger_PersistsLastPulse(t *testing.T) {
+	cfg := tmpConfig(t)
+	ledger, err := NewLevelLedger(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, record.PulseNum(0), ledger.LastPulse())
+
+	provider := pulse.NewManual(10)
+	ledger.SetPulseProvider(provider)
+	ref, err := ledger.SetRecord(&record.LockUnlockRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, record.PulseNum(10), ref.Record.Pulse)
+	assert.Equal(t, record.PulseNum(10), ledger.LastPulse())
+
+	// index writes and records from older pulses should not change persisted pulse
+	provider.Set(7)
+	_, err = ledger.SetRecord(&record.CallRequest{})
+	assert.NoError(t, err)
+	provider.Set(20)
+	err = ledger.SetObjectIndex(ref, &index.ObjectLifeline{})
+	assert.NoError(t, err)
+	assert.Equal(t, record.PulseNum(10), ledger.LastPulse())
+	assert.NoError(t, ledger.Close())
+
+	ledger, err = NewLevelLedger(cfg)
+	assert.NoError(t, err)
+	defer ledger.Drop()
+	assert.Equal(t, record.PulseNum(10), ledger.LastPulse())
+	// default provider continues from persisted pulse
+	ref, err = ledger.SetRecord(&record.LockUnlockRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, record.PulseNum(10), ref.Record.Pulse)
+}
+
+func TestLevelLedger_Drop(t *testing.T) {
+	cfg := tmpConfig(t)
+	cfg.NoCompression = true
+	cfg.Sync = SyncNever
+	ledger, err := NewLevelLedger(cfg)
+	assert.NoError(t, err)
+
+	assert.NoError(t, ledger.Drop())
+	_, err = os.Stat(cfg.DataDirectory)
+	assert.True(t, os.IsNotExist(err))
+}
+
 func setRawRecord(ll *LevelLedger, ref *record.Reference, raw *record.Raw) error {
 	k := prefixkey(scopeIDRecord, ref.Key())
 	return ll.ldb.Put(k, record.MustEncodeRaw(raw), nil)
@@ -159,7 +284,7 @@ func TestSetRecord(t *testing.T) {
 	assert.Nil(t, err)
 	// mock pulse source
 	pulse1 := record.PulseNum(1)
-	ledger.pulseFn = func() record.PulseNum { return pulse1 }
+	ledger.SetPulseProvider(pulse.NewManual(pulse1))
 	defer ledger.Close()
 
 	passRecPulse1 := &record.LockUnlockRequest{}
@@ -191,18 +316,67 @@ func TestSetRecord(t *testing.T) {
 	assert.NotEqual(t, idPulse1Hex, idPulse0Hex, "got hash")
 }
 
-// TODO: uncomment when record storage is functional
-// func TestCreatesRootRecord(t *testing.T) {
-// 	ledger, err := InitDB()
-// 	assert.Nil(t, err)
-// 	defer ledger.Close()
-//
-// 	var zeroID record.ID
-// 	copy([]byte(zeroRecordBinary)[:record.IDSize], zeroID[:])
-// 	zeroRef, ok := ledger.GetRecord(record.ID2Key(zeroID))
-// 	assert.True(t, ok)
-// 	assert.Equal(t, ledger.zeroRef, zeroRef)
-// }
+func TestCreatesRootRecord(t *testing.T) {
+	ledger, err := InitDB()
+	assert.Nil(t, err)
+	defer ledger.Close()
+
+	genesisRef := storage.GenesisReference()
+	rec, err := ledger.GetRecord(&genesisRef)
+	assert.NoError(t, err)
+	assert.Equal(t, storage.GenesisRecord(), rec)
+	idx, err := ledger.GetClassIndex(&genesisRef)
+	assert.NoError(t, err)
+	assert.Equal(t, storage.GenesisIndex(), idx)
+}
+
+func TestNewLevelLedger_RefusesWrongGenesis(t *testing.T) {
+	cfg := tmpConfig(t)
+	defer os.RemoveAll(cfg.DataDirectory)
+	ledger, err := NewLevelLedger(cfg)
+	assert.NoError(t, err)
+	genesisRef := storage.GenesisReference()
+	err = ledger.ldb.Put(prefixkey(scopeIDRecord, genesisRef.Key()), []byte{1, 2, 3}, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, ledger.Close())
+
+	_, err = NewLevelLedger(cfg)
+	assert.Equal(t, storage.ErrGenesisMismatch, errors.Cause(err))
+	cfg.ReadOnly = true
+	_, err = NewLevelLedger(cfg)
+	assert.Equal(t, storage.ErrGenesisMismatch, errors.Cause(err))
+}
+
+func TestNewLevelLedger_AcceptsReencodedGenesis(t *testing.T) {
+	cfg := tmpConfig(t)
+	defer os.RemoveAll(cfg.DataDirectory)
+	ledger, err := NewLevelLedger(cfg)
+	assert.NoError(t, err)
+	// Signature changes raw record encoding, but not its hash.
+	genesisRef := storage.GenesisReference()
+	raw, err := record.EncodeToRaw(storage.GenesisRecord())
+	assert.NoError(t, err)
+	raw.Signature = &record.Signature{KeyID: []byte{1}, Sig: []byte{2}}
+	err = ledger.ldb.Put(prefixkey(scopeIDRecord, genesisRef.Key()), record.MustEncodeRaw(raw), nil)
+	assert.NoError(t, err)
+	assert.NoError(t, ledger.Close())
+
+	ledger, err = NewLevelLedger(cfg)
+	assert.NoError(t, err)
+	assert.NoError(t, ledger.Close())
+}
+
+func TestNewLevelLedger_RefusesStorageWithoutGenesis(t *testing.T) {
+	cfg := tmpConfig(t)
+	defer os.RemoveAll(cfg.DataDirectory)
+	db, err := leveldb.OpenFile(cfg.DataDirectory, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Put([]byte("key"), []byte("value"), nil))
+	assert.NoError(t, db.Close())
+
+	_, err = NewLevelLedger(cfg)
+	assert.Equal(t, storage.ErrGenesisMismatch, errors.Cause(err))
+}
 
 func TestGetClassIndexOnEmptyDataReturnsNotFound(t *testing.T) {
 	ledger, err := InitDB()
@@ -285,3 +459,20 @@ func TestSetObjectIndexStoresDataInDB(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, *storedIndex, idx)
 }
+
+func TestLevelLedger_ReportsCorruptedData(t *testing.T) {
+	ledger := tmpLedger(t)
+	defer ledger.Drop()
+
+	ref := referenceWithHashes("10", "20")
+	garbage := []byte{0xff, 0x00}
+	assert.NoError(t, ledger.ldb.Put(prefixkey(scopeIDRecord, ref.Key()), garbage, nil))
+	assert.NoError(t, ledger.ldb.Put(prefixkey(scopeIDLifeline, ref.Key()), garbage, nil))
+
+	_, err := ledger.GetRecord(&ref)
+	assert.Equal(t, storage.ErrCorrupted, errors.Cause(err))
+	_, err = ledger.GetClassIndex(&ref)
+	assert.Equal(t, storage.ErrCorrupted, errors.Cause(err))
+	_, err = ledger.GetObjectIndex(&ref)
+	assert.Equal(t, storage.ErrCorrupted, errors.Cause(err))
+}
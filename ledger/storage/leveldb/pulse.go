@@ -0,0 +1,94 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package leveldb
+
+import (
+	"encoding/binary"
+
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/insolar/insolar/ledger/pulse"
+	"github.com/insolar/insolar/ledger/record"
+)
+
+const metaKeyPulse = "pulse"
+
+func metakey(name string) []byte {
+	return append([]byte{scopeIDMeta}, name...)
+}
+
+func encodePulse(pn record.PulseNum) []byte {
+	b := make([]byte, record.PulseNumSize)
+	binary.BigEndian.PutUint32(b, uint32(pn))
+	return b
+}
+
+func loadLastPulse(db *leveldb.DB) (record.PulseNum, error) {
+	buf, err := db.Get(metakey(metaKeyPulse), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return record.PulseNum(binary.BigEndian.Uint32(buf)), nil
+}
+
+// SetPulseProvider sets pulse source used to stamp new records.
+func (ll *LevelLedger) SetPulseProvider(p pulse.Provider) {
+	ll.pulseLock.Lock()
+	defer ll.pulseLock.Unlock()
+	ll.pulseProvider = p
+}
+
+// LastPulse returns the newest pulse number of stored records. It is persisted and survives storage restart.
+func (ll *LevelLedger) LastPulse() record.PulseNum {
+	ll.pulseLock.RLock()
+	defer ll.pulseLock.RUnlock()
+	return ll.lastPulse
+}
+
+func (ll *LevelLedger) currentPulse() record.PulseNum {
+	ll.pulseLock.RLock()
+	defer ll.pulseLock.RUnlock()
+	return ll.pulseProvider.Current()
+}
+
+func (ll *LevelLedger) updateLastPulse(pn record.PulseNum) {
+	ll.pulseLock.Lock()
+	defer ll.pulseLock.Unlock()
+	if pn > ll.lastPulse {
+		ll.lastPulse = pn
+	}
+}
+
+// GetPulseRoot returns stored Merkle root of the pulse.
+func (ll *LevelLedger) GetPulseRoot(pn record.PulseNum) ([]byte, error) {
+	buf, err := ll.ldb.Get(append([]byte{scopeIDPulseRoot}, encodePulse(pn)...), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// SetPulseRoot stores Merkle root of the pulse.
+func (ll *LevelLedger) SetPulseRoot(pn record.PulseNum, root []byte) error {
+	return ll.ldb.Put(append([]byte{scopeIDPulseRoot}, encodePulse(pn)...), root, ll.writeOpts)
+}
@@ -0,0 +1,204 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package memory
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// memBatch implements storage.RawBatch by collecting writes in separate maps.
+type memBatch struct {
+	ml      *MemLedger
+	records map[string][]byte
+	// lifelines contains nil values for removed lifelines.
+	lifelines map[string][]byte
+	results   map[string][]byte
+	pulse     record.PulseNum
+	// checks are expected encoded object indexes by lifeline key.
+	checks map[string][]byte
+
+	blobChanges map[string]*blobChange
+	// rawBlobs contains nil values for removed blobs.
+	rawBlobs map[string]*memBlob
+
+	// history contains encoded references of records appended to lifeline histories by head key.
+	history map[string][][]byte
+	// rawHistory contains replaced lifeline histories, nil values for removed ones.
+	rawHistory map[string][][]byte
+}
+
+// SetRecord adds record to batch.
+func (b *memBatch) SetRecord(rec record.Record) (*record.Reference, error) {
+	ref, k, buf, err := b.ml.recordKV(rec, b.pulse)
+	if err != nil {
+		return nil, err
+	}
+	b.records[k] = buf
+	return ref, nil
+}
+
+// SetRawRecord adds serialized record to batch.
+func (b *memBatch) SetRawRecord(ref *record.Reference, raw *record.Raw) error {
+	if !ref.Record.Pulse.IsValid() {
+		return errors.Wrapf(record.ErrReservedPulse, "can't store record at pulse %d", ref.Record.Pulse)
+	}
+	if err := b.ml.CheckSignature(ref, raw); err != nil {
+		return err
+	}
+	buf, err := record.EncodeRaw(raw)
+	if err != nil {
+		return err
+	}
+	b.records[string(ref.Key())] = buf
+	return nil
+}
+
+// SetClassIndex adds lifeline index to batch.
+func (b *memBatch) SetClassIndex(ref *record.Reference, idx *index.ClassLifeline) error {
+	encoded, err := index.EncodeClassLifeline(idx)
+	if err != nil {
+		return err
+	}
+	b.lifelines[string(ref.Key())] = encoded
+	return nil
+}
+
+// SetObjectIndex adds lifeline index to batch.
+func (b *memBatch) SetObjectIndex(ref *record.Reference, idx *index.ObjectLifeline) error {
+	encoded, err := index.EncodeObjectLifeline(idx)
+	if err != nil {
+		return err
+	}
+	b.lifelines[string(ref.Key())] = encoded
+	return nil
+}
+
+// DeleteLifeline adds lifeline index removal to batch.
+func (b *memBatch) DeleteLifeline(ref *record.Reference) error {
+	b.lifelines[string(ref.Key())] = nil
+	return nil
+}
+
+// SetRequestResult adds request result reference to batch.
+func (b *memBatch) SetRequestResult(requestRef, resultRef *record.Reference) error {
+	k := string(requestRef.Key())
+	if _, ok := b.results[k]; ok {
+		return storage.ErrRequestHasResult
+	}
+	b.results[k] = resultRef.Key()
+	return nil
+}
+
+// CheckObjectIndex adds object index precondition to batch.
+func (b *memBatch) CheckObjectIndex(ref *record.Reference, idx *index.ObjectLifeline) error {
+	encoded, err := index.EncodeObjectLifeline(idx)
+	if err != nil {
+		return err
+	}
+	b.checks[string(ref.Key())] = encoded
+	return nil
+}
+
+// checkIndexes checks object index preconditions. It must be called under write lock.
+func (b *memBatch) checkIndexes() error {
+	for k, expected := range b.checks {
+		if err := storage.CompareObjectIndex(b.ml.lifelines[k], expected); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkResults checks that batch does not replace stored request results. It must be called under write lock.
+func (b *memBatch) checkResults() error {
+	for k := range b.results {
+		if _, ok := b.ml.results[k]; ok {
+			return storage.ErrRequestHasResult
+		}
+	}
+	return nil
+}
+
+// Update collects writes made by fn and applies them under single lock.
+func (ml *MemLedger) Update(fn func(storage.Batch) error) error {
+	return ml.update(func(b *memBatch) error {
+		return fn(b)
+	})
+}
+
+// UpdateRaw works as Update, but provided batch also accepts serialized records.
+func (ml *MemLedger) UpdateRaw(fn func(storage.RawBatch) error) error {
+	return ml.update(func(b *memBatch) error {
+		return fn(b)
+	})
+}
+
+func (ml *MemLedger) update(fn func(*memBatch) error) error {
+	b := &memBatch{
+		ml:        ml,
+		records:   map[string][]byte{},
+		lifelines: map[string][]byte{},
+		results:   map[string][]byte{},
+		checks:    map[string][]byte{},
+		pulse:     ml.currentPulse(),
+
+		blobChanges: map[string]*blobChange{},
+		rawBlobs:    map[string]*memBlob{},
+
+		history:    map[string][][]byte{},
+		rawHistory: map[string][][]byte{},
+	}
+	if err := fn(b); err != nil {
+		return err
+	}
+
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	if err := b.checkIndexes(); err != nil {
+		return err
+	}
+	if err := b.checkResults(); err != nil {
+		return err
+	}
+	blobs := b.resolveBlobs()
+	for k, v := range b.records {
+		ml.records[k] = v
+	}
+	for k, v := range b.lifelines {
+		if v == nil {
+			delete(ml.lifelines, k)
+			continue
+		}
+		ml.lifelines[k] = v
+	}
+	for k, v := range b.results {
+		ml.results[k] = v
+	}
+	b.applyHistory()
+	for k, v := range blobs {
+		if v == nil {
+			delete(ml.blobs, k)
+			continue
+		}
+		ml.blobs[k] = v
+	}
+	return nil
+}
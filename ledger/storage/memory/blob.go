@@ -0,0 +1,133 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package memory
+
+import (
+	"sort"
+
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// memBlob is a stored blob with its reference count.
+type memBlob struct {
+	data []byte
+	refs uint64
+}
+
+// GetBlob returns blob stored under provided content hash.
+func (ml *MemLedger) GetBlob(hash []byte) ([]byte, error) {
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+	blob, ok := ml.blobs[string(hash)]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return append([]byte(nil), blob.data...), nil
+}
+
+// GetBlobRefs returns number of references to the blob.
+func (ml *MemLedger) GetBlobRefs(hash []byte) (uint64, error) {
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+	blob, ok := ml.blobs[string(hash)]
+	if !ok {
+		return 0, storage.ErrNotFound
+	}
+	return blob.refs, nil
+}
+
+// IterateBlobs calls provided function for every stored blob with its reference count. Blobs are visited in hash
+// order.
+func (ml *MemLedger) IterateBlobs(fn func(hash, data []byte, refs uint64) error) error {
+	ml.lock.RLock()
+	keys := make([]string, 0, len(ml.blobs))
+	for k := range ml.blobs {
+		keys = append(keys, k)
+	}
+	ml.lock.RUnlock()
+	sort.Strings(keys)
+	for _, k := range keys {
+		ml.lock.RLock()
+		blob, ok := ml.blobs[k]
+		ml.lock.RUnlock()
+		if !ok {
+			continue
+		}
+		err := fn([]byte(k), append([]byte(nil), blob.data...), blob.refs)
+		if err == storage.ErrStopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// blobChange is a pending change of blob reference count.
+type blobChange struct {
+	data []byte
+	refs uint64 // references added by batch
+}
+
+func (b *memBatch) blobChange(hash []byte) *blobChange {
+	c, ok := b.blobChanges[string(hash)]
+	if !ok {
+		c = &blobChange{}
+		b.blobChanges[string(hash)] = c
+	}
+	return c
+}
+
+// SetBlob adds blob reference to batch.
+func (b *memBatch) SetBlob(data []byte) ([]byte, error) {
+	hash := record.BlobHash(data)
+	c := b.blobChange(hash)
+	c.data = append([]byte(nil), data...)
+	c.refs++
+	return hash, nil
+}
+
+// SetRawBlob adds blob with exact reference count to batch.
+func (b *memBatch) SetRawBlob(hash, data []byte, refs uint64) error {
+	if refs == 0 {
+		b.rawBlobs[string(hash)] = nil
+		return nil
+	}
+	b.rawBlobs[string(hash)] = &memBlob{data: append([]byte(nil), data...), refs: refs}
+	return nil
+}
+
+// resolveBlobs turns pending reference count changes into blobs to store. Nil blobs are to be removed. It must be
+// called under write lock.
+func (b *memBatch) resolveBlobs() map[string]*memBlob {
+	resolved := map[string]*memBlob{}
+	for k, v := range b.rawBlobs {
+		resolved[k] = v
+	}
+	for k, c := range b.blobChanges {
+		var refs uint64
+		data := c.data
+		if blob, ok := b.ml.blobs[k]; ok {
+			refs = blob.refs
+			data = blob.data
+		}
+		resolved[k] = &memBlob{data: data, refs: refs + c.refs}
+	}
+	return resolved
+}
@@ -0,0 +1,88 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package memory
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// IterateHistory calls provided function for every record in lifeline history of the head in order of appending.
+func (ml *MemLedger) IterateHistory(headRef *record.Reference, fn func(*record.Reference) error) error {
+	ml.lock.RLock()
+	history := append([][]byte(nil), ml.history[string(headRef.Key())]...)
+	ml.lock.RUnlock()
+	for _, buf := range history {
+		ref, err := index.DecodeHistoryRef(buf)
+		if err != nil {
+			return errors.Wrapf(storage.ErrCorrupted, "failed to decode history reference: %v", err)
+		}
+		err = fn(ref)
+		if err == storage.ErrStopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendHistory adds record to pending lifeline history of the head.
+func (b *memBatch) AppendHistory(headRef, ref *record.Reference) error {
+	buf, err := index.EncodeHistoryRef(ref)
+	if err != nil {
+		return err
+	}
+	k := string(headRef.Key())
+	b.history[k] = append(b.history[k], buf)
+	return nil
+}
+
+// SetHistory adds replacement of lifeline history of the head to batch.
+func (b *memBatch) SetHistory(headRef *record.Reference, refs []record.Reference) error {
+	var history [][]byte
+	for i := range refs {
+		buf, err := index.EncodeHistoryRef(&refs[i])
+		if err != nil {
+			return err
+		}
+		history = append(history, buf)
+	}
+	k := string(headRef.Key())
+	b.rawHistory[k] = history
+	delete(b.history, k)
+	return nil
+}
+
+// applyHistory writes replaced histories and appends pending records to stored histories. It must be called under
+// write lock.
+func (b *memBatch) applyHistory() {
+	for k, history := range b.rawHistory {
+		if history == nil {
+			delete(b.ml.history, k)
+			continue
+		}
+		b.ml.history[k] = history
+	}
+	for k, history := range b.history {
+		b.ml.history[k] = append(b.ml.history[k], history...)
+	}
+}
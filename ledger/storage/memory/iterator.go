@@ -0,0 +1,192 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package memory
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+type iteratedRecord struct {
+	ref *record.Reference
+	raw *record.Raw
+}
+
+func refFromKey(k string) (*record.Reference, error) {
+	ref, n, err := record.ParseRefKey([]byte(k))
+	if err != nil {
+		return nil, errors.Wrapf(storage.ErrCorrupted, "malformed reference key: %v", err)
+	}
+	if n != len(k) {
+		return nil, errors.Wrap(storage.ErrCorrupted, "malformed reference key: trailing bytes")
+	}
+	return ref, nil
+}
+
+// sortedKeys returns snapshot of map keys in the same order as persistent storages keep them.
+func (ml *MemLedger) sortedKeys(m map[string][]byte) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// less orders records by pulse and then by reference key.
+func less(a, b *record.Reference) bool {
+	if a.Record.Pulse != b.Record.Pulse {
+		return a.Record.Pulse < b.Record.Pulse
+	}
+	return bytes.Compare(a.Key(), b.Key()) < 0
+}
+
+// IterateRecords iterates over records matching the query.
+//
+// All stored records are scanned and sorted on every call, so it is not intended for large data sets.
+func (ml *MemLedger) IterateRecords(
+	q storage.RecordQuery, fn func(*record.Reference, record.Record) error,
+) error {
+	var matched []iteratedRecord
+	ml.lock.RLock()
+	for k, buf := range ml.records {
+		ref, err := refFromKey(k)
+		if err != nil {
+			ml.lock.RUnlock()
+			return err
+		}
+		if q.After != nil && !less(q.After, ref) {
+			continue
+		}
+		raw, err := record.DecodeToRaw(buf)
+		if err != nil {
+			ml.lock.RUnlock()
+			return errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
+		}
+		if q.Matches(ref, raw.Type) {
+			matched = append(matched, iteratedRecord{ref: ref, raw: raw})
+		}
+	}
+	ml.lock.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return less(matched[i].ref, matched[j].ref)
+	})
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+	for _, r := range matched {
+		rec, err := r.raw.Decode()
+		if err != nil {
+			return errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
+		}
+		err = fn(r.ref, rec)
+		if err == storage.ErrStopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IterateRawRecords calls provided function for every stored record in its serialized form. Records are visited in
+// order of their reference keys.
+func (ml *MemLedger) IterateRawRecords(fn func(*record.Reference, *record.Raw) error) error {
+	ml.lock.RLock()
+	keys := ml.sortedKeys(ml.records)
+	ml.lock.RUnlock()
+	for _, k := range keys {
+		ml.lock.RLock()
+		buf, ok := ml.records[k]
+		ml.lock.RUnlock()
+		if !ok {
+			continue
+		}
+		raw, err := record.DecodeToRaw(buf)
+		if err != nil {
+			return errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
+		}
+		ref, err := refFromKey(k)
+		if err != nil {
+			return err
+		}
+		err = fn(ref, raw)
+		if err == storage.ErrStopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IterateLifelineRefs calls provided function for reference of every stored lifeline index.
+func (ml *MemLedger) IterateLifelineRefs(fn func(*record.Reference) error) error {
+	ml.lock.RLock()
+	keys := ml.sortedKeys(ml.lifelines)
+	ml.lock.RUnlock()
+	for _, k := range keys {
+		ref, err := refFromKey(k)
+		if err != nil {
+			return err
+		}
+		err = fn(ref)
+		if err == storage.ErrStopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// IterateRequestResults calls provided function for every request which has a result.
+func (ml *MemLedger) IterateRequestResults(fn func(requestRef, resultRef *record.Reference) error) error {
+	ml.lock.RLock()
+	keys := ml.sortedKeys(ml.results)
+	ml.lock.RUnlock()
+	for _, k := range keys {
+		ml.lock.RLock()
+		v := ml.results[k]
+		ml.lock.RUnlock()
+		requestRef, err := refFromKey(k)
+		if err != nil {
+			return err
+		}
+		resultRef, err := refFromKey(string(v))
+		if err != nil {
+			return err
+		}
+		err = fn(requestRef, resultRef)
+		if err == storage.ErrStopIteration {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
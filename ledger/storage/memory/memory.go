@@ -0,0 +1,285 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package memory
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/hash"
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/pulse"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// MemLedger represents ledger's in-memory storage.
+//
+// Records and indexes are stored in their serialized form, so MemLedger behaves exactly as persistent storages do:
+// every getter returns a fresh copy of stored data.
+type MemLedger struct {
+	lock          sync.RWMutex
+	records       map[string][]byte
+	lifelines     map[string][]byte
+	results       map[string][]byte
+	blobs         map[string]*memBlob
+	history       map[string][][]byte
+	roots         map[record.PulseNum][]byte
+	pulseProvider pulse.Provider
+	hashAlgorithm hash.Algorithm
+
+	storage.SignaturePolicy
+}
+
+// NewMemLedger creates in-memory ledger storage containing only genesis record. Records are stamped with zero pulse
+// until another pulse provider is set by SetPulseProvider.
+func NewMemLedger() *MemLedger {
+	ml := &MemLedger{
+		records:       map[string][]byte{},
+		lifelines:     map[string][]byte{},
+		results:       map[string][]byte{},
+		blobs:         map[string]*memBlob{},
+		history:       map[string][][]byte{},
+		roots:         map[record.PulseNum][]byte{},
+		pulseProvider: pulse.NewManual(0),
+	}
+	genesisRef := storage.GenesisReference()
+	raw, err := record.EncodeToRaw(storage.GenesisRecord())
+	if err != nil {
+		panic(err)
+	}
+	encodedIndex, err := index.EncodeClassLifeline(storage.GenesisIndex())
+	if err != nil {
+		panic(err)
+	}
+	ml.records[string(genesisRef.Key())] = record.MustEncodeRaw(raw)
+	ml.lifelines[string(genesisRef.Key())] = encodedIndex
+	return ml
+}
+
+// SetPulseProvider sets pulse source used to stamp new records.
+func (ml *MemLedger) SetPulseProvider(p pulse.Provider) {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	ml.pulseProvider = p
+}
+
+// SetHashAlgorithm sets hash algorithm used to identify new records. Records stored with other algorithms remain
+// readable. Default algorithm is SHA3-224.
+func (ml *MemLedger) SetHashAlgorithm(alg hash.Algorithm) error {
+	if !alg.IsValid() {
+		return errors.Errorf("unsupported hash algorithm %d", byte(alg))
+	}
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	ml.hashAlgorithm = alg
+	return nil
+}
+
+func (ml *MemLedger) currentHashAlgorithm() hash.Algorithm {
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+	return ml.hashAlgorithm
+}
+
+func (ml *MemLedger) currentPulse() record.PulseNum {
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+	return ml.pulseProvider.Current()
+}
+
+// GetPulseRoot returns stored Merkle root of the pulse.
+func (ml *MemLedger) GetPulseRoot(pn record.PulseNum) ([]byte, error) {
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+	root, ok := ml.roots[pn]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return append([]byte(nil), root...), nil
+}
+
+// SetPulseRoot stores Merkle root of the pulse.
+func (ml *MemLedger) SetPulseRoot(pn record.PulseNum, root []byte) error {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	ml.roots[pn] = append([]byte(nil), root...)
+	return nil
+}
+
+// GetRecord returns record from memory by *record.Reference.
+//
+// It returns storage.ErrNotFound if the storage does not contain the key.
+func (ml *MemLedger) GetRecord(ref *record.Reference) (record.Record, error) {
+	raw, err := ml.GetRawRecord(ref)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := raw.Decode()
+	if err != nil {
+		return nil, errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
+	}
+	return rec, nil
+}
+
+// GetRecordSignature returns signature of stored record. It returns nil signature for unsigned records and
+// storage.ErrNotFound if there is no such record.
+func (ml *MemLedger) GetRecordSignature(ref *record.Reference) (*record.Signature, error) {
+	raw, err := ml.GetRawRecord(ref)
+	if err != nil {
+		return nil, err
+	}
+	return raw.Signature, nil
+}
+
+// GetRawRecord returns stored record in serialized form.
+func (ml *MemLedger) GetRawRecord(ref *record.Reference) (*record.Raw, error) {
+	ml.lock.RLock()
+	buf, ok := ml.records[string(ref.Key())]
+	ml.lock.RUnlock()
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	raw, err := record.DecodeToRaw(buf)
+	if err != nil {
+		return nil, errors.Wrapf(storage.ErrCorrupted, "failed to decode record: %v", err)
+	}
+	return raw, nil
+}
+
+func (ml *MemLedger) recordKV(rec record.Record, pn record.PulseNum) (*record.Reference, string, []byte, error) {
+	if !pn.IsValid() {
+		return nil, "", nil, errors.Wrapf(record.ErrReservedPulse, "can't store record at pulse %d", pn)
+	}
+	raw, err := record.EncodeToRaw(rec)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	alg := ml.currentHashAlgorithm()
+	ref := &record.Reference{
+		Domain: rec.Domain(),
+		Record: record.ID{Pulse: pn, Hash: raw.Sum(alg), Algorithm: alg},
+	}
+	if err := ml.SignRecord(ref, raw); err != nil {
+		return nil, "", nil, err
+	}
+	buf, err := record.EncodeRaw(raw)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return ref, string(ref.Key()), buf, nil
+}
+
+// SetRecord stores record in memory. Record is stamped with current pulse of ledger's pulse provider.
+func (ml *MemLedger) SetRecord(rec record.Record) (*record.Reference, error) {
+	ref, k, buf, err := ml.recordKV(rec, ml.currentPulse())
+	if err != nil {
+		return nil, err
+	}
+
+	ml.lock.Lock()
+	ml.records[k] = buf
+	ml.lock.Unlock()
+	return ref, nil
+}
+
+func (ml *MemLedger) getLifeline(ref *record.Reference) ([]byte, error) {
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+	buf, ok := ml.lifelines[string(ref.Key())]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return buf, nil
+}
+
+func (ml *MemLedger) setLifeline(ref *record.Reference, buf []byte) {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	ml.lifelines[string(ref.Key())] = buf
+}
+
+// GetClassIndex fetches lifeline index from memory.
+func (ml *MemLedger) GetClassIndex(ref *record.Reference) (*index.ClassLifeline, error) {
+	buf, err := ml.getLifeline(ref)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := index.DecodeClassLifeline(buf)
+	if err != nil {
+		return nil, errors.Wrapf(storage.ErrCorrupted, "failed to decode class index: %v", err)
+	}
+	return idx, nil
+}
+
+// SetClassIndex stores lifeline index in memory.
+func (ml *MemLedger) SetClassIndex(ref *record.Reference, idx *index.ClassLifeline) error {
+	encoded, err := index.EncodeClassLifeline(idx)
+	if err != nil {
+		return err
+	}
+	ml.setLifeline(ref, encoded)
+	return nil
+}
+
+// GetObjectIndex fetches lifeline index from memory.
+func (ml *MemLedger) GetObjectIndex(ref *record.Reference) (*index.ObjectLifeline, error) {
+	buf, err := ml.getLifeline(ref)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := index.DecodeObjectLifeline(buf)
+	if err != nil {
+		return nil, errors.Wrapf(storage.ErrCorrupted, "failed to decode object index: %v", err)
+	}
+	return idx, nil
+}
+
+// SetObjectIndex stores lifeline index in memory.
+func (ml *MemLedger) SetObjectIndex(ref *record.Reference, idx *index.ObjectLifeline) error {
+	encoded, err := index.EncodeObjectLifeline(idx)
+	if err != nil {
+		return err
+	}
+	ml.setLifeline(ref, encoded)
+	return nil
+}
+
+// GetRequestResult fetches reference of the result record produced by provided request.
+func (ml *MemLedger) GetRequestResult(requestRef *record.Reference) (*record.Reference, error) {
+	ml.lock.RLock()
+	defer ml.lock.RUnlock()
+	k, ok := ml.results[string(requestRef.Key())]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return refFromKey(string(k))
+}
+
+// Close releases all stored data. It is safe to call Close multiple times.
+func (ml *MemLedger) Close() error {
+	ml.lock.Lock()
+	defer ml.lock.Unlock()
+	ml.records = map[string][]byte{}
+	ml.lifelines = map[string][]byte{}
+	ml.results = map[string][]byte{}
+	ml.blobs = map[string]*memBlob{}
+	ml.history = map[string][][]byte{}
+	ml.roots = map[record.PulseNum][]byte{}
+	return nil
+}
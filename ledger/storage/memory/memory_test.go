@@ -0,0 +1,71 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package memory
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/pulse"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+	"github.com/insolar/insolar/ledger/storage/storagetest"
+)
+
+func TestMemLedger_Conformance(t *testing.T) {
+	storagetest.TestLedgerStorer(t, func(t *testing.T) (storage.LedgerStorer, func()) {
+		ledger := NewMemLedger()
+		return ledger, func() {
+			assert.NoError(t, ledger.Close())
+		}
+	})
+}
+
+func TestMemLedger_SetRecordUsesPulse(t *testing.T) {
+	ledger := NewMemLedger()
+	pn := record.PulseNum(42)
+	ledger.SetPulseProvider(pulse.NewManual(pn))
+
+	rec := &record.LockUnlockRequest{}
+	ref, err := ledger.SetRecord(rec)
+	assert.NoError(t, err)
+	assert.Equal(t, pn.ID(rec), ref.Record)
+}
+
+func TestMemLedger_CloseDropsData(t *testing.T) {
+	ledger := NewMemLedger()
+	ref, err := ledger.SetRecord(&record.LockUnlockRequest{})
+	assert.NoError(t, err)
+	var blobHash []byte
+	err = ledger.Update(func(batch storage.Batch) error {
+		blobHash, err = batch.SetBlob([]byte{1})
+		return err
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, ledger.SetPulseRoot(1, []byte{1}))
+
+	assert.NoError(t, ledger.Close())
+	rec, err := ledger.GetRecord(ref)
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Nil(t, rec)
+	_, err = ledger.GetBlob(blobHash)
+	assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+	_, err = ledger.GetPulseRoot(1)
+	assert.Equal(t, storage.ErrNotFound, errors.Cause(err))
+}
@@ -0,0 +1,25 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package merkle maintains Merkle roots of ledger pulses and proves that a record was written in a given pulse.
+//
+// When a pulse is closed, Merkle tree is built over keys of all records of the pulse ordered as storage iterates them,
+// and its root is persisted by storage. Inclusion proof of a record is a path from the record leaf to the pulse root,
+// so anyone who knows the root can check the proof without access to the ledger. Record keys contain hashes of record
+// contents, so the root commits to the contents of the pulse as well.
+//
+// Roots.Subscribe closes pulses as the network switches to new ones.
+package merkle
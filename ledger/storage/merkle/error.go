@@ -0,0 +1,33 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package merkle
+
+import "errors"
+
+var (
+	// ErrPulseNotClosed returns if pulse root is requested before the pulse was closed.
+	ErrPulseNotClosed = errors.New("pulse is not closed")
+
+	// ErrNotInPulse returns if record is not found among records of the pulse.
+	ErrNotInPulse = errors.New("record is not in pulse")
+
+	// ErrRootMismatch returns if records of the pulse were changed after the pulse was closed.
+	ErrRootMismatch = errors.New("pulse records do not match stored root")
+
+	// ErrInvalidProof returns if proof does not connect record with pulse root.
+	ErrInvalidProof = errors.New("invalid inclusion proof")
+)
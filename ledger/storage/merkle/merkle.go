@@ -0,0 +1,186 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package merkle
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/hash"
+	"github.com/insolar/insolar/ledger/pulse"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// Root is a Merkle root of closed pulse.
+type Root struct {
+	Pulse     record.PulseNum
+	Algorithm hash.Algorithm
+	Hash      []byte
+}
+
+// Proof proves that record was written in the pulse.
+type Proof struct {
+	Pulse     record.PulseNum
+	Algorithm hash.Algorithm
+	Path      hash.MerkleProof
+}
+
+// Verify checks that proof connects record reference with provided pulse root.
+func (p *Proof) Verify(ref *record.Reference, root *Root) error {
+	if ref.Record.Pulse != p.Pulse || root.Pulse != p.Pulse {
+		return errors.Wrap(ErrInvalidProof, "pulse mismatch")
+	}
+	if root.Algorithm != p.Algorithm {
+		return errors.Wrap(ErrInvalidProof, "hash algorithm mismatch")
+	}
+	if !p.Algorithm.IsValid() || !p.Algorithm.VerifyMerkleProof(root.Hash, ref.Key(), p.Path) {
+		return ErrInvalidProof
+	}
+	return nil
+}
+
+// Roots maintains Merkle roots of closed pulses in ledger storage.
+type Roots struct {
+	lock  sync.Mutex
+	it    storage.RecordIterator
+	store storage.PulseRootStorer
+	alg   hash.Algorithm
+}
+
+// NewRoots creates Roots for provided storage. Storage should implement storage.RecordIterator and
+// storage.PulseRootStorer. Trees of new roots are built with provided hash algorithm, roots stored with other
+// algorithms remain valid.
+func NewRoots(s storage.LedgerStorer, alg hash.Algorithm) (*Roots, error) {
+	it, ok := s.(storage.RecordIterator)
+	if !ok {
+		return nil, errors.New("storage does not implement RecordIterator")
+	}
+	store, ok := s.(storage.PulseRootStorer)
+	if !ok {
+		return nil, errors.New("storage does not implement PulseRootStorer")
+	}
+	if !alg.IsValid() {
+		return nil, errors.Errorf("unsupported hash algorithm %d", byte(alg))
+	}
+	return &Roots{it: it, store: store, alg: alg}, nil
+}
+
+// ClosePulse builds Merkle tree over records of the pulse and persists its root. Closing already closed pulse returns
+// the stored root.
+//
+// Pulse should be closed after ledger switched to the next pulse. Records written to the pulse after it was closed
+// are not covered by its root and break proofs of the pulse (see ErrRootMismatch).
+func (r *Roots) ClosePulse(pn record.PulseNum) (*Root, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	root, err := r.Root(pn)
+	if err != ErrPulseNotClosed {
+		return root, err
+	}
+	leaves, err := r.leaves(pn)
+	if err != nil {
+		return nil, err
+	}
+	root = &Root{Pulse: pn, Algorithm: r.alg, Hash: r.alg.MerkleRoot(leaves)}
+	if err := r.store.SetPulseRoot(pn, append([]byte{byte(root.Algorithm)}, root.Hash...)); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// Subscribe closes pulses on pulse switches of the network. When network switches to a new pulse, the previous current
+// pulse is closed. Errors of closing are passed to onError, which can be nil.
+func (r *Roots) Subscribe(n *pulse.Network, onError func(record.PulseNum, error)) {
+	var lock sync.Mutex
+	current := n.Current()
+	n.Subscribe(func(pn record.PulseNum) {
+		lock.Lock()
+		closed := current
+		current = pn
+		lock.Unlock()
+		if _, err := r.ClosePulse(closed); err != nil && onError != nil {
+			onError(closed, err)
+		}
+	})
+}
+
+// Root returns root of closed pulse. It returns ErrPulseNotClosed if pulse was not closed yet.
+func (r *Roots) Root(pn record.PulseNum) (*Root, error) {
+	buf, err := r.store.GetPulseRoot(pn)
+	if err == storage.ErrNotFound {
+		return nil, ErrPulseNotClosed
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(buf) == 0 {
+		return nil, errors.Wrap(storage.ErrCorrupted, "empty pulse root")
+	}
+	alg := hash.Algorithm(buf[0])
+	if !alg.IsValid() || len(buf)-1 != alg.Size() {
+		return nil, errors.Wrapf(storage.ErrCorrupted, "malformed root of pulse %d", pn)
+	}
+	return &Root{Pulse: pn, Algorithm: alg, Hash: buf[1:]}, nil
+}
+
+// Prove returns inclusion proof of the record in its pulse. Pulse of the record should be closed.
+func (r *Roots) Prove(ref *record.Reference) (*Proof, error) {
+	root, err := r.Root(ref.Record.Pulse)
+	if err != nil {
+		return nil, err
+	}
+	leaves, err := r.leaves(root.Pulse)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(root.Algorithm.MerkleRoot(leaves), root.Hash) {
+		return nil, ErrRootMismatch
+	}
+	key := ref.Key()
+	for i, leaf := range leaves {
+		if !bytes.Equal(leaf, key) {
+			continue
+		}
+		path, err := root.Algorithm.MerkleProof(leaves, i)
+		if err != nil {
+			return nil, err
+		}
+		return &Proof{Pulse: root.Pulse, Algorithm: root.Algorithm, Path: path}, nil
+	}
+	return nil, ErrNotInPulse
+}
+
+// leaves returns keys of all records of the pulse.
+//
+// Record hash is a part of the key, as references are content-addressed: record ID holds hash of the raw record. So
+// leaves commit to record contents, and proof of a key is checked without the record itself. Storage that returns a
+// record not matching its reference is detected by storage.VerifyRecord.
+func (r *Roots) leaves(pn record.PulseNum) ([][]byte, error) {
+	var leaves [][]byte
+	q := storage.RecordQuery{FromPulse: pn, ToPulse: pn + 1}
+	err := r.it.IterateRecords(q, func(ref *record.Reference, rec record.Record) error {
+		leaves = append(leaves, ref.Key())
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return leaves, nil
+}
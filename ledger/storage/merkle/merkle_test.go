@@ -0,0 +1,147 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package merkle
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/hash"
+	"github.com/insolar/insolar/ledger/pulse"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+	"github.com/insolar/insolar/ledger/storage/memory"
+)
+
+func mustNoError(t *testing.T, err error) {
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+}
+
+func setRecords(t *testing.T, s storage.LedgerStorer, n int) []*record.Reference {
+	var refs []*record.Reference
+	for i := 0; i < n; i++ {
+		ref, err := s.SetRecord(&record.CallRequest{ParamMemory: []byte{byte(i), byte(n)}})
+		mustNoError(t, err)
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+func TestRoots_ProveRecords(t *testing.T) {
+	s := memory.NewMemLedger()
+	provider := pulse.NewManual(1)
+	s.SetPulseProvider(provider)
+	roots, err := NewRoots(s, hash.SHA3256)
+	mustNoError(t, err)
+
+	pulse1 := setRecords(t, s, 5)
+	provider.Set(2)
+	pulse2 := setRecords(t, s, 3)
+
+	_, err = roots.Prove(pulse1[0])
+	assert.Equal(t, ErrPulseNotClosed, err)
+
+	root1, err := roots.ClosePulse(1)
+	mustNoError(t, err)
+	assert.Equal(t, hash.SHA3256, root1.Algorithm)
+	again, err := roots.ClosePulse(1)
+	mustNoError(t, err)
+	assert.Equal(t, root1, again)
+	root2, err := roots.ClosePulse(2)
+	mustNoError(t, err)
+	assert.NotEqual(t, root1.Hash, root2.Hash)
+
+	for i, ref := range pulse1 {
+		proof, err := roots.Prove(ref)
+		mustNoError(t, err)
+		assert.NoError(t, proof.Verify(ref, root1))
+		assert.Equal(t, ErrInvalidProof, errors.Cause(proof.Verify(ref, root2)))
+		other := pulse1[(i+1)%len(pulse1)]
+		assert.Equal(t, ErrInvalidProof, errors.Cause(proof.Verify(other, root1)))
+	}
+	for _, ref := range pulse2 {
+		proof, err := roots.Prove(ref)
+		mustNoError(t, err)
+		assert.NoError(t, proof.Verify(ref, root2))
+	}
+
+	// roots are persisted by storage
+	restored, err := NewRoots(s, hash.SHA3224)
+	mustNoError(t, err)
+	got, err := restored.Root(1)
+	mustNoError(t, err)
+	assert.Equal(t, root1, got)
+}
+
+func TestRoots_EmptyPulse(t *testing.T) {
+	roots, err := NewRoots(memory.NewMemLedger(), hash.SHA3224)
+	mustNoError(t, err)
+	root, err := roots.ClosePulse(10)
+	mustNoError(t, err)
+	assert.Equal(t, hash.SHA3224.MerkleRoot(nil), root.Hash)
+
+	ref := &record.Reference{Record: record.ID{Pulse: 10, Hash: make([]byte, record.HashSize)}}
+	_, err = roots.Prove(ref)
+	assert.Equal(t, ErrNotInPulse, err)
+}
+
+func TestRoots_DetectsRecordsWrittenAfterClose(t *testing.T) {
+	s := memory.NewMemLedger()
+	s.SetPulseProvider(pulse.NewManual(1))
+	roots, err := NewRoots(s, hash.SHA3224)
+	mustNoError(t, err)
+	refs := setRecords(t, s, 2)
+	_, err = roots.ClosePulse(1)
+	mustNoError(t, err)
+
+	setRecords(t, s, 1)
+	_, err = roots.Prove(refs[0])
+	assert.Equal(t, ErrRootMismatch, err)
+}
+
+func TestRoots_Subscribe(t *testing.T) {
+	s := memory.NewMemLedger()
+	network := pulse.NewNetwork(1)
+	s.SetPulseProvider(network)
+	roots, err := NewRoots(s, hash.SHA3224)
+	mustNoError(t, err)
+	var failed []record.PulseNum
+	roots.Subscribe(network, func(pn record.PulseNum, err error) {
+		failed = append(failed, pn)
+	})
+
+	refs := setRecords(t, s, 2)
+	_, err = roots.Root(1)
+	assert.Equal(t, ErrPulseNotClosed, err)
+	mustNoError(t, network.OnPulse(3))
+	root, err := roots.Root(1)
+	mustNoError(t, err)
+	proof, err := roots.Prove(refs[1])
+	mustNoError(t, err)
+	assert.NoError(t, proof.Verify(refs[1], root))
+
+	_, err = roots.Root(3)
+	assert.Equal(t, ErrPulseNotClosed, err)
+	mustNoError(t, network.OnPulse(4))
+	_, err = roots.Root(3)
+	assert.NoError(t, err)
+	assert.Empty(t, failed)
+}
@@ -0,0 +1,85 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storage
+
+import (
+	"bytes"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/record"
+)
+
+// SignaturePolicy keeps signer of new records and keys required for stored records. It is embedded by storages which
+// sign records. Zero value signs nothing and accepts all records. It is safe for concurrent use.
+type SignaturePolicy struct {
+	lock   sync.RWMutex
+	signer record.Signer
+	keys   *record.KeyRing
+}
+
+// SetSigner sets signer of new records. Records are stored unsigned if signer is nil.
+func (p *SignaturePolicy) SetSigner(signer record.Signer) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.signer = signer
+}
+
+// RequireSignatures makes storage to accept only records signed by provided keys. New records are checked after
+// signing by storage's signer, serialized records are checked before storing. Nil keys disables the check.
+func (p *SignaturePolicy) RequireSignatures(keys *record.KeyRing) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.keys = keys
+}
+
+// SignRecord signs new record by signer if it is set and checks the signature (see CheckSignature).
+func (p *SignaturePolicy) SignRecord(ref *record.Reference, raw *record.Raw) error {
+	p.lock.RLock()
+	signer := p.signer
+	p.lock.RUnlock()
+	if signer != nil {
+		raw.Signature = signer.Sign(ref.Record)
+	}
+	return p.CheckSignature(ref, raw)
+}
+
+// CheckSignature checks that record is signed by one of required keys. Any record is accepted if keys are not
+// required.
+func (p *SignaturePolicy) CheckSignature(ref *record.Reference, raw *record.Raw) error {
+	p.lock.RLock()
+	keys := p.keys
+	p.lock.RUnlock()
+	if keys == nil {
+		return nil
+	}
+	return raw.Signature.Verify(ref.Record, keys)
+}
+
+// VerifyRecord checks that stored record matches its reference and is signed by one of provided keys. It returns
+// ErrCorrupted if stored data does not match the record hash and record.ErrNotSigned for unsigned records.
+func VerifyRecord(s RawStorer, ref *record.Reference, keys *record.KeyRing) error {
+	raw, err := s.GetRawRecord(ref)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(raw.Sum(ref.Record.Algorithm), ref.Record.Hash) {
+		return errors.Wrap(ErrCorrupted, "record data does not match reference hash")
+	}
+	return raw.Signature.Verify(ref.Record, keys)
+}
@@ -17,6 +17,8 @@
 package storage
 
 import (
+	"bytes"
+
 	"github.com/insolar/insolar/ledger/index"
 	"github.com/insolar/insolar/ledger/record"
 )
@@ -25,10 +27,142 @@ import (
 type LedgerStorer interface {
 	GetRecord(*record.Reference) (record.Record, error)
 	SetRecord(record.Record) (*record.Reference, error)
+	// GetRecordSignature returns signature of stored record. It returns nil signature for unsigned records and
+	// ErrNotFound if there is no such record.
+	GetRecordSignature(*record.Reference) (*record.Signature, error)
 
 	GetClassIndex(*record.Reference) (*index.ClassLifeline, error)
 	SetClassIndex(*record.Reference, *index.ClassLifeline) error
 
 	GetObjectIndex(*record.Reference) (*index.ObjectLifeline, error)
 	SetObjectIndex(*record.Reference, *index.ObjectLifeline) error
+
+	// IterateHistory calls provided function for every record appended to lifeline history of the head (see
+	// Batch.AppendHistory) in order of appending. Lifelines without history are iterated as empty. Iteration stops
+	// on the first error. ErrStopIteration stops iteration without error.
+	IterateHistory(headRef *record.Reference, fn func(*record.Reference) error) error
+
+	// GetRequestResult returns reference of the result record produced by provided request. It returns ErrNotFound
+	// if request has no result yet.
+	GetRequestResult(requestRef *record.Reference) (*record.Reference, error)
+
+	// GetBlob returns blob stored under provided content hash. It returns ErrNotFound if there is no such blob.
+	GetBlob(hash []byte) ([]byte, error)
+	// GetBlobRefs returns number of references to the blob. It returns ErrNotFound if there is no such blob.
+	GetBlobRefs(hash []byte) (uint64, error)
+
+	// Update calls provided function with a new Batch. If the function returns nil, all writes made through the
+	// batch are applied to storage at once. If the function returns an error, none of them are applied and the error
+	// is returned as is.
+	Update(func(Batch) error) error
+}
+
+// Batch accumulates storage writes to apply them atomically.
+//
+// Batch writes are not visible to storage getters until Update returns.
+type Batch interface {
+	SetRecord(record.Record) (*record.Reference, error)
+	SetClassIndex(*record.Reference, *index.ClassLifeline) error
+	SetObjectIndex(*record.Reference, *index.ObjectLifeline) error
+	// SetRequestResult stores reference of the result record produced by request. Request can have only one result, so
+	// if the request already has a result when batch is applied, the whole batch is discarded and ErrRequestHasResult
+	// is returned.
+	SetRequestResult(requestRef, resultRef *record.Reference) error
+	// SetBlob adds a reference to the blob and returns its content hash (see record.BlobHash). Blob data is stored
+	// only once, no matter how many times it was set.
+	SetBlob(data []byte) ([]byte, error)
+	// AppendHistory appends record to lifeline history of the head. History is stored separately from lifeline
+	// index, so the index doesn't grow with the number of lifeline records.
+	AppendHistory(headRef, ref *record.Reference) error
+	// CheckObjectIndex adds a precondition: batch is applied only if object index stored under the reference is equal
+	// to provided one, otherwise the whole batch is discarded and ErrConflict is returned. It allows to update index
+	// read before Update without losing concurrent updates.
+	CheckObjectIndex(*record.Reference, *index.ObjectLifeline) error
+}
+
+// RecordQuery defines filters for record iteration. Zero value matches all records.
+type RecordQuery struct {
+	// FromPulse and ToPulse define half-open pulse range [FromPulse, ToPulse). Zero ToPulse means no upper bound.
+	FromPulse record.PulseNum
+	ToPulse   record.PulseNum
+	// Type filters records by type. Zero Type matches records of any type.
+	Type record.TypeID
+	// Domain filters records by domain. Nil Domain matches records of any domain.
+	Domain *record.ID
+
+	// After is a pagination cursor. If provided, iteration starts right after this record.
+	After *record.Reference
+	// Limit is a maximum number of records passed to iteration callback. Zero Limit means no limit.
+	Limit int
+}
+
+// Matches checks if record with provided reference and type matches query filters. Pagination fields are ignored.
+func (q *RecordQuery) Matches(ref *record.Reference, typeID record.TypeID) bool {
+	pn := ref.Record.Pulse
+	if pn < q.FromPulse || (q.ToPulse != 0 && pn >= q.ToPulse) {
+		return false
+	}
+	if q.Type != 0 && q.Type != typeID {
+		return false
+	}
+	if q.Domain != nil && !bytes.Equal(record.ID2Bytes(*q.Domain), record.ID2Bytes(ref.Domain)) {
+		return false
+	}
+	return true
+}
+
+// RecordIterator is implemented by storages which support record listing.
+//
+// Records are iterated ordered by pulse number and then by reference key. To fetch the next page of records, pass
+// the reference of the last fetched record as RecordQuery.After.
+type RecordIterator interface {
+	// IterateRecords calls provided function for every record matching the query. If the function returns
+	// ErrStopIteration, iteration stops and IterateRecords returns nil. Any other error stops iteration and is
+	// returned as is.
+	IterateRecords(RecordQuery, func(*record.Reference, record.Record) error) error
+}
+
+// PulseRootStorer is implemented by storages which persist Merkle roots of closed pulses (see package merkle).
+type PulseRootStorer interface {
+	// GetPulseRoot returns stored root of the pulse. It returns ErrNotFound if pulse root was not stored.
+	GetPulseRoot(record.PulseNum) ([]byte, error)
+	// SetPulseRoot stores root of the pulse.
+	SetPulseRoot(record.PulseNum, []byte) error
+}
+
+// RawStorer provides low level access to stored data. It is intended for tools like ledger export/import and
+// consistency checks, which need records exactly as they are stored.
+type RawStorer interface {
+	// GetRawRecord returns stored record in serialized form with its signature. It returns ErrNotFound if there is no
+	// such record.
+	GetRawRecord(*record.Reference) (*record.Raw, error)
+	// IterateRawRecords calls provided function for every stored record in its serialized form. Iteration stops on
+	// the first error. ErrStopIteration stops iteration without error.
+	IterateRawRecords(func(*record.Reference, *record.Raw) error) error
+	// IterateLifelineRefs calls provided function for reference of every stored lifeline index (class or object).
+	// Iteration stops on the first error. ErrStopIteration stops iteration without error.
+	IterateLifelineRefs(func(*record.Reference) error) error
+	// IterateRequestResults calls provided function for every request which has a result. Iteration stops on the
+	// first error. ErrStopIteration stops iteration without error.
+	IterateRequestResults(func(requestRef, resultRef *record.Reference) error) error
+	// IterateBlobs calls provided function for every stored blob with its reference count. Iteration stops on the
+	// first error. ErrStopIteration stops iteration without error.
+	IterateBlobs(func(hash, data []byte, refs uint64) error) error
+	// UpdateRaw works as LedgerStorer.Update, but provided batch also allows to store serialized records.
+	UpdateRaw(func(RawBatch) error) error
+}
+
+// RawBatch is a Batch which also accepts serialized records.
+type RawBatch interface {
+	Batch
+	// SetRawRecord stores serialized record under provided reference. Reference is not checked against record hash.
+	SetRawRecord(*record.Reference, *record.Raw) error
+	// DeleteLifeline removes lifeline index. It is intended for repairing broken storages only.
+	DeleteLifeline(*record.Reference) error
+	// SetHistory replaces lifeline history of the head. Nil refs removes the history. It is intended for repairing
+	// broken storages only.
+	SetHistory(headRef *record.Reference, refs []record.Reference) error
+	// SetRawBlob stores blob under provided hash with exact reference count, replacing stored one. Hash is not
+	// checked against blob data. Zero refs removes the blob.
+	SetRawBlob(hash, data []byte, refs uint64) error
 }
@@ -0,0 +1,26 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package storagetest contains conformance test suite for storage.LedgerStorer implementations.
+//
+// Every storage implementation should pass it from its own tests:
+//
+//	func TestLedgerStorer(t *testing.T) {
+//	    storagetest.TestLedgerStorer(t, func(t *testing.T) (storage.LedgerStorer, func()) {
+//	        ...
+//	    })
+//	}
+package storagetest
@@ -0,0 +1,886 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package storagetest
+
+import (
+	"crypto/rand"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ed25519"
+
+	"github.com/insolar/insolar/ledger/hash"
+	"github.com/insolar/insolar/ledger/index"
+	"github.com/insolar/insolar/ledger/pulse"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage"
+)
+
+// Factory creates new empty storage for a single test case. Returned function is called at the end of the test case
+// and should release all storage resources.
+type Factory func(t *testing.T) (storage.LedgerStorer, func())
+
+// pulseSetter is implemented by storages which allow to change pulse source.
+type pulseSetter interface {
+	SetPulseProvider(pulse.Provider)
+}
+
+// fixedPulse is a pulse provider which always returns the same pulse number, even an invalid one.
+type fixedPulse record.PulseNum
+
+func (p fixedPulse) Current() record.PulseNum {
+	return record.PulseNum(p)
+}
+
+// hashAlgorithmSetter is implemented by storages which allow to change hash algorithm of new records.
+type hashAlgorithmSetter interface {
+	SetHashAlgorithm(hash.Algorithm) error
+}
+
+// signatureSetter is implemented by storages which allow to sign records and require signatures.
+type signatureSetter interface {
+	SetSigner(record.Signer)
+	RequireSignatures(*record.KeyRing)
+}
+
+// TestLedgerStorer runs conformance test suite against storage created by provided factory.
+func TestLedgerStorer(t *testing.T, factory Factory) {
+	cases := []struct {
+		name string
+		test func(*testing.T, storage.LedgerStorer)
+	}{
+		{"Genesis", testGenesis},
+		{"GetRecordNotFound", testGetRecordNotFound},
+		{"SetRecord", testSetRecord},
+		{"SetRecordIsIdempotent", testSetRecordIsIdempotent},
+		{"ReferenceString", testReferenceString},
+		{"GetClassIndexNotFound", testGetClassIndexNotFound},
+		{"SetClassIndex", testSetClassIndex},
+		{"GetObjectIndexNotFound", testGetObjectIndexNotFound},
+		{"SetObjectIndex", testSetObjectIndex},
+		{"IndexesAreCopied", testIndexesAreCopied},
+		{"CheckObjectIndex", testCheckObjectIndex},
+		{"ConcurrentAccess", testConcurrentAccess},
+		{"UpdateAppliesAllWrites", testUpdateAppliesAllWrites},
+		{"UpdateDiscardsWritesOnError", testUpdateDiscardsWritesOnError},
+		{"IterateRecords", testIterateRecords},
+		{"RequestResult", testRequestResult},
+		{"ConcurrentRequestResults", testConcurrentRequestResults},
+		{"Blobs", testBlobs},
+		{"History", testHistory},
+		{"ConcurrentHistory", testConcurrentHistory},
+		{"HashAlgorithms", testHashAlgorithms},
+		{"Signatures", testSignatures},
+		{"PulseRoots", testPulseRoots},
+		{"ReservedPulse", testReservedPulse},
+	}
+	for _, c := range cases {
+		test := c.test
+		t.Run(c.name, func(t *testing.T) {
+			s, closer := factory(t)
+			defer closer()
+			test(t, s)
+		})
+	}
+}
+
+func mustNoError(t *testing.T, err error) {
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+}
+
+func randHash() []byte {
+	b := make([]byte, record.HashSize)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func randRef() record.Reference {
+	return record.Reference{
+		Domain: record.ID{Hash: randHash()},
+		Record: record.ID{Hash: randHash()},
+	}
+}
+
+func testRecords() []record.Record {
+	return []record.Record{
+		&record.CallRequest{
+			RequestRecord: record.RequestRecord{Requester: randRef(), Target: randRef()},
+			ParamMemory:   record.Memory{1, 2, 3},
+		},
+		&record.CodeRecord{
+			StorageRecord: record.StorageRecord{StatefulResult: record.StatefulResult{
+				ResultRecord: record.ResultRecord{RequestRecord: randRef()},
+			}},
+			TargetedCode: map[record.ArchType][]byte{1: {4, 5, 6}},
+		},
+		&record.ClassActivateRecord{
+			ActivationRecord: record.ActivationRecord{StatefulResult: record.StatefulResult{
+				ResultRecord: record.ResultRecord{RequestRecord: randRef()},
+			}},
+			CodeRecord:    randRef(),
+			DefaultMemory: record.Memory{7, 8, 9},
+		},
+		&record.ObjectAmendRecord{
+			AmendRecord: record.AmendRecord{
+				HeadRecord:    randRef(),
+				AmendedRecord: randRef(),
+			},
+			NewMemory: record.Memory{10},
+		},
+	}
+}
+
+func testGenesis(t *testing.T, s storage.LedgerStorer) {
+	genesisRef := storage.GenesisReference()
+	rec, err := s.GetRecord(&genesisRef)
+	mustNoError(t, err)
+	assert.Equal(t, storage.GenesisRecord(), rec)
+
+	idx, err := s.GetClassIndex(&genesisRef)
+	mustNoError(t, err)
+	assert.Equal(t, genesisRef, idx.LatestStateRef)
+}
+
+func testGetRecordNotFound(t *testing.T, s storage.LedgerStorer) {
+	ref := randRef()
+	rec, err := s.GetRecord(&ref)
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Nil(t, rec)
+}
+
+func testSetRecord(t *testing.T, s storage.LedgerStorer) {
+	for _, rec := range testRecords() {
+		raw, err := record.EncodeToRaw(rec)
+		mustNoError(t, err)
+
+		ref, err := s.SetRecord(rec)
+		mustNoError(t, err)
+		assert.Equal(t, rec.Domain(), ref.Domain)
+		assert.Equal(t, raw.Hash(), ref.Record.Hash)
+
+		got, err := s.GetRecord(ref)
+		mustNoError(t, err)
+		assert.Equal(t, rec, got)
+	}
+}
+
+func testReferenceString(t *testing.T, s storage.LedgerStorer) {
+	for _, rec := range testRecords() {
+		ref, err := s.SetRecord(rec)
+		mustNoError(t, err)
+
+		parsed, err := record.ParseReference(ref.String())
+		mustNoError(t, err)
+		assert.True(t, ref.IsEqual(parsed))
+		assert.Equal(t, *ref, parsed)
+		got, err := s.GetRecord(&parsed)
+		mustNoError(t, err)
+		assert.Equal(t, rec, got)
+	}
+}
+
+func testSetRecordIsIdempotent(t *testing.T, s storage.LedgerStorer) {
+	rec := &record.CodeRecord{TargetedCode: map[record.ArchType][]byte{1: {1}}}
+	ref1, err := s.SetRecord(rec)
+	mustNoError(t, err)
+	ref2, err := s.SetRecord(rec)
+	mustNoError(t, err)
+	assert.Equal(t, ref1.Record.Hash, ref2.Record.Hash)
+
+	got, err := s.GetRecord(ref2)
+	mustNoError(t, err)
+	assert.Equal(t, rec, got)
+}
+
+func testGetClassIndexNotFound(t *testing.T, s storage.LedgerStorer) {
+	ref := randRef()
+	idx, err := s.GetClassIndex(&ref)
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Nil(t, idx)
+}
+
+func testSetClassIndex(t *testing.T, s storage.LedgerStorer) {
+	ref := randRef()
+	idx := index.ClassLifeline{
+		LatestStateRef: randRef(),
+		AmendRefs:      []record.Reference{randRef(), randRef()},
+	}
+	mustNoError(t, s.SetClassIndex(&ref, &idx))
+	got, err := s.GetClassIndex(&ref)
+	mustNoError(t, err)
+	assert.Equal(t, idx, *got)
+
+	// overwrite existing index
+	idx.LatestStateRef = randRef()
+	idx.AmendRefs = append(idx.AmendRefs, idx.LatestStateRef)
+	mustNoError(t, s.SetClassIndex(&ref, &idx))
+	got, err = s.GetClassIndex(&ref)
+	mustNoError(t, err)
+	assert.Equal(t, idx, *got)
+}
+
+func testGetObjectIndexNotFound(t *testing.T, s storage.LedgerStorer) {
+	ref := randRef()
+	idx, err := s.GetObjectIndex(&ref)
+	assert.Equal(t, storage.ErrNotFound, err)
+	assert.Nil(t, idx)
+}
+
+func testSetObjectIndex(t *testing.T, s storage.LedgerStorer) {
+	ref := randRef()
+	idx := index.ObjectLifeline{
+		ClassRef:       randRef(),
+		LatestStateRef: randRef(),
+		AppendRefs:     []record.Reference{randRef()},
+	}
+	mustNoError(t, s.SetObjectIndex(&ref, &idx))
+	got, err := s.GetObjectIndex(&ref)
+	mustNoError(t, err)
+	assert.Equal(t, idx, *got)
+
+	// overwrite existing index
+	idx.LatestStateRef = randRef()
+	idx.AppendRefs = nil
+	mustNoError(t, s.SetObjectIndex(&ref, &idx))
+	got, err = s.GetObjectIndex(&ref)
+	mustNoError(t, err)
+	assert.Equal(t, idx.LatestStateRef, got.LatestStateRef)
+	assert.Empty(t, got.AppendRefs)
+}
+
+func testCheckObjectIndex(t *testing.T, s storage.LedgerStorer) {
+	ref := randRef()
+	setIf := func(expected, idx *index.ObjectLifeline) error {
+		return s.Update(func(batch storage.Batch) error {
+			if err := batch.CheckObjectIndex(&ref, expected); err != nil {
+				return err
+			}
+			return batch.SetObjectIndex(&ref, idx)
+		})
+	}
+	idx := index.ObjectLifeline{ClassRef: randRef(), LatestStateRef: randRef()}
+	assert.Equal(t, storage.ErrConflict, errors.Cause(setIf(&idx, &idx)))
+	_, err := s.GetObjectIndex(&ref)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	mustNoError(t, s.SetObjectIndex(&ref, &idx))
+	stored, err := s.GetObjectIndex(&ref)
+	mustNoError(t, err)
+	updated := *stored
+	updated.LatestStateRef = randRef()
+	mustNoError(t, setIf(stored, &updated))
+
+	// stored index was changed after it was read
+	stale := *stored
+	stale.AppendRefs = []record.Reference{randRef()}
+	assert.Equal(t, storage.ErrConflict, errors.Cause(setIf(stored, &stale)))
+	got, err := s.GetObjectIndex(&ref)
+	mustNoError(t, err)
+	assert.Equal(t, updated, *got)
+}
+
+func testIndexesAreCopied(t *testing.T, s storage.LedgerStorer) {
+	classRef := randRef()
+	classIdx := index.ClassLifeline{LatestStateRef: randRef()}
+	mustNoError(t, s.SetClassIndex(&classRef, &classIdx))
+	// changes of stored and returned values should not affect the storage
+	classIdx.LatestStateRef = randRef()
+	got, err := s.GetClassIndex(&classRef)
+	mustNoError(t, err)
+	assert.NotEqual(t, classIdx.LatestStateRef, got.LatestStateRef)
+	got.AmendRefs = append(got.AmendRefs, randRef())
+	got, err = s.GetClassIndex(&classRef)
+	mustNoError(t, err)
+	assert.Empty(t, got.AmendRefs)
+
+	objRef := randRef()
+	objIdx := index.ObjectLifeline{ClassRef: classRef, LatestStateRef: randRef()}
+	mustNoError(t, s.SetObjectIndex(&objRef, &objIdx))
+	objIdx.LatestStateRef = randRef()
+	gotObj, err := s.GetObjectIndex(&objRef)
+	mustNoError(t, err)
+	assert.NotEqual(t, objIdx.LatestStateRef, gotObj.LatestStateRef)
+}
+
+func testConcurrentAccess(t *testing.T, s storage.LedgerStorer) {
+	const workers = 8
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func(i int) {
+			defer wg.Done()
+			rec := &record.CodeRecord{TargetedCode: map[record.ArchType][]byte{1: {byte(i)}}}
+			ref, err := s.SetRecord(rec)
+			if !assert.NoError(t, err) {
+				return
+			}
+			got, err := s.GetRecord(ref)
+			assert.NoError(t, err)
+			assert.Equal(t, rec, got)
+
+			idxRef := randRef()
+			idx := index.ObjectLifeline{LatestStateRef: *ref}
+			assert.NoError(t, s.SetObjectIndex(&idxRef, &idx))
+			gotIdx, err := s.GetObjectIndex(&idxRef)
+			assert.NoError(t, err)
+			assert.Equal(t, idx.LatestStateRef, gotIdx.LatestStateRef)
+		}(i)
+	}
+	wg.Wait()
+}
+
+func testUpdateAppliesAllWrites(t *testing.T, s storage.LedgerStorer) {
+	rec := &record.ClassActivateRecord{DefaultMemory: record.Memory{1}}
+	classRef := randRef()
+	objRef := randRef()
+	var recRef *record.Reference
+	err := s.Update(func(batch storage.Batch) error {
+		var err error
+		recRef, err = batch.SetRecord(rec)
+		if err != nil {
+			return err
+		}
+		// batch writes should not be visible before commit
+		_, err = s.GetRecord(recRef)
+		assert.Equal(t, storage.ErrNotFound, err)
+
+		err = batch.SetClassIndex(&classRef, &index.ClassLifeline{LatestStateRef: *recRef})
+		if err != nil {
+			return err
+		}
+		return batch.SetObjectIndex(&objRef, &index.ObjectLifeline{ClassRef: classRef, LatestStateRef: *recRef})
+	})
+	mustNoError(t, err)
+
+	got, err := s.GetRecord(recRef)
+	mustNoError(t, err)
+	assert.Equal(t, rec, got)
+	classIdx, err := s.GetClassIndex(&classRef)
+	mustNoError(t, err)
+	assert.Equal(t, *recRef, classIdx.LatestStateRef)
+	objIdx, err := s.GetObjectIndex(&objRef)
+	mustNoError(t, err)
+	assert.Equal(t, classRef, objIdx.ClassRef)
+	assert.Equal(t, *recRef, objIdx.LatestStateRef)
+}
+
+func testRequestResult(t *testing.T, s storage.LedgerStorer) {
+	requestRef := randRef()
+	resultRef := randRef()
+	_, err := s.GetRequestResult(&requestRef)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	err = s.Update(func(batch storage.Batch) error {
+		return batch.SetRequestResult(&requestRef, &resultRef)
+	})
+	mustNoError(t, err)
+	got, err := s.GetRequestResult(&requestRef)
+	mustNoError(t, err)
+	assert.Equal(t, resultRef.Key(), got.Key())
+
+	// request can't get another result, the whole batch is discarded
+	otherRef := randRef()
+	err = s.Update(func(batch storage.Batch) error {
+		if err := batch.SetRequestResult(&otherRef, &resultRef); err != nil {
+			return err
+		}
+		return batch.SetRequestResult(&requestRef, &otherRef)
+	})
+	assert.Equal(t, storage.ErrRequestHasResult, errors.Cause(err))
+	got, err = s.GetRequestResult(&requestRef)
+	mustNoError(t, err)
+	assert.Equal(t, resultRef.Key(), got.Key())
+	_, err = s.GetRequestResult(&otherRef)
+	assert.Equal(t, storage.ErrNotFound, err)
+	err = s.Update(func(batch storage.Batch) error {
+		if err := batch.SetRequestResult(&otherRef, &resultRef); err != nil {
+			return err
+		}
+		return batch.SetRequestResult(&otherRef, &resultRef)
+	})
+	assert.Equal(t, storage.ErrRequestHasResult, errors.Cause(err))
+}
+
+func testConcurrentRequestResults(t *testing.T, s storage.LedgerStorer) {
+	requestRef := randRef()
+	const workers = 10
+	errs := make(chan error, workers)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resultRef := randRef()
+			errs <- s.Update(func(batch storage.Batch) error {
+				return batch.SetRequestResult(&requestRef, &resultRef)
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	succeeded := 0
+	for err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.Equal(t, storage.ErrRequestHasResult, errors.Cause(err))
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func getHistory(t *testing.T, s storage.LedgerStorer, headRef *record.Reference) []record.Reference {
+	var history []record.Reference
+	err := s.IterateHistory(headRef, func(ref *record.Reference) error {
+		history = append(history, *ref)
+		return nil
+	})
+	mustNoError(t, err)
+	return history
+}
+
+func testHistory(t *testing.T, s storage.LedgerStorer) {
+	headRef := randRef()
+	otherRef := randRef()
+	assert.Empty(t, getHistory(t, s, &headRef))
+
+	// The first reference has no domain hash, like references of records without domain.
+	refs := []record.Reference{{Record: record.ID{Pulse: 1, Hash: randHash()}}, randRef(), randRef()}
+	err := s.Update(func(batch storage.Batch) error {
+		if err := batch.AppendHistory(&headRef, &refs[0]); err != nil {
+			return err
+		}
+		if err := batch.AppendHistory(&otherRef, &refs[2]); err != nil {
+			return err
+		}
+		return batch.AppendHistory(&headRef, &refs[1])
+	})
+	mustNoError(t, err)
+	err = s.Update(func(batch storage.Batch) error {
+		return batch.AppendHistory(&headRef, &refs[2])
+	})
+	mustNoError(t, err)
+	// References are returned exactly as they were appended.
+	assert.Equal(t, refs, getHistory(t, s, &headRef))
+	assert.Equal(t, refs[2:], getHistory(t, s, &otherRef))
+
+	var first []record.Reference
+	err = s.IterateHistory(&headRef, func(ref *record.Reference) error {
+		first = append(first, *ref)
+		return storage.ErrStopIteration
+	})
+	mustNoError(t, err)
+	assert.Equal(t, refs[:1], first)
+
+	// History of discarded batch is not stored.
+	updateErr := errors.New("update failed")
+	err = s.Update(func(batch storage.Batch) error {
+		if err := batch.AppendHistory(&headRef, &refs[0]); err != nil {
+			return err
+		}
+		return updateErr
+	})
+	assert.Equal(t, updateErr, err)
+	assert.Len(t, getHistory(t, s, &headRef), 3)
+
+	rs, ok := s.(storage.RawStorer)
+	if !ok {
+		return
+	}
+	err = rs.UpdateRaw(func(batch storage.RawBatch) error {
+		if err := batch.SetHistory(&headRef, refs[1:2]); err != nil {
+			return err
+		}
+		if err := batch.AppendHistory(&headRef, &refs[0]); err != nil {
+			return err
+		}
+		return batch.SetHistory(&otherRef, nil)
+	})
+	mustNoError(t, err)
+	assert.Equal(t, []record.Reference{refs[1], refs[0]}, getHistory(t, s, &headRef))
+	assert.Empty(t, getHistory(t, s, &otherRef))
+}
+
+func testConcurrentHistory(t *testing.T, s storage.LedgerStorer) {
+	headRef := randRef()
+	const workers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ref := randRef()
+			err := s.Update(func(batch storage.Batch) error {
+				return batch.AppendHistory(&headRef, &ref)
+			})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Len(t, getHistory(t, s, &headRef), workers)
+}
+
+func testBlobs(t *testing.T, s storage.LedgerStorer) {
+	data := []byte("code blob")
+	setBlob := func() []byte {
+		var hash []byte
+		err := s.Update(func(batch storage.Batch) error {
+			var err error
+			hash, err = batch.SetBlob(data)
+			return err
+		})
+		mustNoError(t, err)
+		return hash
+	}
+
+	hash := setBlob()
+	assert.Equal(t, record.BlobHash(data), hash)
+	assert.Equal(t, hash, setBlob())
+	got, err := s.GetBlob(hash)
+	mustNoError(t, err)
+	assert.Equal(t, data, got)
+	refs, err := s.GetBlobRefs(hash)
+	mustNoError(t, err)
+	assert.Equal(t, uint64(2), refs)
+}
+
+func testUpdateDiscardsWritesOnError(t *testing.T, s storage.LedgerStorer) {
+	updateErr := errors.New("update failed")
+	classRef := randRef()
+	objRef := randRef()
+	var recRef *record.Reference
+	err := s.Update(func(batch storage.Batch) error {
+		var err error
+		recRef, err = batch.SetRecord(&record.ClassActivateRecord{DefaultMemory: record.Memory{2}})
+		if err != nil {
+			return err
+		}
+		err = batch.SetClassIndex(&classRef, &index.ClassLifeline{LatestStateRef: *recRef})
+		if err != nil {
+			return err
+		}
+		err = batch.SetObjectIndex(&objRef, &index.ObjectLifeline{LatestStateRef: *recRef})
+		if err != nil {
+			return err
+		}
+		return updateErr
+	})
+	assert.Equal(t, updateErr, err)
+
+	_, err = s.GetRecord(recRef)
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.GetClassIndex(&classRef)
+	assert.Equal(t, storage.ErrNotFound, err)
+	_, err = s.GetObjectIndex(&objRef)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+// collectRefs returns keys of iterated references. References are compared by keys, because iterated references are
+// restored from storage keys and can differ from references returned by SetRecord in empty hash representation.
+func collectRefs(t *testing.T, it storage.RecordIterator, q storage.RecordQuery) []string {
+	var keys []string
+	err := it.IterateRecords(q, func(ref *record.Reference, rec record.Record) error {
+		got, err := it.(storage.LedgerStorer).GetRecord(ref)
+		mustNoError(t, err)
+		assert.Equal(t, got, rec)
+		keys = append(keys, string(ref.Key()))
+		return nil
+	})
+	mustNoError(t, err)
+	return keys
+}
+
+func testIterateRecords(t *testing.T, s storage.LedgerStorer) {
+	it, ok := s.(storage.RecordIterator)
+	if !ok {
+		t.Skip("storage does not implement RecordIterator")
+	}
+	ps, ok := s.(pulseSetter)
+	if !ok {
+		t.Skip("storage does not allow to set pulse provider")
+	}
+	provider := pulse.NewManual(1)
+	ps.SetPulseProvider(provider)
+
+	domain := record.ID{Pulse: 1, Hash: randHash()}
+	var all, codes, inDomain, pulse2 []string
+	for pn := record.PulseNum(1); pn <= 3; pn++ {
+		mustNoError(t, provider.Set(pn))
+		codeRef, err := s.SetRecord(&record.CodeRecord{
+			StorageRecord: record.StorageRecord{StatefulResult: record.StatefulResult{
+				ResultRecord: record.ResultRecord{RequestRecord: record.Reference{Domain: domain}},
+			}},
+			SourceCode: string(randHash()),
+		})
+		mustNoError(t, err)
+		reqRef, err := s.SetRecord(&record.CallRequest{ParamMemory: randHash()})
+		mustNoError(t, err)
+
+		pulseRefs := []string{string(codeRef.Key()), string(reqRef.Key())}
+		if pulseRefs[1] < pulseRefs[0] {
+			pulseRefs[0], pulseRefs[1] = pulseRefs[1], pulseRefs[0]
+		}
+		all = append(all, pulseRefs...)
+		codes = append(codes, string(codeRef.Key()))
+		inDomain = append(inDomain, string(codeRef.Key()))
+		if pn == 2 {
+			pulse2 = pulseRefs
+		}
+	}
+
+	gotAll := collectRefs(t, it, storage.RecordQuery{FromPulse: 1})
+	assert.Equal(t, all, gotAll)
+	assert.Equal(t, pulse2, collectRefs(t, it, storage.RecordQuery{FromPulse: 2, ToPulse: 3}))
+	assert.Equal(t, codes, collectRefs(t, it, storage.RecordQuery{
+		Type: record.TypeIDOf(&record.CodeRecord{}),
+	}))
+	assert.Equal(t, inDomain, collectRefs(t, it, storage.RecordQuery{Domain: &domain}))
+	assert.Equal(t, codes[1:2], collectRefs(t, it, storage.RecordQuery{
+		Domain:    &domain,
+		Type:      record.TypeIDOf(&record.CodeRecord{}),
+		FromPulse: 2,
+		ToPulse:   3,
+	}))
+
+	// records with empty domain
+	var emptyDomain record.ID
+	assert.Len(t, collectRefs(t, it, storage.RecordQuery{Domain: &emptyDomain}), len(all)-len(inDomain))
+
+	// genesis record is the first record of the whole storage
+	withGenesis := collectRefs(t, it, storage.RecordQuery{})
+	if assert.Len(t, withGenesis, len(all)+1) {
+		genesisRef := storage.GenesisReference()
+		assert.Equal(t, string(genesisRef.Key()), withGenesis[0])
+	}
+
+	// pagination
+	var paged []string
+	q := storage.RecordQuery{FromPulse: 1, Limit: 4}
+	for i := 0; i < len(all); i++ {
+		var page []*record.Reference
+		err := it.IterateRecords(q, func(ref *record.Reference, rec record.Record) error {
+			page = append(page, ref)
+			return nil
+		})
+		mustNoError(t, err)
+		if len(page) == 0 {
+			break
+		}
+		assert.True(t, len(page) <= q.Limit)
+		for _, ref := range page {
+			paged = append(paged, string(ref.Key()))
+		}
+		q.After = page[len(page)-1]
+	}
+	assert.Equal(t, all, paged)
+
+	// early termination
+	calls := 0
+	err := it.IterateRecords(storage.RecordQuery{}, func(*record.Reference, record.Record) error {
+		calls++
+		return storage.ErrStopIteration
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	iterErr := errors.New("iteration failed")
+	err = it.IterateRecords(storage.RecordQuery{}, func(*record.Reference, record.Record) error {
+		return iterErr
+	})
+	assert.Equal(t, iterErr, err)
+}
+
+func testHashAlgorithms(t *testing.T, s storage.LedgerStorer) {
+	hs, ok := s.(hashAlgorithmSetter)
+	if !ok {
+		t.Skip("storage does not allow to set hash algorithm")
+	}
+	assert.Error(t, hs.SetHashAlgorithm(hash.Algorithm(255)))
+
+	algorithms := []hash.Algorithm{hash.SHA3224, hash.SHA3256, hash.BLAKE2b256}
+	recs := map[string]record.Record{}
+	var refs []*record.Reference
+	for _, alg := range algorithms {
+		mustNoError(t, hs.SetHashAlgorithm(alg))
+		rec := &record.CallRequest{ParamMemory: randHash()}
+		ref, err := s.SetRecord(rec)
+		mustNoError(t, err)
+		raw, err := record.EncodeToRaw(rec)
+		mustNoError(t, err)
+		assert.Equal(t, alg, ref.Record.Algorithm)
+		assert.Equal(t, raw.Sum(alg), ref.Record.Hash)
+		recs[string(ref.Key())] = rec
+		refs = append(refs, ref)
+	}
+	// the same record with different algorithms gets different references
+	assert.Len(t, recs, len(algorithms))
+
+	for _, ref := range refs {
+		got, err := s.GetRecord(ref)
+		mustNoError(t, err)
+		assert.Equal(t, recs[string(ref.Key())], got)
+	}
+	mustNoError(t, s.Update(func(b storage.Batch) error {
+		return b.SetRequestResult(refs[1], refs[2])
+	}))
+	resultRef, err := s.GetRequestResult(refs[1])
+	mustNoError(t, err)
+	assert.Equal(t, refs[2].Key(), resultRef.Key())
+
+	if it, ok := s.(storage.RecordIterator); ok {
+		got := collectRefs(t, it, storage.RecordQuery{Type: record.TypeIDOf(&record.CallRequest{})})
+		assert.Len(t, got, len(algorithms))
+		for _, k := range got {
+			assert.Contains(t, recs, k)
+		}
+	}
+}
+
+func testSignatures(t *testing.T, s storage.LedgerStorer) {
+	unsignedRef, err := s.SetRecord(&record.CallRequest{ParamMemory: randHash()})
+	mustNoError(t, err)
+	sig, err := s.GetRecordSignature(unsignedRef)
+	mustNoError(t, err)
+	assert.Nil(t, sig)
+	missing := randRef()
+	_, err = s.GetRecordSignature(&missing)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	ss, ok := s.(signatureSetter)
+	if !ok {
+		t.Skip("storage does not allow to sign records")
+	}
+	rs, ok := s.(storage.RawStorer)
+	if !ok {
+		t.Skip("storage does not implement RawStorer")
+	}
+	assert.Equal(t, record.ErrNotSigned, storage.VerifyRecord(rs, unsignedRef, nil))
+	assert.Equal(t, storage.ErrNotFound, storage.VerifyRecord(rs, &missing, nil))
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	mustNoError(t, err)
+	_, other, err := ed25519.GenerateKey(rand.Reader)
+	mustNoError(t, err)
+	keys, err := record.NewKeyRing(pub)
+	mustNoError(t, err)
+
+	ss.SetSigner(record.NewEd25519Signer(key))
+	ref, err := s.SetRecord(&record.CallRequest{ParamMemory: randHash()})
+	mustNoError(t, err)
+	assert.NoError(t, storage.VerifyRecord(rs, ref, keys))
+
+	ss.RequireSignatures(keys)
+	_, err = s.SetRecord(&record.CallRequest{ParamMemory: randHash()})
+	assert.NoError(t, err)
+
+	ss.SetSigner(record.NewEd25519Signer(other))
+	_, err = s.SetRecord(&record.CallRequest{ParamMemory: randHash()})
+	assert.Equal(t, record.ErrUnknownSigner, errors.Cause(err))
+
+	ss.SetSigner(nil)
+	_, err = s.SetRecord(&record.CallRequest{ParamMemory: randHash()})
+	assert.Equal(t, record.ErrNotSigned, errors.Cause(err))
+
+	raw, err := record.EncodeToRaw(&record.CallRequest{ParamMemory: randHash()})
+	mustNoError(t, err)
+	rawRef := &record.Reference{Record: record.ID{Hash: raw.Hash()}}
+	err = rs.UpdateRaw(func(b storage.RawBatch) error {
+		return b.SetRawRecord(rawRef, raw)
+	})
+	assert.Equal(t, record.ErrNotSigned, errors.Cause(err))
+
+	raw.Signature = record.NewEd25519Signer(key).Sign(rawRef.Record)
+	mustNoError(t, rs.UpdateRaw(func(b storage.RawBatch) error {
+		return b.SetRawRecord(rawRef, raw)
+	}))
+	assert.NoError(t, storage.VerifyRecord(rs, rawRef, keys))
+
+	// valid signature of the reference does not make other data valid
+	forged, err := record.EncodeToRaw(&record.CallRequest{ParamMemory: randHash()})
+	mustNoError(t, err)
+	forgedRef := &record.Reference{Record: record.ID{Hash: randHash()}}
+	forged.Signature = record.NewEd25519Signer(key).Sign(forgedRef.Record)
+	mustNoError(t, rs.UpdateRaw(func(b storage.RawBatch) error {
+		return b.SetRawRecord(forgedRef, forged)
+	}))
+	assert.Equal(t, storage.ErrCorrupted, errors.Cause(storage.VerifyRecord(rs, forgedRef, keys)))
+}
+
+func testPulseRoots(t *testing.T, s storage.LedgerStorer) {
+	rs, ok := s.(storage.PulseRootStorer)
+	if !ok {
+		t.Skip("storage does not implement PulseRootStorer")
+	}
+	_, err := rs.GetPulseRoot(1)
+	assert.Equal(t, storage.ErrNotFound, err)
+
+	root := randHash()
+	mustNoError(t, rs.SetPulseRoot(1, root))
+	got, err := rs.GetPulseRoot(1)
+	mustNoError(t, err)
+	assert.Equal(t, root, got)
+	_, err = rs.GetPulseRoot(2)
+	assert.Equal(t, storage.ErrNotFound, err)
+}
+
+func testReservedPulse(t *testing.T, s storage.LedgerStorer) {
+	ps, ok := s.(pulseSetter)
+	if !ok {
+		t.Skip("storage does not allow to set pulse provider")
+	}
+	if hs, ok := s.(hashAlgorithmSetter); ok {
+		// non-default algorithm is flagged in the upper bit of binary pulse number
+		mustNoError(t, hs.SetHashAlgorithm(hash.SHA3256))
+	}
+
+	ps.SetPulseProvider(fixedPulse(1 << 31))
+	_, err := s.SetRecord(&record.CallRequest{ParamMemory: randHash()})
+	assert.Equal(t, record.ErrReservedPulse, errors.Cause(err))
+	err = s.Update(func(b storage.Batch) error {
+		_, err := b.SetRecord(&record.CallRequest{ParamMemory: randHash()})
+		return err
+	})
+	assert.Equal(t, record.ErrReservedPulse, errors.Cause(err))
+	if rs, ok := s.(storage.RawStorer); ok {
+		raw, err := record.EncodeToRaw(&record.CallRequest{ParamMemory: randHash()})
+		mustNoError(t, err)
+		ref := record.Reference{Record: record.ID{Pulse: 1 << 31, Hash: raw.Hash()}}
+		err = rs.UpdateRaw(func(b storage.RawBatch) error {
+			return b.SetRawRecord(&ref, raw)
+		})
+		assert.Equal(t, record.ErrReservedPulse, errors.Cause(err))
+	}
+
+	ps.SetPulseProvider(fixedPulse(record.MaxPulseNum))
+	rec := &record.CallRequest{ParamMemory: randHash()}
+	ref, err := s.SetRecord(rec)
+	mustNoError(t, err)
+	assert.Equal(t, record.MaxPulseNum, ref.Record.Pulse)
+	got, err := s.GetRecord(ref)
+	mustNoError(t, err)
+	assert.Equal(t, rec, got)
+	if it, ok := s.(storage.RecordIterator); ok {
+		assert.Equal(t, []string{string(ref.Key())}, collectRefs(t, it, storage.RecordQuery{FromPulse: record.MaxPulseNum}))
+	}
+}
@@ -0,0 +1,113 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package builtin
+
+import (
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/artifactmanager"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/logicrunner"
+)
+
+// ErrUnknownContract is returned if there is no contract registered under the name stored in object's code record.
+var ErrUnknownContract = errors.New("unknown builtin contract")
+
+// ArchType is architecture of code records storing builtin contracts.
+const ArchType = record.ArchType(logicrunner.MachineTypeBuiltin)
+
+// Constructor creates zero contract value. It should return a pointer, object memory is decoded into it.
+type Constructor func() interface{}
+
+var (
+	lock      sync.RWMutex
+	contracts = map[string]Constructor{}
+)
+
+// Register registers contract under provided name. It panics if name is already registered.
+func Register(name string, c Constructor) {
+	lock.Lock()
+	defer lock.Unlock()
+	if _, ok := contracts[name]; ok {
+		panic("builtin contract " + name + " is already registered")
+	}
+	contracts[name] = c
+}
+
+// Deploy stores code record of registered contract.
+//
+// Code of the record is the contract name. Classes activated with returned code reference are executed by builtin
+// executor, as LedgerRunner passes code reference of object's class to executors.
+func Deploy(am artifactmanager.ArtifactManager, requestRef record.Reference, name string) (*record.Reference, error) {
+	if _, err := lookup(name); err != nil {
+		return nil, err
+	}
+	codeRef, err := am.DeployCode(requestRef, map[record.ArchType][]byte{ArchType: []byte(name)})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to deploy builtin contract %s", name)
+	}
+	return codeRef, nil
+}
+
+func lookup(name string) (Constructor, error) {
+	lock.RLock()
+	c, ok := contracts[name]
+	lock.RUnlock()
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownContract, "%s", name)
+	}
+	return c, nil
+}
+
+// Builtin is an executor of registered builtin contracts. Object reference is the code record reference contract is
+// deployed with. Contract name is fetched from the code record through artifact manager, so its architecture
+// preferences should include ArchType.
+type Builtin struct {
+	ArtifactManager artifactmanager.ArtifactManager
+}
+
+// NewBuiltin creates builtin executor fetching code records through provided artifact manager.
+func NewBuiltin(am artifactmanager.ArtifactManager) *Builtin {
+	return &Builtin{ArtifactManager: am}
+}
+
+// Start does nothing, builtin contracts need no preparation.
+func (b *Builtin) Start() {}
+
+// Stop does nothing.
+func (b *Builtin) Stop() {}
+
+// Exec runs method of the object in-process.
+func (b *Builtin) Exec(object logicrunner.Object, method string, args logicrunner.Arguments) (
+	[]byte, logicrunner.Arguments, error,
+) {
+	codeRef, err := record.ParseReference(string(object.Reference))
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "invalid code reference")
+	}
+	name, err := b.ArtifactManager.GetCode(codeRef)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "failed to fetch code %s", object.Reference)
+	}
+	c, err := lookup(string(name))
+	if err != nil {
+		return nil, nil, err
+	}
+	return logicrunner.CallMethod(c(), object.Data, method, args)
+}
@@ -0,0 +1,111 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package builtin
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+
+	"github.com/insolar/insolar/ledger/artifactmanager"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage/memory"
+	"github.com/insolar/insolar/logicrunner"
+)
+
+type greeter struct {
+	Greeted int
+}
+
+func (g *greeter) Greet(name string) string {
+	g.Greeted++
+	return "hello " + name
+}
+
+func init() {
+	Register("builtin.greeter", func() interface{} { return &greeter{} })
+}
+
+func TestDeploy(t *testing.T) {
+	am := artifactmanager.NewArtifactManager(memory.NewMemLedger())
+	am.SetArchPref([]record.ArchType{ArchType})
+	requestRef, err := am.RegisterRequest(&record.CallRequest{})
+	assert.NoError(t, err)
+
+	_, err = Deploy(am, *requestRef, "builtin.unknown")
+	assert.Equal(t, ErrUnknownContract, errors.Cause(err))
+	codeRef, err := Deploy(am, *requestRef, "builtin.greeter")
+	assert.NoError(t, err)
+	code, err := am.GetCode(*codeRef)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("builtin.greeter"), code)
+}
+
+func TestBuiltin_Exec(t *testing.T) {
+	am := artifactmanager.NewArtifactManager(memory.NewMemLedger())
+	am.SetArchPref([]record.ArchType{ArchType})
+	requestRef, err := am.RegisterRequest(&record.CallRequest{})
+	assert.NoError(t, err)
+	codeRef, err := Deploy(am, *requestRef, "builtin.greeter")
+	assert.NoError(t, err)
+
+	ch := new(codec.CborHandle)
+	var data, args []byte
+	assert.NoError(t, codec.NewEncoderBytes(&data, ch).Encode(greeter{Greeted: 1}))
+	assert.NoError(t, codec.NewEncoderBytes(&args, ch).Encode([]interface{}{"world"}))
+
+	var b logicrunner.Executor = NewBuiltin(am)
+	b.Start()
+	defer b.Stop()
+	object := logicrunner.Object{
+		MachineType: logicrunner.MachineTypeBuiltin,
+		Reference:   logicrunner.Reference(codeRef.String()),
+		Data:        data,
+	}
+	data, ret, err := b.Exec(object, "Greet", args)
+	assert.NoError(t, err)
+
+	var g greeter
+	assert.NoError(t, codec.NewDecoderBytes(data, ch).Decode(&g))
+	assert.Equal(t, greeter{Greeted: 2}, g)
+	var res []interface{}
+	assert.NoError(t, codec.NewDecoderBytes(ret, ch).Decode(&res))
+	assert.Equal(t, []interface{}{"hello world"}, res)
+
+	// Contract is resolved from the code record, not from the executor deploying it.
+	_, _, err = NewBuiltin(am).Exec(object, "Greet", args)
+	assert.NoError(t, err)
+
+	requestRef, err = am.RegisterRequest(&record.CallRequest{ParamMemory: record.Memory{1}})
+	assert.NoError(t, err)
+	unknownRef, err := am.DeployCode(*requestRef, map[record.ArchType][]byte{ArchType: []byte("builtin.unknown")})
+	assert.NoError(t, err)
+	object.Reference = logicrunner.Reference(unknownRef.String())
+	_, _, err = b.Exec(object, "Greet", args)
+	assert.Equal(t, ErrUnknownContract, errors.Cause(err))
+	object.Reference = "builtin.unknown"
+	_, _, err = b.Exec(object, "Greet", args)
+	assert.Equal(t, record.ErrInvalidReference, errors.Cause(err))
+}
+
+func TestRegister_PanicsOnDuplicates(t *testing.T) {
+	assert.Panics(t, func() {
+		Register("builtin.greeter", func() interface{} { return &greeter{} })
+	})
+}
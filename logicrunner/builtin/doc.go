@@ -0,0 +1,35 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+// Package builtin provides executor of contracts compiled into the node binary.
+//
+// Contracts are registered at compile time, usually from init functions of contract packages:
+//
+//	func init() {
+//		builtin.Register("example.Wallet", func() interface{} { return &Wallet{} })
+//	}
+//
+// Executor runs contracts by code record reference, so registered contract is deployed to ledger before its classes
+// are activated:
+//
+//	codeRef, err := builtin.Deploy(am, requestRef, "example.Wallet")
+//
+// Code of the deployed record is the contract name. Executor resolves contracts from code records on every call, so
+// deployed contracts are executed after node restart as long as they are registered under the same name.
+//
+// Builtin executor runs calls in-process with the same CBOR conventions as goplugin's ginsider
+// (see logicrunner.CallMethod), so system contracts don't need plugin subprocesses.
+package builtin
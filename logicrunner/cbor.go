@@ -0,0 +1,97 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package logicrunner
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+	"github.com/ugorji/go/codec"
+)
+
+// ErrUnknownMethod is returned if contract has no called method.
+var ErrUnknownMethod = errors.New("unknown method")
+
+// CallMethod runs method of the contract using CBOR conventions shared by executors.
+//
+// Contract should be a pointer. Object data is CBOR encoded contract, it is decoded into contract before the call and
+// contract is encoded back as new object data after it. Arguments are CBOR array of method arguments, returned values
+// are encoded as CBOR array too. Panic in the method is returned as an error.
+func CallMethod(contract interface{}, data []byte, method string, args Arguments) (
+	newData []byte, ret Arguments, err error,
+) {
+	ch := new(codec.CborHandle)
+
+	err = codec.NewDecoderBytes(data, ch).Decode(contract)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "couldn't decode data into %T", contract)
+	}
+
+	m := reflect.ValueOf(contract).MethodByName(method)
+	if !m.IsValid() {
+		return nil, nil, errors.Wrapf(ErrUnknownMethod, "%T.%s", contract, method)
+	}
+
+	inLen := m.Type().NumIn()
+	mask := make([]interface{}, inLen)
+	for i := 0; i < inLen; i++ {
+		mask[i] = reflect.New(m.Type().In(i)).Interface()
+	}
+	err = codec.NewDecoderBytes(args, ch).Decode(&mask)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "couldn't unmarshal CBOR for arguments of the method")
+	}
+	if len(mask) != inLen {
+		return nil, nil, errors.Errorf("method %s expects %d arguments, got %d", method, inLen, len(mask))
+	}
+	in := make([]reflect.Value, inLen)
+	for i := 0; i < inLen; i++ {
+		in[i] = reflect.ValueOf(mask[i]).Elem()
+	}
+
+	resValues, err := call(m, in)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "method %s failed", method)
+	}
+
+	err = codec.NewEncoderBytes(&newData, ch).Encode(contract)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "couldn't marshal new object data into cbor")
+	}
+
+	res := make([]interface{}, len(resValues))
+	for i, v := range resValues {
+		res[i] = v.Interface()
+	}
+	err = codec.NewEncoderBytes((*[]byte)(&ret), ch).Encode(res)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "couldn't marshal returned values into cbor")
+	}
+
+	return newData, ret, nil
+}
+
+// call calls the method recovering panic.
+func call(m reflect.Value, in []reflect.Value) (res []reflect.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return m.Call(in), nil
+}
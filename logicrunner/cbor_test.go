@@ -0,0 +1,73 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package logicrunner
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/ugorji/go/codec"
+)
+
+type counter struct {
+	Name  string
+	Count int
+}
+
+func (c *counter) Add(n int, suffix string) (int, string) {
+	c.Count += n
+	return c.Count, c.Name + suffix
+}
+
+func (c *counter) Fail() {
+	panic("failed")
+}
+
+func cborEncode(t *testing.T, v interface{}) []byte {
+	var b []byte
+	assert.NoError(t, codec.NewEncoderBytes(&b, new(codec.CborHandle)).Encode(v))
+	return b
+}
+
+func TestCallMethod(t *testing.T) {
+	data := cborEncode(t, counter{Name: "c", Count: 1})
+	newData, ret, err := CallMethod(&counter{}, data, "Add", cborEncode(t, []interface{}{2, "!"}))
+	assert.NoError(t, err)
+
+	var c counter
+	assert.NoError(t, codec.NewDecoderBytes(newData, new(codec.CborHandle)).Decode(&c))
+	assert.Equal(t, counter{Name: "c", Count: 3}, c)
+	var res []interface{}
+	assert.NoError(t, codec.NewDecoderBytes(ret, new(codec.CborHandle)).Decode(&res))
+	assert.Equal(t, []interface{}{uint64(3), "c!"}, res)
+}
+
+func TestCallMethod_Errors(t *testing.T) {
+	data := cborEncode(t, counter{})
+
+	_, _, err := CallMethod(&counter{}, data, "Sub", cborEncode(t, []interface{}{}))
+	assert.Equal(t, ErrUnknownMethod, errors.Cause(err))
+	_, _, err = CallMethod(&counter{}, data, "Add", cborEncode(t, []interface{}{1}))
+	assert.Error(t, err)
+	_, _, err = CallMethod(&counter{}, data, "Add", cborEncode(t, []interface{}{"1", 1}))
+	assert.Error(t, err)
+	_, _, err = CallMethod(&counter{}, []byte{0xff}, "Add", cborEncode(t, []interface{}{1, ""}))
+	assert.Error(t, err)
+	_, _, err = CallMethod(&counter{}, data, "Fail", cborEncode(t, []interface{}{}))
+	assert.EqualError(t, err, "method Fail failed: panic: failed")
+}
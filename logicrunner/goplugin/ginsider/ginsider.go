@@ -17,27 +17,38 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"encoding/gob"
+	"io"
 	"io/ioutil"
 	"log"
 	"net"
 	"net/http"
 	"net/rpc"
 	"os"
+	"os/signal"
 	"plugin"
-	"reflect"
+	"sync"
+	"syscall"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
-	"github.com/ugorji/go/codec"
 
 	"github.com/insolar/insolar/logicrunner"
 	"github.com/insolar/insolar/logicrunner/goplugin/girpc"
 )
 
+// ErrStopping is returned by calls made after ginsider began to stop.
+var ErrStopping = errors.New("ginsider is stopping")
+
 // GoInsider is an RPC interface to run code of plugins
 type GoInsider struct {
 	dir        string
 	RPCAddress string
+
+	lock     sync.Mutex
+	stopping bool
 }
 
 // NewGoInsider creates a new GoInsider instance validating arguments
@@ -46,9 +57,26 @@ func NewGoInsider(path string, address string) *GoInsider {
 	return &GoInsider{dir: path, RPCAddress: address}
 }
 
+// stop makes new calls fail with ErrStopping. Running calls are waited by connServer.drain.
+func (t *GoInsider) stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.stopping = true
+}
+
+func (t *GoInsider) isStopping() bool {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.stopping
+}
+
 // Call is an RPC that runs a method on an object and
-// returns a new state of the object and result of the method
+// returns a new state of the object and result of the method (see logicrunner.CallMethod)
 func (t *GoInsider) Call(args girpc.CallReq, reply *girpc.CallResp) error {
+	if t.isStopping() {
+		return ErrStopping
+	}
+
 	path, err := t.ObtainCode(args.Object)
 	if err != nil {
 		return errors.Wrap(err, "couldn't obtain code")
@@ -64,56 +92,13 @@ func (t *GoInsider) Call(args girpc.CallReq, reply *girpc.CallResp) error {
 		return errors.Wrap(err, "couldn't lookup 'INSEXPORT' in '"+path+"'")
 	}
 
-	ch := new(codec.CborHandle)
-
-	err = codec.NewDecoderBytes(args.Object.Data, ch).Decode(export)
-	if err != nil {
-		return errors.Wrapf(err, "couldn't decode data into %T", export)
-	}
-
-	method := reflect.ValueOf(export).MethodByName(args.Method)
-	if !method.IsValid() {
-		return errors.New("wtf, no method " + args.Method + "in the plugin")
-	}
-
-	inLen := method.Type().NumIn()
-
-	mask := make([]interface{}, inLen)
-	for i := 0; i < inLen; i++ {
-		argType := method.Type().In(i)
-		mask[i] = reflect.Zero(argType).Interface()
-	}
-
-	err = codec.NewDecoderBytes(args.Arguments, ch).Decode(&mask)
-	if err != nil {
-		return errors.Wrap(err, "couldn't unmarshal CBOR for arguments of the method")
-	}
-
-	in := make([]reflect.Value, inLen)
-	for i := 0; i < inLen; i++ {
-		in[i] = reflect.ValueOf(mask[i])
-	}
-
-	resValues := method.Call(in)
-
-	err = codec.NewEncoderBytes(&reply.Data, ch).Encode(export)
-	if err != nil {
-		return errors.Wrap(err, "couldn't marshal new object data into cbor")
-	}
-
-	res := make([]interface{}, len(resValues))
-	for i, v := range resValues {
-		res[i] = v.Interface()
-	}
-
-	var resSerialized []byte
-	err = codec.NewEncoderBytes(&resSerialized, ch).Encode(res)
-	if err != nil {
-		return errors.Wrap(err, "couldn't marshal returned values into cbor")
-	}
-
-	reply.Ret = resSerialized
+	reply.Data, reply.Ret, err = logicrunner.CallMethod(export, args.Object.Data, args.Method, args.Arguments)
+	return err
+}
 
+// Ping is an RPC used to check that connection to the runner is alive, it replies with the request
+func (t *GoInsider) Ping(req int, reply *int) error {
+	*reply = req
 	return nil
 }
 
@@ -152,27 +137,173 @@ func main() {
 	listen := pflag.StringP("listen", "l", ":7777", "address and port to listen")
 	path := pflag.StringP("directory", "d", "", "directory where to store code of go plugins")
 	rpcAddress := pflag.String("rpc", "localhost:7778", "address and port of RPC API")
+	readyFD := pflag.Int("ready-fd", 0, "file descriptor to report readiness to")
 	pflag.Parse()
 
 	insider := NewGoInsider(*path, *rpcAddress)
 	err := rpc.Register(insider)
 	if err != nil {
 		log.Fatal("Couldn't register RPC interface: ", err)
-		os.Exit(1)
 	}
 
-	rpc.HandleHTTP()
+	conns := newConnServer()
+	http.Handle(rpc.DefaultRPCPath, conns)
 	listener, err := net.Listen("tcp", *listen)
-
-	log.Print("ginsider launched, listens " + *listen)
 	if err != nil {
 		log.Fatal("listen error:", err)
-		os.Exit(1)
 	}
-	err = http.Serve(listener, nil)
-	if err != nil {
+	log.Print("ginsider launched, listens " + *listen)
+
+	// Graceful shutdown: stop accepting connections and calls, wait for replies of running calls and close
+	// connections.
+	server := &http.Server{}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	go func() {
+		<-signals
+		if err := server.Shutdown(context.Background()); err != nil {
+			log.Print("couldn't shutdown server: ", err)
+		}
+	}()
+
+	if *readyFD > 0 {
+		ready := os.NewFile(uintptr(*readyFD), "ready")
+		_, err = ready.WriteString("ready\n")
+		if err != nil {
+			log.Fatal("couldn't report readiness: ", err)
+		}
+		_ = ready.Close()
+	}
+
+	err = server.Serve(listener)
+	if err != http.ErrServerClosed {
 		log.Fatal("couldn't start server: ", err)
-		os.Exit(1)
 	}
+	// RPC connections are hijacked from HTTP server, so Shutdown leaves them open. They are closed after running
+	// calls have sent their replies.
+	insider.stop()
+	conns.drain()
 	log.Print("bye\n")
 }
+
+// connServer serves RPC connections made with rpc.DialHTTP. It counts read requests until their replies are sent,
+// so connections can be closed without cutting replies.
+type connServer struct {
+	lock    sync.Mutex
+	drained *sync.Cond
+	pending int
+	conns   map[net.Conn]struct{}
+}
+
+func newConnServer() *connServer {
+	s := &connServer{conns: map[net.Conn]struct{}{}}
+	s.drained = sync.NewCond(&s.lock)
+	return s
+}
+
+// ServeHTTP hijacks connection and serves RPC on it like rpc.Server.ServeHTTP does.
+func (s *connServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != "CONNECT" {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		_, _ = io.WriteString(w, "405 must CONNECT\n")
+		return
+	}
+	conn, _, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		log.Print("rpc hijacking ", req.RemoteAddr, ": ", err.Error())
+		return
+	}
+	_, _ = io.WriteString(conn, "HTTP/1.0 200 Connected to Go RPC\n\n")
+
+	s.lock.Lock()
+	s.conns[conn] = struct{}{}
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		delete(s.conns, conn)
+		s.lock.Unlock()
+	}()
+
+	buf := bufio.NewWriter(conn)
+	rpc.ServeCodec(&trackingCodec{
+		rwc: conn,
+		dec: gob.NewDecoder(conn),
+		enc: gob.NewEncoder(buf),
+		buf: buf,
+		s:   s,
+	})
+}
+
+// drain waits for replies to all read requests and closes connections.
+func (s *connServer) drain() {
+	s.lock.Lock()
+	for s.pending > 0 {
+		s.drained.Wait()
+	}
+	conns := make([]net.Conn, 0, len(s.conns))
+	for c := range s.conns {
+		conns = append(conns, c)
+	}
+	s.lock.Unlock()
+	for _, c := range conns {
+		_ = c.Close()
+	}
+}
+
+func (s *connServer) begin() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pending++
+}
+
+func (s *connServer) end() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pending--
+	if s.pending == 0 {
+		s.drained.Broadcast()
+	}
+}
+
+// trackingCodec is a gob codec of net/rpc which reports read requests and sent replies to connServer. Server sends
+// exactly one reply to every request which header was read.
+type trackingCodec struct {
+	rwc io.ReadWriteCloser
+	dec *gob.Decoder
+	enc *gob.Encoder
+	buf *bufio.Writer
+	s   *connServer
+}
+
+func (c *trackingCodec) ReadRequestHeader(r *rpc.Request) error {
+	if err := c.dec.Decode(r); err != nil {
+		return err
+	}
+	c.s.begin()
+	return nil
+}
+
+func (c *trackingCodec) ReadRequestBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+func (c *trackingCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	defer c.s.end()
+	err := c.enc.Encode(r)
+	if err == nil {
+		err = c.enc.Encode(body)
+	}
+	if err == nil {
+		err = c.buf.Flush()
+	}
+	if err != nil {
+		// Partially written reply breaks the stream.
+		_ = c.rwc.Close()
+	}
+	return err
+}
+
+func (c *trackingCodec) Close() error {
+	return c.rwc.Close()
+}
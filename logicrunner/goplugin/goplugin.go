@@ -24,10 +24,11 @@ import (
 	"net/http"
 	"net/rpc"
 	"os"
-	"os/exec"
-
+	"sync"
 	"time"
 
+	"github.com/insolar/insolar/ledger/artifactmanager"
+	"github.com/insolar/insolar/ledger/record"
 	"github.com/insolar/insolar/logicrunner"
 	"github.com/insolar/insolar/logicrunner/goplugin/girpc"
 	"github.com/pkg/errors"
@@ -39,6 +40,9 @@ type Options struct {
 	Listen string
 	// CodePath is path to directory with plugin's code, this should go away at some point
 	CodePath string
+	// ArtifactManager is used to fetch code from ledger, references are treated as code record references. It is required
+	// to run objects with logicrunner.LedgerRunner. CodePath is not used if it is set.
+	ArtifactManager artifactmanager.ArtifactManager
 }
 
 // RunnerOptions - set of options to control internal isolated code runner(s)
@@ -47,14 +51,39 @@ type RunnerOptions struct {
 	Listen string
 	// CodeStoragePath is path to directory where the runner caches code
 	CodeStoragePath string
+	// Path is path to the runner binary, "ginsider/ginsider" by default
+	Path string
+	// ReadyTimeout is how long to wait for the runner to become ready after launch, 5 seconds by default
+	ReadyTimeout time.Duration
+	// MinRestartDelay is delay before the first restart of crashed runner, it doubles on each failed restart up to
+	// MaxRestartDelay. Defaults are 100 milliseconds and 5 seconds.
+	MinRestartDelay time.Duration
+	MaxRestartDelay time.Duration
+	// StopTimeout is how long to wait for the runner to exit on Stop before killing it, 5 seconds by default
+	StopTimeout time.Duration
+	// MaxConnections is the maximum number of persistent connections to the runner, 8 by default. Calls wait for
+	// a free connection when all of them are busy.
+	MaxConnections int
 }
 
+// Errors returned by Exec.
+var (
+	// ErrRunnerUnavailable is returned if the runner is not running, e.g. it is restarted after a crash.
+	ErrRunnerUnavailable = errors.New("runner is not running")
+	// ErrRunnerCrashed is returned if the runner exited or lost connection during the call.
+	ErrRunnerCrashed = errors.New("runner crashed during the call")
+	// ErrTimeout is returned if the call is not finished in time.
+	ErrTimeout = errors.New("timeout")
+)
+
 // GoPlugin is a logic runner of code written in golang and compiled as go plugins
 type GoPlugin struct {
 	Options       Options
 	RunnerOptions RunnerOptions
-	sock          net.Listener
-	runner        *exec.Cmd
+
+	lock       sync.Mutex
+	sock       net.Listener
+	supervisor *supervisor
 }
 
 // RPC is a RPC interface for runner to use for variouse tasks, e.g. code fetching
@@ -65,6 +94,15 @@ type RPC struct {
 // GetObject is an RPC retriving an object by its reference, so far short circueted to return
 // code of the plugin
 func (gpr *RPC) GetObject(ref logicrunner.Reference, reply *logicrunner.Object) error {
+	if am := gpr.gp.Options.ArtifactManager; am != nil {
+		codeRef, err := record.ParseReference(string(ref))
+		if err != nil {
+			return err
+		}
+		reply.Data, err = am.GetCode(codeRef)
+		return err
+	}
+
 	f, err := os.Open(gpr.gp.Options.CodePath + string(ref) + ".so")
 	if err != nil {
 		return err
@@ -83,79 +121,145 @@ func NewGoPlugin(options Options, runnerOptions RunnerOptions) (*GoPlugin, error
 	if gp.Options.Listen == "" {
 		gp.Options.Listen = "127.0.0.1:7777"
 	}
-
-	var runnerArguments []string
-	if gp.RunnerOptions.Listen != "" {
-		runnerArguments = append(runnerArguments, "-l", gp.RunnerOptions.Listen)
-	} else {
+	if gp.RunnerOptions.Listen == "" {
 		return nil, errors.New("listen is not optional in gp.RunnerOptions")
 	}
-	if gp.RunnerOptions.CodeStoragePath != "" {
-		runnerArguments = append(runnerArguments, "-d", gp.RunnerOptions.CodeStoragePath)
+	if gp.RunnerOptions.Path == "" {
+		gp.RunnerOptions.Path = "ginsider/ginsider"
+	}
+	if gp.RunnerOptions.ReadyTimeout == 0 {
+		gp.RunnerOptions.ReadyTimeout = 5 * time.Second
+	}
+	if gp.RunnerOptions.MinRestartDelay == 0 {
+		gp.RunnerOptions.MinRestartDelay = 100 * time.Millisecond
+	}
+	if gp.RunnerOptions.MaxRestartDelay == 0 {
+		gp.RunnerOptions.MaxRestartDelay = 5 * time.Second
+	}
+	if gp.RunnerOptions.StopTimeout == 0 {
+		gp.RunnerOptions.StopTimeout = 5 * time.Second
+	}
+	if gp.RunnerOptions.MaxConnections == 0 {
+		gp.RunnerOptions.MaxConnections = 8
 	}
-	runnerArguments = append(runnerArguments, "--rpc", gp.Options.Listen)
 
-	runner := exec.Command("ginsider/ginsider", runnerArguments...)
-	runner.Stdout = os.Stdout
-	runner.Stderr = os.Stderr
-	err := runner.Start()
+	err := gp.start()
 	if err != nil {
 		return nil, err
 	}
-	time.Sleep(200 * time.Millisecond)
-	gp.runner = runner
-	go gp.Start()
 	return &gp, nil
 }
 
-// Start starts runner and RPC interface to help runner, note that NewGoPlugin does
-// this for you
+// runnerArguments returns command line arguments of the runner.
+func (gp *GoPlugin) runnerArguments() []string {
+	args := []string{"-l", gp.RunnerOptions.Listen}
+	if gp.RunnerOptions.CodeStoragePath != "" {
+		args = append(args, "-d", gp.RunnerOptions.CodeStoragePath)
+	}
+	return append(args, "--rpc", gp.Options.Listen)
+}
+
+// start starts RPC interface and the runner.
+func (gp *GoPlugin) start() error {
+	gp.lock.Lock()
+	defer gp.lock.Unlock()
+	if gp.sock == nil {
+		server := rpc.NewServer()
+		err := server.Register(&RPC{gp: gp})
+		if err != nil {
+			return errors.Wrap(err, "couldn't register RPC interface")
+		}
+		l, err := net.Listen("tcp", gp.Options.Listen)
+		if err != nil {
+			return errors.Wrap(err, "listen error")
+		}
+		gp.sock = l
+		go func() {
+			log.Printf("START")
+			_ = http.Serve(l, server)
+			log.Printf("STOP")
+		}()
+	}
+	if gp.supervisor == nil {
+		s := newSupervisor(gp.RunnerOptions.Path, gp.runnerArguments(), gp.RunnerOptions)
+		err := s.start()
+		if err != nil {
+			return err
+		}
+		gp.supervisor = s
+	}
+	return nil
+}
+
+// Start starts RPC interface and runner, note that NewGoPlugin does
+// this for you. Calling Start on started GoPlugin does nothing.
 func (gp *GoPlugin) Start() {
-	r := RPC{gp: gp}
-	_ = rpc.Register(&r)
-	rpc.HandleHTTP()
-	l, e := net.Listen("tcp", gp.Options.Listen)
-	if e != nil {
-		log.Fatal("listen error:", e)
-	}
-	gp.sock = l
-	log.Printf("START")
-	_ = http.Serve(l, nil)
-	log.Printf("STOP")
+	err := gp.start()
+	if err != nil {
+		log.Printf("couldn't start GoPlugin: %v", err)
+	}
 }
 
-// Stop stops runner(s) and RPC service
+// Stop gracefully stops runner(s) and RPC service. Calling Stop on stopped GoPlugin does nothing.
 func (gp *GoPlugin) Stop() {
-	err := gp.runner.Process.Kill()
-	if err != nil {
-		log.Fatal(err)
+	gp.lock.Lock()
+	defer gp.lock.Unlock()
+	if gp.supervisor != nil {
+		gp.supervisor.stop()
+		gp.supervisor = nil
 	}
 
 	if gp.sock != nil {
-		err = gp.sock.Close()
+		err := gp.sock.Close()
 		if err != nil {
-			log.Fatal(err)
+			log.Printf("couldn't close RPC socket: %v", err)
 		}
+		gp.sock = nil
 	}
 }
 
 const timeout = time.Second * 5
 
+// runner returns running runner process.
+func (gp *GoPlugin) runner() *process {
+	gp.lock.Lock()
+	defer gp.lock.Unlock()
+	if gp.supervisor == nil {
+		return nil
+	}
+	return gp.supervisor.current()
+}
+
 // Exec runs a method on an object in controlled environment
 func (gp *GoPlugin) Exec(object logicrunner.Object, method string, args logicrunner.Arguments) ([]byte, logicrunner.Arguments, error) {
-	client, err := rpc.DialHTTP("tcp", gp.RunnerOptions.Listen)
+	p := gp.runner()
+	if p == nil {
+		return nil, nil, ErrRunnerUnavailable
+	}
+	client, err := p.conns.get()
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "problem with rpc connection")
+		return nil, nil, errors.Wrap(ErrRunnerUnavailable, err.Error())
 	}
 	res := girpc.CallResp{}
 
 	select {
 	case call := <-client.Go("GoInsider.Call", girpc.CallReq{Object: object, Method: method, Arguments: args}, &res, nil).Done:
 		if call.Error != nil {
-			return nil, nil, errors.Wrap(err, "problem with API call")
+			if _, ok := call.Error.(rpc.ServerError); !ok {
+				p.conns.discard(client)
+				return nil, nil, errors.Wrap(ErrRunnerCrashed, call.Error.Error())
+			}
+			p.conns.put(client)
+			return nil, nil, errors.Wrap(call.Error, "problem with API call")
 		}
+	case <-p.exited:
+		p.conns.discard(client)
+		return nil, nil, ErrRunnerCrashed
 	case <-time.After(timeout):
-		return nil, nil, errors.New("timeout")
+		// The call is still running, so the connection can't be reused.
+		p.conns.discard(client)
+		return nil, nil, ErrTimeout
 	}
+	p.conns.put(client)
 	return res.Data, res.Ret, res.Err
 }
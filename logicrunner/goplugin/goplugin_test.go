@@ -2,6 +2,7 @@ package goplugin
 
 import (
 	"io/ioutil"
+	"net/rpc"
 	"os"
 	"os/exec"
 	"testing"
@@ -9,7 +10,11 @@ import (
 	"github.com/pkg/errors"
 	"github.com/ugorji/go/codec"
 
+	"github.com/insolar/insolar/ledger/artifactmanager"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage/memory"
 	"github.com/insolar/insolar/logicrunner"
+	"github.com/insolar/insolar/logicrunner/goplugin/girpc"
 )
 
 type HelloWorlder struct {
@@ -58,6 +63,8 @@ func (r *HelloWorlder) ProxyEcho(gp *GoPlugin, s string) string {
 	return resParsed[0].(string)
 }
 
+var _ logicrunner.Executor = (*GoPlugin)(nil)
+
 func compileBinaries() error {
 	d, _ := os.Getwd()
 
@@ -120,3 +127,176 @@ func TestHelloWorld(t *testing.T) {
 		t.Fatalf("Got unexpected value: %s, 'hi there here we are' is expected", res)
 	}
 }
+
+func TestLedgerHelloWorld(t *testing.T) {
+	if err := compileBinaries(); err != nil {
+		t.Fatal("Can't compile binaries", err)
+	}
+	dir, err := ioutil.TempDir("", "test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	code, err := ioutil.ReadFile("./testplugins/secondary.so")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch := new(codec.CborHandle)
+	var data []byte
+	err = codec.NewEncoderBytes(&data, ch).Encode(HelloWorlder{77})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	am := artifactmanager.NewArtifactManager(memory.NewMemLedger())
+	am.SetArchPref([]record.ArchType{1})
+	requestRef, err := am.RegisterRequest(&record.CallRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	codeRef, err := am.DeployCode(*requestRef, map[record.ArchType][]byte{1: code})
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestRef, err = am.RegisterRequest(&record.CallRequest{ParamMemory: record.Memory{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	classRef, err := am.ActivateClass(*requestRef, *codeRef, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	requestRef, err = am.RegisterRequest(&record.CallRequest{RequestRecord: record.RequestRecord{Target: *classRef}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	objRef, err := am.ActivateObj(*requestRef, *classRef, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gp, err := NewGoPlugin(
+		Options{
+			Listen:          "127.0.0.1:7780",
+			ArtifactManager: am,
+		},
+		RunnerOptions{
+			Listen:          "127.0.0.1:7779",
+			CodeStoragePath: dir,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := logicrunner.NewRunner()
+	err = runner.RegisterExecutor(logicrunner.MachineTypeGoPlugin, gp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lr := logicrunner.NewLedgerRunner(runner, am)
+	lr.Start()
+	defer lr.Stop()
+
+	var args []byte
+	err = codec.NewEncoderBytes(&args, ch).Encode([]interface{}{"hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := logicrunner.Object{MachineType: logicrunner.MachineTypeGoPlugin, Reference: logicrunner.Reference(objRef.String())}
+	for i := 0; i < 2; i++ {
+		_, err = lr.Exec(obj, "Echo", args)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, objDesc, err := am.GetLatestObj(*objRef, record.Reference{}, record.Reference{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem, err := objDesc.GetMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var hw HelloWorlder
+	err = codec.NewDecoderBytes(mem, ch).Decode(&hw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hw.Greeted != 79 {
+		t.Fatalf("Got unexpected value: %d, 79 is expected", hw.Greeted)
+	}
+}
+
+// prepareBenchmark starts GoPlugin with test plugins and returns it with an Echo call of the secondary plugin.
+func prepareBenchmark(b *testing.B, listen, runnerListen string) (*GoPlugin, girpc.CallReq, func()) {
+	if err := compileBinaries(); err != nil {
+		b.Fatal("Can't compile binaries", err)
+	}
+	dir, err := ioutil.TempDir("", "test-")
+	if err != nil {
+		b.Fatal(err)
+	}
+	gp, err := NewGoPlugin(
+		Options{Listen: listen, CodePath: "./testplugins/"},
+		RunnerOptions{Listen: runnerListen, CodeStoragePath: dir},
+	)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ch := new(codec.CborHandle)
+	var data, args []byte
+	err = codec.NewEncoderBytes(&data, ch).Encode(HelloWorlder{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	err = codec.NewEncoderBytes(&args, ch).Encode([]interface{}{"hi"})
+	if err != nil {
+		b.Fatal(err)
+	}
+	req := girpc.CallReq{
+		Object:    logicrunner.Object{MachineType: logicrunner.MachineTypeGoPlugin, Reference: "secondary", Data: data},
+		Method:    "Echo",
+		Arguments: args,
+	}
+	return gp, req, func() {
+		gp.Stop()
+		os.RemoveAll(dir) // nolint: errcheck
+	}
+}
+
+// BenchmarkExec measures repeated calls through pooled connections.
+func BenchmarkExec(b *testing.B) {
+	gp, req, cleanup := prepareBenchmark(b, "127.0.0.1:7786", "127.0.0.1:7785")
+	defer cleanup()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _, err := gp.Exec(req.Object, req.Method, req.Arguments)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkExec_DialPerCall measures the same calls dialing the runner for each of them, as Exec did without pool.
+func BenchmarkExec_DialPerCall(b *testing.B) {
+	gp, req, cleanup := prepareBenchmark(b, "127.0.0.1:7788", "127.0.0.1:7787")
+	defer cleanup()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client, err := rpc.DialHTTP("tcp", gp.RunnerOptions.Listen)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var res girpc.CallResp
+		err = client.Call("GoInsider.Call", req, &res)
+		if err != nil {
+			b.Fatal(err)
+		}
+		client.Close() // nolint: errcheck
+	}
+}
@@ -0,0 +1,139 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package goplugin
+
+import (
+	"net/rpc"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// healthCheckIdle is how long connection should stay idle to be checked before reuse.
+const healthCheckIdle = time.Second
+
+// errPoolClosed is returned by connPool.get after the pool is closed.
+var errPoolClosed = errors.New("connection pool is closed")
+
+// pooledConn is an idle connection of the pool.
+type pooledConn struct {
+	client *rpc.Client
+	used   time.Time
+}
+
+// connPool is a bounded pool of persistent RPC connections to the runner.
+//
+// At most size connections are open at once, get waits for a released connection when all of them are in use.
+// Connections idle for longer than healthCheckIdle are pinged before reuse, broken ones are replaced by new ones.
+type connPool struct {
+	addr  string
+	slots chan struct{}    // holds a value for every open connection
+	idle  chan *pooledConn // idle open connections
+
+	lock   sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+func newConnPool(addr string, size int) *connPool {
+	return &connPool{
+		addr:  addr,
+		slots: make(chan struct{}, size),
+		idle:  make(chan *pooledConn, size),
+		done:  make(chan struct{}),
+	}
+}
+
+// get returns an idle connection or dials a new one.
+func (p *connPool) get() (*rpc.Client, error) {
+	for {
+		select {
+		case <-p.done:
+			return nil, errPoolClosed
+		default:
+		}
+		var conn *pooledConn
+		select {
+		case conn = <-p.idle:
+		default:
+			select {
+			case conn = <-p.idle:
+			case p.slots <- struct{}{}:
+				client, err := rpc.DialHTTP("tcp", p.addr)
+				if err != nil {
+					<-p.slots
+					return nil, err
+				}
+				return client, nil
+			case <-p.done:
+				return nil, errPoolClosed
+			}
+		}
+		if time.Since(conn.used) < healthCheckIdle || ping(conn.client) == nil {
+			return conn.client, nil
+		}
+		p.discard(conn.client)
+	}
+}
+
+// put returns healthy connection to the pool.
+func (p *connPool) put(client *rpc.Client) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		p.discard(client)
+		return
+	}
+	p.idle <- &pooledConn{client: client, used: time.Now()}
+}
+
+// discard closes broken connection, so a new one can be dialed instead.
+func (p *connPool) discard(client *rpc.Client) {
+	_ = client.Close()
+	<-p.slots
+}
+
+// close closes idle connections. Connections in use are closed when they are released.
+func (p *connPool) close() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	close(p.done)
+	for {
+		select {
+		case conn := <-p.idle:
+			p.discard(conn.client)
+		default:
+			return
+		}
+	}
+}
+
+// ping checks that connection is alive.
+func ping(client *rpc.Client) error {
+	var reply int
+	select {
+	case call := <-client.Go("GoInsider.Ping", 1, &reply, nil).Done:
+		return call.Error
+	case <-time.After(healthCheckIdle):
+		return errors.New("ping timeout")
+	}
+}
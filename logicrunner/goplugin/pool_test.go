@@ -0,0 +1,143 @@
+package goplugin
+
+import (
+	"net"
+	"net/http"
+	"net/rpc"
+	"testing"
+	"time"
+
+	"github.com/insolar/insolar/logicrunner/goplugin/girpc"
+)
+
+type testInsider struct{}
+
+func (t *testInsider) Ping(req int, reply *int) error {
+	*reply = req
+	return nil
+}
+
+func (t *testInsider) Call(args girpc.CallReq, reply *girpc.CallResp) error {
+	reply.Ret = args.Arguments
+	return nil
+}
+
+// startTestInsider starts RPC server imitating the runner and returns its address and listener.
+func startTestInsider(t *testing.T) (string, net.Listener) {
+	server := rpc.NewServer()
+	err := server.RegisterName("GoInsider", &testInsider{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go http.Serve(l, server) // nolint: errcheck
+	return l.Addr().String(), l
+}
+
+func TestConnPool_ReusesConnections(t *testing.T) {
+	addr, l := startTestInsider(t)
+	defer l.Close() // nolint: errcheck
+	p := newConnPool(addr, 1)
+	defer p.close()
+
+	c1, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.put(c1)
+	c2, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 != c2 {
+		t.Fatal("idle connection is not reused")
+	}
+
+	// The pool is exhausted, so get waits until the connection is released.
+	got := make(chan error)
+	go func() {
+		c, err := p.get()
+		if err == nil && c != c2 {
+			t.Error("released connection is not reused")
+		}
+		got <- err
+	}()
+	select {
+	case <-got:
+		t.Fatal("pool is not bounded")
+	case <-time.After(50 * time.Millisecond):
+	}
+	p.put(c2)
+	if err := <-got; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnPool_ReplacesBrokenConnections(t *testing.T) {
+	addr, l := startTestInsider(t)
+	defer l.Close() // nolint: errcheck
+	p := newConnPool(addr, 1)
+	defer p.close()
+
+	c1, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.discard(c1)
+	c2, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 == c2 {
+		t.Fatal("discarded connection is reused")
+	}
+
+	// Stale idle connection fails health check and is replaced.
+	_ = c2.Close()
+	p.idle <- &pooledConn{client: c2, used: time.Now().Add(-2 * healthCheckIdle)}
+	c3, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c3 == c2 {
+		t.Fatal("broken connection is reused")
+	}
+	var reply girpc.CallResp
+	err = c3.Call("GoInsider.Call", girpc.CallReq{Arguments: []byte{1}}, &reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConnPool_Close(t *testing.T) {
+	addr, l := startTestInsider(t)
+	defer l.Close() // nolint: errcheck
+	p := newConnPool(addr, 2)
+
+	c1, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := p.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.put(c1)
+	p.close()
+	p.put(c2)
+
+	if _, err = p.get(); err != errPoolClosed {
+		t.Fatalf("Got unexpected error: %v, errPoolClosed is expected", err)
+	}
+	for _, c := range []*rpc.Client{c1, c2} {
+		if err = c.Call("GoInsider.Ping", 1, new(int)); err != rpc.ErrShutdown {
+			t.Fatalf("Got unexpected error: %v, connection should be closed", err)
+		}
+	}
+	if len(p.slots) != 0 {
+		t.Fatalf("%d connections are left open", len(p.slots))
+	}
+}
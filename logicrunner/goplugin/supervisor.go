@@ -0,0 +1,193 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package goplugin
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// readyMessage is written by ginsider to readiness pipe when it is ready to serve calls.
+const readyMessage = "ready\n"
+
+// process is a running ginsider process.
+type process struct {
+	cmd     *exec.Cmd
+	started time.Time
+	exited  chan struct{} // closed when the process exits
+	err     error         // exit error, valid after exited is closed
+	conns   *connPool     // connections to the process
+}
+
+// supervisor runs ginsider and restarts it with exponential backoff when it exits unexpectedly.
+type supervisor struct {
+	path    string
+	args    []string
+	options RunnerOptions
+
+	lock    sync.Mutex
+	proc    *process
+	stopped chan struct{}
+	done    chan struct{}
+}
+
+func newSupervisor(path string, args []string, options RunnerOptions) *supervisor {
+	return &supervisor{path: path, args: args, options: options}
+}
+
+// start launches ginsider and waits until it is ready. Process is restarted until stop is called.
+func (s *supervisor) start() error {
+	p, err := s.launch()
+	if err != nil {
+		return err
+	}
+	s.lock.Lock()
+	s.proc = p
+	s.stopped = make(chan struct{})
+	s.done = make(chan struct{})
+	s.lock.Unlock()
+	go s.run(p)
+	return nil
+}
+
+// current returns running ginsider process or nil if it is not running.
+func (s *supervisor) current() *process {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.proc
+}
+
+func (s *supervisor) setCurrent(p *process) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.proc = p
+}
+
+// stop stops restarting ginsider and terminates running process gracefully.
+func (s *supervisor) stop() {
+	close(s.stopped)
+	<-s.done
+}
+
+// run waits for process exit and restarts it until supervisor is stopped.
+func (s *supervisor) run(p *process) {
+	defer close(s.done)
+	backoff := s.options.MinRestartDelay
+	for {
+		select {
+		case <-s.stopped:
+			s.setCurrent(nil)
+			s.terminate(p)
+			p.conns.close()
+			return
+		case <-p.exited:
+		}
+		s.setCurrent(nil)
+		p.conns.close()
+		log.Printf("ginsider exited: %v", p.err)
+		if time.Since(p.started) > s.options.MaxRestartDelay {
+			backoff = s.options.MinRestartDelay
+		}
+
+		for p = nil; p == nil; {
+			select {
+			case <-s.stopped:
+				return
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+			if backoff > s.options.MaxRestartDelay {
+				backoff = s.options.MaxRestartDelay
+			}
+			var err error
+			p, err = s.launch()
+			if err != nil {
+				log.Printf("couldn't restart ginsider: %v", err)
+			}
+		}
+		s.setCurrent(p)
+	}
+}
+
+// launch starts ginsider and waits for readiness message. Process is killed if it is not ready in time.
+func (s *supervisor) launch() (*process, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't create readiness pipe")
+	}
+	defer r.Close() // nolint: errcheck
+
+	// Readiness pipe is the first of extra files, so it is the descriptor 3 in child.
+	cmd := exec.Command(s.path, append(s.args, "--ready-fd", "3")...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = []*os.File{w}
+	err = cmd.Start()
+	w.Close() // nolint: errcheck
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't start %s", s.path)
+	}
+	p := &process{cmd: cmd, started: time.Now(), exited: make(chan struct{})}
+	go func() {
+		p.err = cmd.Wait()
+		close(p.exited)
+	}()
+
+	ready := make(chan error, 1)
+	go func() {
+		msg, err := bufio.NewReader(r).ReadString('\n')
+		if err == nil && msg != readyMessage {
+			err = errors.Errorf("unexpected readiness message %q", msg)
+		}
+		ready <- err
+	}()
+	select {
+	case err = <-ready:
+	case <-time.After(s.options.ReadyTimeout):
+		err = errors.New("readiness timeout")
+	}
+	if err != nil {
+		_ = cmd.Process.Kill()
+		<-p.exited
+		return nil, errors.Wrapf(err, "ginsider is not ready (exit status: %v)", p.err)
+	}
+	p.conns = newConnPool(s.options.Listen, s.options.MaxConnections)
+	return p, nil
+}
+
+// terminate asks process to exit and kills it if it doesn't exit in time.
+func (s *supervisor) terminate(p *process) {
+	err := p.cmd.Process.Signal(syscall.SIGTERM)
+	if err != nil {
+		log.Printf("couldn't terminate ginsider: %v", err)
+	}
+	select {
+	case <-p.exited:
+		return
+	case <-time.After(s.options.StopTimeout):
+	}
+	log.Print("ginsider didn't exit in time, killing it")
+	_ = p.cmd.Process.Kill()
+	<-p.exited
+}
@@ -0,0 +1,170 @@
+package goplugin
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/ugorji/go/codec"
+
+	"github.com/insolar/insolar/logicrunner"
+)
+
+func TestNewGoPlugin_RunnerIsNotReady(t *testing.T) {
+	_, err := NewGoPlugin(
+		Options{Listen: "127.0.0.1:7782"},
+		RunnerOptions{Listen: "127.0.0.1:7781", Path: "/bin/true"},
+	)
+	if err == nil {
+		t.Fatal("runner exiting before readiness should fail the start")
+	}
+	_, err = NewGoPlugin(
+		Options{Listen: "127.0.0.1:7782"},
+		RunnerOptions{Listen: "127.0.0.1:7781", Path: "./no-such-runner"},
+	)
+	if err == nil {
+		t.Fatal("missing runner binary should fail the start")
+	}
+}
+
+func TestGoPlugin_RestartsCrashedRunner(t *testing.T) {
+	if err := compileBinaries(); err != nil {
+		t.Fatal("Can't compile binaries", err)
+	}
+	dir, err := ioutil.TempDir("", "test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	gp, err := NewGoPlugin(
+		Options{
+			Listen:   "127.0.0.1:7784",
+			CodePath: "./testplugins/",
+		},
+		RunnerOptions{
+			Listen:          "127.0.0.1:7783",
+			CodeStoragePath: dir,
+			MinRestartDelay: 10 * time.Millisecond,
+			MaxRestartDelay: 100 * time.Millisecond,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gp.Stop()
+
+	ch := new(codec.CborHandle)
+	var data, args []byte
+	err = codec.NewEncoderBytes(&data, ch).Encode(HelloWorlder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = codec.NewEncoderBytes(&args, ch).Encode([]interface{}{5000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := logicrunner.Object{MachineType: logicrunner.MachineTypeGoPlugin, Reference: "secondary", Data: data}
+
+	// Crash the runner during the call.
+	p := gp.runner()
+	execErr := make(chan error)
+	go func() {
+		_, _, err := gp.Exec(obj, "Sleep", args)
+		execErr <- err
+	}()
+	time.Sleep(500 * time.Millisecond)
+	err = p.cmd.Process.Kill()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = <-execErr
+	if errors.Cause(err) != ErrRunnerCrashed {
+		t.Fatalf("Got unexpected error: %v, ErrRunnerCrashed is expected", err)
+	}
+
+	// The runner is restarted and serves calls again.
+	deadline := time.Now().Add(5 * time.Second)
+	for gp.runner() == nil || gp.runner() == p {
+		if time.Now().After(deadline) {
+			t.Fatal("runner is not restarted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	hw := &HelloWorlder{1}
+	if res := hw.ProxyEcho(gp, "again"); res != "again" || hw.Greeted != 2 {
+		t.Fatalf("Got unexpected result: %s, %d", res, hw.Greeted)
+	}
+
+	gp.Stop()
+	_, _, err = gp.Exec(obj, "Sleep", args)
+	if err != ErrRunnerUnavailable {
+		t.Fatalf("Got unexpected error: %v, ErrRunnerUnavailable is expected", err)
+	}
+}
+
+func TestGoPlugin_StopWaitsForRunningCalls(t *testing.T) {
+	if err := compileBinaries(); err != nil {
+		t.Fatal("Can't compile binaries", err)
+	}
+	dir, err := ioutil.TempDir("", "test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir) // nolint: errcheck
+
+	gp, err := NewGoPlugin(
+		Options{
+			Listen:   "127.0.0.1:7790",
+			CodePath: "./testplugins/",
+		},
+		RunnerOptions{
+			Listen:          "127.0.0.1:7789",
+			CodeStoragePath: dir,
+			StopTimeout:     10 * time.Second,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gp.Stop()
+
+	// Pooled connection is left idle.
+	hw := &HelloWorlder{}
+	if res := hw.ProxyEcho(gp, "hi"); res != "hi" {
+		t.Fatalf("Got unexpected result: %s", res)
+	}
+
+	ch := new(codec.CborHandle)
+	var data, args []byte
+	err = codec.NewEncoderBytes(&data, ch).Encode(HelloWorlder{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = codec.NewEncoderBytes(&args, ch).Encode([]interface{}{1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := logicrunner.Object{MachineType: logicrunner.MachineTypeGoPlugin, Reference: "secondary", Data: data}
+
+	p := gp.runner()
+	execErr := make(chan error)
+	go func() {
+		_, _, err := gp.Exec(obj, "Sleep", args)
+		execErr <- err
+	}()
+	time.Sleep(300 * time.Millisecond)
+	started := time.Now()
+	gp.Stop()
+	if err = <-execErr; err != nil {
+		t.Fatalf("Running call failed on stop: %v", err)
+	}
+	if p.err != nil {
+		t.Fatalf("ginsider didn't exit gracefully: %v", p.err)
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("ginsider stopped in %v", elapsed)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"fmt"
+	"time"
 )
 
 // @inscontract
@@ -41,6 +42,12 @@ func (hw *HelloWorlder) Echo(s string) (string, error) {
 	return s, nil
 }
 
+// nolint
+func (hw *HelloWorlder) Sleep(ms int) int {
+	time.Sleep(time.Duration(ms) * time.Millisecond)
+	return ms
+}
+
 func (hw *HelloWorlder) HelloHuman(Name FullName) PersonalGreeting {
 	hw.Greeted++
 	return PersonalGreeting{
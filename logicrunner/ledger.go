@@ -0,0 +1,103 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package logicrunner
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/artifactmanager"
+	"github.com/insolar/insolar/ledger/record"
+)
+
+// LedgerRunner is a LogicRunner which executes methods of objects stored in ledger.
+//
+// Object reference passed to Exec is a reference to the object head. LedgerRunner fetches the latest object memory
+// and class code through artifact manager and executes the method on executor of object's machine type. Calls of
+// objects whose class code is not available for artifact manager's architecture preferences fail before execution.
+// Executor gets the object referring to the class code record, like migrations do (see Migrator), and fetches the code
+// by this reference from the same ledger (see goplugin.Options.ArtifactManager).
+//
+// Every call is stored as a call request record before execution. The request refers to the object state the call is
+// made on, so identical calls produce different requests. New object memory returned by executor is stored
+// with CompareAndUpdateObj, so the object amend record is the result of the request. If the object was changed
+// during execution, the call fails with artifactmanager.ErrConflict instead of overwriting the change. Failed calls
+// leave requests without results.
+type LedgerRunner struct {
+	Runner          *Runner
+	ArtifactManager artifactmanager.ArtifactManager
+}
+
+// NewLedgerRunner creates LedgerRunner executing calls on runner's executors.
+func NewLedgerRunner(runner *Runner, am artifactmanager.ArtifactManager) *LedgerRunner {
+	return &LedgerRunner{Runner: runner, ArtifactManager: am}
+}
+
+// Start starts underlying Runner.
+func (r *LedgerRunner) Start() {
+	r.Runner.Start()
+}
+
+// Stop stops underlying Runner.
+func (r *LedgerRunner) Stop() {
+	r.Runner.Stop()
+}
+
+// Exec runs method of the object stored in ledger and stores its new memory.
+func (r *LedgerRunner) Exec(object Object, method string, args Arguments) (Arguments, error) {
+	objRef, err := record.ParseReference(string(object.Reference))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid object reference")
+	}
+	e, err := r.Runner.GetExecutor(object.MachineType)
+	if err != nil {
+		return nil, err
+	}
+	class, obj, err := r.ArtifactManager.GetLatestObj(objRef, record.Reference{}, record.Reference{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch object %s", object.Reference)
+	}
+	memory, err := obj.GetMemory()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch memory of object %s", object.Reference)
+	}
+	if _, err = class.GetCode(); err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch code of object %s", object.Reference)
+	}
+
+	requestRef, err := r.ArtifactManager.RegisterRequest(&record.CallRequest{
+		RequestRecord: record.RequestRecord{Target: objRef},
+		ParamMemory:   record.Memory(args),
+		Method:        method,
+		ObjectState:   &obj.StateRef,
+	})
+	if err != nil {
+		return nil, err
+	}
+	data, ret, err := e.Exec(Object{
+		MachineType: object.MachineType,
+		Reference:   Reference(class.GetCodeRef().String()),
+		Data:        memory,
+	}, method, args)
+	if err != nil {
+		return nil, errors.Wrapf(err, "call %s.%s failed", object.Reference, method)
+	}
+	_, err = r.ArtifactManager.CompareAndUpdateObj(*requestRef, objRef, obj.StateRef, record.Memory(data))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to update object %s", object.Reference)
+	}
+	return ret, nil
+}
@@ -0,0 +1,162 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package logicrunner
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/artifactmanager"
+	"github.com/insolar/insolar/ledger/record"
+	"github.com/insolar/insolar/ledger/storage/memory"
+)
+
+// prepareLedgerObject deploys code, activates class and its object with provided memory.
+func prepareLedgerObject(
+	t *testing.T, am artifactmanager.ArtifactManager, memory record.Memory,
+) (codeRef, objRef record.Reference) {
+	requestRef, err := am.RegisterRequest(&record.CallRequest{})
+	assert.NoError(t, err)
+	code, err := am.DeployCode(*requestRef, map[record.ArchType][]byte{1: {1}})
+	assert.NoError(t, err)
+	// Identical requests have the same reference, so the second one is made different.
+	requestRef, err = am.RegisterRequest(&record.CallRequest{ParamMemory: record.Memory{1}})
+	assert.NoError(t, err)
+	classRef, err := am.ActivateClass(*requestRef, *code, nil)
+	assert.NoError(t, err)
+	requestRef, err = am.RegisterRequest(&record.CallRequest{RequestRecord: record.RequestRecord{Target: *classRef}})
+	assert.NoError(t, err)
+	obj, err := am.ActivateObj(*requestRef, *classRef, memory)
+	assert.NoError(t, err)
+	return *code, *obj
+}
+
+func TestLedgerRunner_Exec(t *testing.T) {
+	ledger := memory.NewMemLedger()
+	am := artifactmanager.NewArtifactManager(ledger)
+	am.SetArchPref([]record.ArchType{1})
+	codeRef, objRef := prepareLedgerObject(t, am, record.Memory{1})
+
+	var called []Object
+	runner := NewRunner()
+	err := runner.RegisterExecutor(MachineTypeGoPlugin, &testExecutor{
+		exec: func(object Object, method string, args Arguments) ([]byte, Arguments, error) {
+			called = append(called, object)
+			data := append(append([]byte{}, object.Data...), args...)
+			return data, Arguments(method), nil
+		},
+	})
+	assert.NoError(t, err)
+	r := NewLedgerRunner(runner, am)
+	var _ LogicRunner = r
+
+	object := Object{MachineType: MachineTypeGoPlugin, Reference: Reference(objRef.String())}
+	ret, err := r.Exec(object, "Add", Arguments{2})
+	assert.NoError(t, err)
+	assert.Equal(t, Arguments("Add"), ret)
+	ret, err = r.Exec(object, "Add", Arguments{2})
+	assert.NoError(t, err)
+	assert.Equal(t, Arguments("Add"), ret)
+
+	assert.Equal(t, []Object{
+		{MachineType: MachineTypeGoPlugin, Reference: Reference(codeRef.String()), Data: []byte{1}},
+		{MachineType: MachineTypeGoPlugin, Reference: Reference(codeRef.String()), Data: []byte{1, 2}},
+	}, called)
+	_, obj, err := am.GetLatestObj(objRef, record.Reference{}, record.Reference{})
+	assert.NoError(t, err)
+	memory, err := obj.GetMemory()
+	assert.NoError(t, err)
+	assert.Equal(t, record.Memory{1, 2, 2}, memory)
+
+	history, err := am.GetHistory(objRef)
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	for _, entry := range history[1:] {
+		requestRef := entry.Record.(*record.ObjectAmendRecord).RequestRecord
+		resultRef, err := ledger.GetRequestResult(&requestRef)
+		assert.NoError(t, err)
+		assert.Equal(t, entry.Ref.Key(), resultRef.Key())
+		rec, err := ledger.GetRecord(&requestRef)
+		assert.NoError(t, err)
+		request := rec.(*record.CallRequest)
+		assert.Equal(t, objRef.Key(), request.Target.Key())
+		assert.Equal(t, "Add", request.Method)
+		assert.Equal(t, record.Memory{2}, request.ParamMemory)
+	}
+}
+
+func TestLedgerRunner_ExecErrors(t *testing.T) {
+	ledger := memory.NewMemLedger()
+	am := artifactmanager.NewArtifactManager(ledger)
+	am.SetArchPref([]record.ArchType{1})
+	_, objRef := prepareLedgerObject(t, am, record.Memory{1})
+
+	execErr := errors.New("exec failed")
+	runner := NewRunner()
+	err := runner.RegisterExecutor(MachineTypeGoPlugin, &testExecutor{
+		exec: func(object Object, method string, args Arguments) ([]byte, Arguments, error) {
+			return nil, nil, execErr
+		},
+	})
+	assert.NoError(t, err)
+	r := NewLedgerRunner(runner, am)
+
+	_, err = r.Exec(Object{MachineType: MachineTypeGoPlugin, Reference: "invalid"}, "Add", nil)
+	assert.Equal(t, record.ErrInvalidReference, errors.Cause(err))
+	_, err = r.Exec(Object{MachineType: MachineTypeBuiltin, Reference: Reference(objRef.String())}, "Add", nil)
+	assert.Equal(t, ErrUnknownMachineType, errors.Cause(err))
+	_, err = r.Exec(Object{MachineType: MachineTypeGoPlugin, Reference: Reference(objRef.String())}, "Add", nil)
+	assert.Equal(t, execErr, errors.Cause(err))
+	am.SetArchPref([]record.ArchType{2})
+	_, err = r.Exec(Object{MachineType: MachineTypeGoPlugin, Reference: Reference(objRef.String())}, "Add", nil)
+	assert.Equal(t, record.ErrArchUnavailable, errors.Cause(err))
+
+	history, err := am.GetHistory(objRef)
+	assert.NoError(t, err)
+	assert.Len(t, history, 1)
+}
+
+func TestLedgerRunner_ExecConflict(t *testing.T) {
+	ledger := memory.NewMemLedger()
+	am := artifactmanager.NewArtifactManager(ledger)
+	am.SetArchPref([]record.ArchType{1})
+	_, objRef := prepareLedgerObject(t, am, record.Memory{1})
+
+	runner := NewRunner()
+	err := runner.RegisterExecutor(MachineTypeGoPlugin, &testExecutor{
+		exec: func(object Object, method string, args Arguments) ([]byte, Arguments, error) {
+			// object is changed while the call is executed
+			requestRef, err := am.RegisterRequest(&record.CallRequest{RequestRecord: record.RequestRecord{Target: objRef}})
+			assert.NoError(t, err)
+			_, err = am.UpdateObj(*requestRef, objRef, record.Memory{3})
+			assert.NoError(t, err)
+			return []byte{2}, nil, nil
+		},
+	})
+	assert.NoError(t, err)
+	r := NewLedgerRunner(runner, am)
+
+	_, err = r.Exec(Object{MachineType: MachineTypeGoPlugin, Reference: Reference(objRef.String())}, "Add", nil)
+	assert.Equal(t, artifactmanager.ErrConflict, errors.Cause(err))
+	_, obj, err := am.GetLatestObj(objRef, record.Reference{}, record.Reference{})
+	assert.NoError(t, err)
+	memory, err := obj.GetMemory()
+	assert.NoError(t, err)
+	assert.Equal(t, record.Memory{3}, memory)
+}
@@ -17,6 +17,10 @@
 // Package logicrunner - infrastructure for executing smartcontracts
 package logicrunner
 
+import (
+	"fmt"
+)
+
 // MachineType is a type of virtual machine
 type MachineType int
 
@@ -26,9 +30,28 @@ const (
 	MachineTypeGoPlugin
 )
 
+func (t MachineType) String() string {
+	switch t {
+	case MachineTypeBuiltin:
+		return "builtin"
+	case MachineTypeGoPlugin:
+		return "goplugin"
+	default:
+		return fmt.Sprintf("MachineType(%d)", int(t))
+	}
+}
+
 // LogicRunner is a general interface of contract executor
 type LogicRunner interface {
 	Start()
 	Stop()
 	Exec(object Object, method string, args Arguments) (ret Arguments, err error)
 }
+
+// Executor is an executor of contracts of a single machine type (see Runner).
+type Executor interface {
+	Start()
+	Stop()
+	// Exec runs method of the object and returns new object data and method result.
+	Exec(object Object, method string, args Arguments) (data []byte, ret Arguments, err error)
+}
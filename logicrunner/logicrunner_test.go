@@ -15,3 +15,46 @@
  */
 
 package logicrunner
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/insolar/insolar/ledger/record"
+)
+
+type execFunc func(object Object, method string, args Arguments) (Arguments, error)
+
+func (f execFunc) Start() {}
+func (f execFunc) Stop()  {}
+
+func (f execFunc) Exec(object Object, method string, args Arguments) (Arguments, error) {
+	return f(object, method, args)
+}
+
+func TestMigrator_RunMigration(t *testing.T) {
+	codeRef := record.Reference{Record: record.ID{Pulse: 1, Hash: []byte{1, 2, 3}}}
+	var called Object
+	m := Migrator{
+		MachineType: MachineTypeGoPlugin,
+		Runner: execFunc(func(object Object, method string, args Arguments) (Arguments, error) {
+			called = object
+			assert.Equal(t, MigrationMethod, method)
+			return append(args, 4), nil
+		}),
+	}
+	memory, err := m.RunMigration(codeRef, record.Memory{1, 2})
+	assert.NoError(t, err)
+	assert.Equal(t, record.Memory{1, 2, 4}, memory)
+	assert.Equal(t, MachineTypeGoPlugin, called.MachineType)
+	assert.Equal(t, Reference(codeRef.String()), called.Reference)
+
+	execErr := errors.New("exec failed")
+	m.Runner = execFunc(func(object Object, method string, args Arguments) (Arguments, error) {
+		return nil, execErr
+	})
+	_, err = m.RunMigration(codeRef, record.Memory{1})
+	assert.Equal(t, execErr, errors.Cause(err))
+}
@@ -0,0 +1,48 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package logicrunner
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/insolar/insolar/ledger/record"
+)
+
+// MigrationMethod is a method of migration code which converts object memory to the new class layout.
+const MigrationMethod = "Migrate"
+
+// Migrator runs class migrations through a logic runner. It implements artifactmanager.MigrationRunner.
+//
+// Migration code is executed as an object referring to the migration code record. Object memory is passed as call
+// arguments and migrated memory is expected as call result.
+type Migrator struct {
+	Runner      LogicRunner
+	MachineType MachineType
+}
+
+// RunMigration executes migration code referred by codeRef on provided memory and returns migrated memory.
+func (m *Migrator) RunMigration(codeRef record.Reference, memory record.Memory) (record.Memory, error) {
+	object := Object{
+		MachineType: m.MachineType,
+		Reference:   Reference(codeRef.String()),
+	}
+	ret, err := m.Runner.Exec(object, MigrationMethod, Arguments(memory))
+	if err != nil {
+		return nil, errors.Wrapf(err, "migration %s failed", object.Reference)
+	}
+	return record.Memory(ret), nil
+}
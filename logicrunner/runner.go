@@ -0,0 +1,121 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package logicrunner
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrUnknownMachineType is returned if there is no executor for object's machine type.
+	ErrUnknownMachineType = errors.New("unknown machine type")
+	// ErrExecutorExists is returned on attempt to register second executor for the same machine type.
+	ErrExecutorExists = errors.New("executor for machine type is already registered")
+)
+
+// Runner is a LogicRunner which dispatches calls to executors by object's machine type.
+//
+// Runner manages lifecycle of registered executors: Start starts all of them in order of machine types and Stop stops
+// them in reverse order.
+type Runner struct {
+	lock      sync.RWMutex
+	executors map[MachineType]Executor
+	started   bool
+}
+
+// NewRunner creates Runner without executors.
+func NewRunner() *Runner {
+	return &Runner{executors: map[MachineType]Executor{}}
+}
+
+// RegisterExecutor registers executor for machine type. Executor registered to started Runner is started immediately.
+func (r *Runner) RegisterExecutor(t MachineType, e Executor) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.executors[t]; ok {
+		return errors.Wrapf(ErrExecutorExists, "%v", t)
+	}
+	r.executors[t] = e
+	if r.started {
+		e.Start()
+	}
+	return nil
+}
+
+// GetExecutor returns executor registered for machine type.
+func (r *Runner) GetExecutor(t MachineType) (Executor, error) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	e, ok := r.executors[t]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownMachineType, "%v", t)
+	}
+	return e, nil
+}
+
+// machineTypes returns registered machine types in ascending order.
+func (r *Runner) machineTypes() []MachineType {
+	types := make([]MachineType, 0, len(r.executors))
+	for t := range r.executors {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// Start starts all registered executors. Calling Start on started Runner does nothing.
+func (r *Runner) Start() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.started {
+		return
+	}
+	for _, t := range r.machineTypes() {
+		r.executors[t].Start()
+	}
+	r.started = true
+}
+
+// Stop stops all registered executors. Calling Stop on stopped Runner does nothing.
+func (r *Runner) Stop() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if !r.started {
+		return
+	}
+	types := r.machineTypes()
+	for i := len(types) - 1; i >= 0; i-- {
+		r.executors[types[i]].Stop()
+	}
+	r.started = false
+}
+
+// Exec runs method of the object on executor of object's machine type. It returns ErrUnknownMachineType if there is
+// no such executor.
+//
+// New object data returned by executor is dropped, use GetExecutor to get it.
+func (r *Runner) Exec(object Object, method string, args Arguments) (Arguments, error) {
+	e, err := r.GetExecutor(object.MachineType)
+	if err != nil {
+		return nil, err
+	}
+	_, ret, err := e.Exec(object, method, args)
+	return ret, err
+}
@@ -0,0 +1,108 @@
+/*
+ *    Copyright 2018 INS Ecosystem
+ *
+ *    Licensed under the Apache License, Version 2.0 (the "License");
+ *    you may not use this file except in compliance with the License.
+ *    You may obtain a copy of the License at
+ *
+ *        http://www.apache.org/licenses/LICENSE-2.0
+ *
+ *    Unless required by applicable law or agreed to in writing, software
+ *    distributed under the License is distributed on an "AS IS" BASIS,
+ *    WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ *    See the License for the specific language governing permissions and
+ *    limitations under the License.
+ */
+
+package logicrunner
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type testExecutor struct {
+	name   string
+	events *[]string
+	exec   func(object Object, method string, args Arguments) ([]byte, Arguments, error)
+}
+
+func (e *testExecutor) Start() {
+	*e.events = append(*e.events, "start "+e.name)
+}
+
+func (e *testExecutor) Stop() {
+	*e.events = append(*e.events, "stop "+e.name)
+}
+
+func (e *testExecutor) Exec(object Object, method string, args Arguments) ([]byte, Arguments, error) {
+	return e.exec(object, method, args)
+}
+
+func TestRunner_DispatchesByMachineType(t *testing.T) {
+	var events []string
+	r := NewRunner()
+	var _ LogicRunner = r
+	for _, mt := range []MachineType{MachineTypeBuiltin, MachineTypeGoPlugin} {
+		name := mt.String()
+		err := r.RegisterExecutor(mt, &testExecutor{
+			name:   name,
+			events: &events,
+			exec: func(object Object, method string, args Arguments) ([]byte, Arguments, error) {
+				return object.Data, Arguments(name + "." + method + string(args)), nil
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	ret, err := r.Exec(Object{MachineType: MachineTypeBuiltin}, "Echo", Arguments("!"))
+	assert.NoError(t, err)
+	assert.Equal(t, Arguments("builtin.Echo!"), ret)
+	ret, err = r.Exec(Object{MachineType: MachineTypeGoPlugin}, "Echo", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, Arguments("goplugin.Echo"), ret)
+
+	_, err = r.Exec(Object{MachineType: 42}, "Echo", nil)
+	assert.Equal(t, ErrUnknownMachineType, errors.Cause(err))
+	assert.Contains(t, err.Error(), "MachineType(42)")
+	_, err = r.GetExecutor(42)
+	assert.Equal(t, ErrUnknownMachineType, errors.Cause(err))
+
+	err = r.RegisterExecutor(MachineTypeBuiltin, &testExecutor{events: &events})
+	assert.Equal(t, ErrExecutorExists, errors.Cause(err))
+	assert.Empty(t, events)
+}
+
+func TestRunner_StartStop(t *testing.T) {
+	var events []string
+	r := NewRunner()
+	assert.NoError(t, r.RegisterExecutor(MachineTypeGoPlugin, &testExecutor{name: "goplugin", events: &events}))
+	assert.NoError(t, r.RegisterExecutor(MachineTypeBuiltin, &testExecutor{name: "builtin", events: &events}))
+
+	r.Start()
+	r.Start()
+	assert.Equal(t, []string{"start builtin", "start goplugin"}, events)
+
+	events = nil
+	assert.NoError(t, r.RegisterExecutor(42, &testExecutor{name: "late", events: &events}))
+	assert.Equal(t, []string{"start late"}, events)
+
+	events = nil
+	r.Stop()
+	r.Stop()
+	assert.Equal(t, []string{"stop late", "stop goplugin", "stop builtin"}, events)
+}
+
+func TestRunner_ExecErrors(t *testing.T) {
+	execErr := errors.New("exec failed")
+	r := NewRunner()
+	assert.NoError(t, r.RegisterExecutor(MachineTypeBuiltin, &testExecutor{
+		exec: func(Object, string, Arguments) ([]byte, Arguments, error) {
+			return nil, nil, execErr
+		},
+	}))
+	_, err := r.Exec(Object{MachineType: MachineTypeBuiltin}, "Fail", nil)
+	assert.Equal(t, execErr, err)
+}